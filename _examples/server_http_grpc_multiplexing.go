@@ -1,8 +1,6 @@
 package main
 
 import (
-	"context"
-	"log"
 	"os"
 	"time"
 
@@ -23,24 +21,22 @@ func main() {
 	}).With().Timestamp().Logger()
 
 	greeterSvc := &greeter.GreeterService{}
-	greeterRegistrator := func(s *grpc.Server) {
+	greeterRegistration := func(s grpc.ServiceRegistrar) {
 		greeter_pb.RegisterGreeterServiceServer(s, greeterSvc)
 	}
 
 	logger.Debug().Msg("building cadre")
 
-	greeterCon, err := grpc.Dial("localhost:9000", grpc.WithInsecure())
-	if err != nil {
-		log.Fatalf("cannot create connection to grpc server: %v", err)
-	}
-	greeterClient := greeter_pb.NewGreeterServiceClient(greeterCon)
+	// calls the greeter service in-process, set once the cadre is built
+	var greeterClient greeter_pb.GreeterServiceClient
 
 	b, err := cadre.NewBuilder(
 		"example",
 		cadre.WithLogger(logger),
 		cadre.WithGRPC(
 			cadre.WithGRPCMultiplex(),
-			cadre.WithService("example.GreeterService", greeterRegistrator),
+			cadre.WithServiceRegistration("example.GreeterService", greeterRegistration),
+			cadre.WithInProcessConn(),
 		),
 		cadre.WithHTTP(
 			"main_http",
@@ -61,7 +57,7 @@ func main() {
 						"GET": {
 							func(c *gin.Context) {
 								name := c.DefaultQuery("name", "world")
-								res, err := greeterClient.SayHi(context.Background(), &greeter_pb.GreetingRequest{Name: name})
+								res, err := greeterClient.SayHi(c, &greeter_pb.GreetingRequest{Name: name})
 								if err != nil {
 									responses.InternalError(c, responses.NewError(err))
 									return
@@ -84,5 +80,7 @@ func main() {
 		panic(err)
 	}
 
+	greeterClient = greeter_pb.NewGreeterServiceClient(c.InProcessConn())
+
 	panic(c.Start())
 }
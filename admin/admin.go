@@ -0,0 +1,282 @@
+// Package admin provides the cadre.admin.v1.Admin gRPC service for environments exposing only gRPC ports.
+//
+// The service returns the status report, gets and sets the global log level, dumps the effective
+// configuration with secrets redacted, lists instances of services in the registry and triggers
+// a configuration reload. It uses well-known protobuf types only, so it can be called without
+// generated code (see Client), e.g. by grpcurl:
+//
+//	grpcurl -H 'x-admin-token: ...' -d '"debug"' localhost:9000 cadre.admin.v1.Admin/SetLogLevel
+//
+// Every call has to carry the admin token in the x-admin-token metadata, which is checked by
+// UnaryServerInterceptor.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/moderntv/cadre/config"
+	"github.com/moderntv/cadre/registry"
+	"github.com/moderntv/cadre/status"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Redacted replaces values of redacted configuration keys.
+const Redacted = "[REDACTED]"
+
+// keys containing any of these (case-insensitive) are redacted.
+var defaultRedactedKeys = []string{"password", "secret", "token", "apikey", "api_key", "private_key", "credential"}
+
+type Server struct {
+	status *status.Status
+	token  string
+
+	config       func() any
+	redactedKeys []string
+	reload       func(context.Context) error
+	registry     registry.Registry
+}
+
+type Option func(*Server) error
+
+// New creates a new admin service reporting the status and accepting calls with the token.
+func New(st *status.Status, token string, opts ...Option) (s *Server, err error) {
+	if st == nil {
+		err = errors.New("status cannot be nil")
+		return
+	}
+
+	if token == "" {
+		err = errors.New("admin token cannot be empty")
+		return
+	}
+
+	s = &Server{
+		status:       st,
+		token:        token,
+		redactedKeys: slices.Clone(defaultRedactedKeys),
+	}
+
+	for _, opt := range opts {
+		err = opt(s)
+		if err != nil {
+			err = fmt.Errorf("cannot apply admin option: %w", err)
+			return
+		}
+	}
+
+	return
+}
+
+// WithConfig enables dumping of the configuration returned by the function. The configuration is marshaled
+// to JSON and values of keys containing password, secret, token, apikey, api_key, private_key or credential
+// are redacted.
+func WithConfig(get func() any) Option {
+	return func(s *Server) error {
+		if get == nil {
+			return errors.New("config function cannot be nil")
+		}
+
+		s.config = get
+
+		return nil
+	}
+}
+
+// WithRedactedKeys redacts also values of configuration keys containing any of the keys (case-insensitive).
+func WithRedactedKeys(keys ...string) Option {
+	return func(s *Server) error {
+		for _, key := range keys {
+			s.redactedKeys = append(s.redactedKeys, strings.ToLower(key))
+		}
+
+		return nil
+	}
+}
+
+// WithConfigReload enables triggering of configuration reloads by the function.
+func WithConfigReload(reload func(context.Context) error) Option {
+	return func(s *Server) error {
+		if reload == nil {
+			return errors.New("config reload function cannot be nil")
+		}
+
+		s.reload = reload
+
+		return nil
+	}
+}
+
+// WithConfigManager enables triggering of configuration reloads of the manager's subscribers.
+func WithConfigManager(m *config.Manager) Option {
+	return func(s *Server) error {
+		if m == nil {
+			return errors.New("config manager cannot be nil")
+		}
+
+		return WithConfigReload(func(context.Context) error {
+			m.Reload()
+
+			return nil
+		})(s)
+	}
+}
+
+// WithRegistry enables listing of service instances in the registry.
+func WithRegistry(r registry.Registry) Option {
+	return func(s *Server) error {
+		if r == nil {
+			return errors.New("registry cannot be nil")
+		}
+
+		s.registry = r
+
+		return nil
+	}
+}
+
+func (s *Server) GetStatus(context.Context, *emptypb.Empty) (*structpb.Struct, error) {
+	return toStruct(s.status.Report())
+}
+
+func (s *Server) GetLogLevel(context.Context, *emptypb.Empty) (*wrapperspb.StringValue, error) {
+	return wrapperspb.String(zerolog.GlobalLevel().String()), nil
+}
+
+// SetLogLevel sets the global log level and returns the previous one.
+func (s *Server) SetLogLevel(_ context.Context, req *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	level, err := zerolog.ParseLevel(strings.ToLower(req.GetValue()))
+	if err != nil || req.GetValue() == "" {
+		return nil, grpcstatus.Errorf(codes.InvalidArgument, "invalid log level `%s`", req.GetValue())
+	}
+
+	previous := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(level)
+
+	return wrapperspb.String(previous.String()), nil
+}
+
+func (s *Server) GetConfig(context.Context, *emptypb.Empty) (*structpb.Struct, error) {
+	if s.config == nil {
+		return nil, grpcstatus.Error(codes.FailedPrecondition, "config dump is not enabled")
+	}
+
+	b, err := json.Marshal(s.config())
+	if err != nil {
+		return nil, grpcstatus.Errorf(codes.Internal, "cannot marshal config: %v", err)
+	}
+
+	var cfg map[string]any
+
+	err = json.Unmarshal(b, &cfg)
+	if err != nil {
+		return nil, grpcstatus.Errorf(codes.Internal, "config is not an object: %v", err)
+	}
+
+	s.redact(cfg)
+
+	st, err := structpb.NewStruct(cfg)
+	if err != nil {
+		return nil, grpcstatus.Errorf(codes.Internal, "cannot convert config: %v", err)
+	}
+
+	return st, nil
+}
+
+// redact replaces values of sensitive keys in the (nested) object.
+func (s *Server) redact(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if s.redacted(key) {
+				v[key] = Redacted
+				continue
+			}
+
+			s.redact(value)
+		}
+
+	case []any:
+		for _, value := range v {
+			s.redact(value)
+		}
+	}
+}
+
+func (s *Server) redacted(key string) bool {
+	key = strings.ToLower(key)
+
+	for _, k := range s.redactedKeys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ListInstances lists instances of the service in the registry.
+func (s *Server) ListInstances(_ context.Context, req *wrapperspb.StringValue) (*structpb.Struct, error) {
+	if s.registry == nil {
+		return nil, grpcstatus.Error(codes.FailedPrecondition, "registry is not configured")
+	}
+
+	if req.GetValue() == "" {
+		return nil, grpcstatus.Error(codes.InvalidArgument, "service name cannot be empty")
+	}
+
+	instances := []any{}
+	for _, instance := range s.registry.Instances(req.GetValue()) {
+		instances = append(instances, map[string]any{
+			"service": instance.ServiceName(),
+			"address": instance.Address(),
+		})
+	}
+
+	st, err := structpb.NewStruct(map[string]any{"instances": instances})
+	if err != nil {
+		return nil, grpcstatus.Errorf(codes.Internal, "cannot convert instances: %v", err)
+	}
+
+	return st, nil
+}
+
+func (s *Server) ReloadConfig(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	if s.reload == nil {
+		return nil, grpcstatus.Error(codes.FailedPrecondition, "config reload is not enabled")
+	}
+
+	err := s.reload(ctx)
+	if err != nil {
+		return nil, grpcstatus.Errorf(codes.Internal, "config reload failed: %v", err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// toStruct converts the value to a struct through its JSON representation.
+func toStruct(v any) (*structpb.Struct, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, grpcstatus.Errorf(codes.Internal, "cannot marshal response: %v", err)
+	}
+
+	st := &structpb.Struct{}
+
+	err = protojson.Unmarshal(b, st)
+	if err != nil {
+		return nil, grpcstatus.Errorf(codes.Internal, "cannot convert response: %v", err)
+	}
+
+	return st, nil
+}
@@ -0,0 +1,300 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/moderntv/cadre/config"
+	"github.com/moderntv/cadre/config/encoder/yaml"
+	"github.com/moderntv/cadre/config/source/file"
+	"github.com/moderntv/cadre/grpc/inprocess"
+	"github.com/moderntv/cadre/registry/static"
+	"github.com/moderntv/cadre/status"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const testToken = "admin-token"
+
+type testConfig struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Database struct {
+		Host   string `json:"host"`
+		DBUser string `json:"db_user"`
+	} `json:"database"`
+	APIKeys []map[string]string `json:"api_keys"`
+}
+
+func newTestClient(t *testing.T, opts ...Option) *Client {
+	t.Helper()
+
+	st := status.NewStatus("v1.0.0")
+
+	cs, err := st.Register("database")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs.SetStatus(status.WARN, "slow")
+
+	s, err := New(st, testToken, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch, err := inprocess.New(inprocess.WithServerInterceptors(
+		[]grpc.UnaryServerInterceptor{s.UnaryServerInterceptor()},
+		nil,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Register(ch, s)
+
+	return NewClient(ch, testToken)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(status.NewStatus("v1.0.0"), "")
+	if err == nil {
+		t.Error("New() without token succeeded")
+	}
+}
+
+func TestAdmin_Token(t *testing.T) {
+	tests := []struct {
+		token string
+		want  codes.Code
+	}{
+		{token: testToken, want: codes.OK},
+		{token: "invalid", want: codes.Unauthenticated},
+		{token: "", want: codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q", tt.token), func(t *testing.T) {
+			client := newTestClient(t)
+			client.token = tt.token
+
+			_, err := client.GetStatus(t.Context())
+			if got := grpcstatus.Code(err); got != tt.want {
+				t.Errorf("GetStatus() code = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdmin_GetStatus(t *testing.T) {
+	report, err := newTestClient(t).GetStatus(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := report.AsMap()
+	database, _ := m["components"].(map[string]any)["database"].(map[string]any)
+
+	got := []any{m["version"], m["status"], database["message"]}
+	if want := []any{"v1.0.0", "WARN", "slow"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("version, status, database message = %v, want %v", got, want)
+	}
+}
+
+// not parallel - changes the global log level
+func TestAdmin_SetLogLevel(t *testing.T) {
+	previous := zerolog.GlobalLevel()
+	t.Cleanup(func() { zerolog.SetGlobalLevel(previous) })
+
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	client := newTestClient(t)
+
+	tests := []struct {
+		level        string
+		wantPrevious string
+		wantCode     codes.Code
+		wantLevel    zerolog.Level
+	}{
+		{level: "DEBUG", wantPrevious: "info", wantLevel: zerolog.DebugLevel},
+		{level: "verbose", wantCode: codes.InvalidArgument, wantLevel: zerolog.DebugLevel},
+		{level: "warn", wantPrevious: "debug", wantLevel: zerolog.WarnLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			got, err := client.SetLogLevel(t.Context(), tt.level)
+			if grpcstatus.Code(err) != tt.wantCode {
+				t.Fatalf("SetLogLevel() error = %v, want %v", err, tt.wantCode)
+			}
+
+			if got != tt.wantPrevious {
+				t.Errorf("SetLogLevel() = %q, want %q", got, tt.wantPrevious)
+			}
+
+			if zerolog.GlobalLevel() != tt.wantLevel {
+				t.Errorf("global level = %v, want %v", zerolog.GlobalLevel(), tt.wantLevel)
+			}
+
+			level, err := client.GetLogLevel(t.Context())
+			if err != nil || level != tt.wantLevel.String() {
+				t.Errorf("GetLogLevel() = %q, %v, want %q", level, err, tt.wantLevel)
+			}
+		})
+	}
+}
+
+func TestAdmin_GetConfig(t *testing.T) {
+	cfg := &testConfig{Name: "users", Password: "secret"}
+	cfg.Database.Host = "db"
+	cfg.Database.DBUser = "users"
+	cfg.APIKeys = []map[string]string{{"name": "ci", "token": "abc"}}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		want     map[string]any
+		wantCode codes.Code
+	}{
+		{
+			name: "redacted",
+			opts: []Option{WithConfig(func() any { return cfg }), WithRedactedKeys("DB_USER")},
+			want: map[string]any{
+				"name":     "users",
+				"password": Redacted,
+				"database": map[string]any{"host": "db", "db_user": Redacted},
+				"api_keys": Redacted,
+			},
+		},
+		{name: "no config", wantCode: codes.FailedPrecondition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dump, err := newTestClient(t, tt.opts...).GetConfig(t.Context())
+			if grpcstatus.Code(err) != tt.wantCode {
+				t.Fatalf("GetConfig() error = %v, want %v", err, tt.wantCode)
+			}
+
+			if got := dump.AsMap(); tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdmin_ListInstances(t *testing.T) {
+	reg, err := static.NewRegistry(map[string][]string{"users": {"10.0.0.1:9000"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newTestClient(t, WithRegistry(reg))
+
+	tests := []struct {
+		service  string
+		want     []any
+		wantCode codes.Code
+	}{
+		{service: "users", want: []any{map[string]any{"service": "users", "address": "10.0.0.1:9000"}}},
+		{service: "orders", want: []any{}},
+		{service: "", wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q", tt.service), func(t *testing.T) {
+			instances, err := client.ListInstances(t.Context(), tt.service)
+			if grpcstatus.Code(err) != tt.wantCode {
+				t.Fatalf("ListInstances() error = %v, want %v", err, tt.wantCode)
+			}
+
+			if got := instances.AsMap()["instances"]; tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListInstances() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdmin_ReloadConfig(t *testing.T) {
+	reloads := 0
+	client := newTestClient(t, WithConfigReload(func(context.Context) error {
+		reloads++
+		if reloads > 1 {
+			return errors.New("invalid config")
+		}
+
+		return nil
+	}))
+
+	for _, want := range []codes.Code{codes.OK, codes.Internal} {
+		err := client.ReloadConfig(t.Context())
+		if grpcstatus.Code(err) != want {
+			t.Errorf("ReloadConfig() error = %v, want %v", err, want)
+		}
+	}
+
+	if reloads != 2 {
+		t.Errorf("reloads = %d, want 2", reloads)
+	}
+}
+
+func TestAdmin_ConfigManager(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	err := os.WriteFile(path, []byte("name: users\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := file.NewSource(path, yaml.NewEncoder())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := config.NewManager(config.WithSource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := m.Subscribe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer m.Unsubscribe(changes)
+
+	client := newTestClient(t, WithConfigManager(m))
+
+	// the subscriber is not waiting for changes during the reloads
+	for range 2 {
+		err = client.ReloadConfig(t.Context())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case change := <-changes:
+		if change.SourceName != config.ReloadSourceName {
+			t.Errorf("change source = %q, want %q", change.SourceName, config.ReloadSourceName)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reload not delivered")
+	}
+}
+
+func TestAdmin_Descriptor(t *testing.T) {
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(ServiceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := d.ParentFile().Path(); got != ServiceDesc.Metadata {
+		t.Errorf("descriptor file = %q, want %q", got, ServiceDesc.Metadata)
+	}
+}
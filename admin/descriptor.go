@@ -0,0 +1,53 @@
+package admin
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// the file descriptor of the service is registered, so that it can be described by grpc reflection.
+func init() {
+	methods := []struct {
+		name, input, output string
+	}{
+		{"GetStatus", "google.protobuf.Empty", "google.protobuf.Struct"},
+		{"GetLogLevel", "google.protobuf.Empty", "google.protobuf.StringValue"},
+		{"SetLogLevel", "google.protobuf.StringValue", "google.protobuf.StringValue"},
+		{"GetConfig", "google.protobuf.Empty", "google.protobuf.Struct"},
+		{"ListInstances", "google.protobuf.StringValue", "google.protobuf.Struct"},
+		{"ReloadConfig", "google.protobuf.Empty", "google.protobuf.Empty"},
+	}
+
+	service := &descriptorpb.ServiceDescriptorProto{Name: proto.String("Admin")}
+	for _, m := range methods {
+		service.Method = append(service.Method, &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(m.name),
+			InputType:  proto.String("." + m.input),
+			OutputType: proto.String("." + m.output),
+		})
+	}
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String(ServiceDesc.Metadata.(string)),
+		Package: proto.String("cadre.admin.v1"),
+		Dependency: []string{
+			"google/protobuf/empty.proto",
+			"google/protobuf/struct.proto",
+			"google/protobuf/wrappers.proto",
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{service},
+		Syntax:  proto.String("proto3"),
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		panic(fmt.Sprintf("invalid admin service descriptor: %v", err))
+	}
+
+	err = protoregistry.GlobalFiles.RegisterFile(file)
+	if err != nil {
+		panic(fmt.Sprintf("cannot register admin service descriptor: %v", err))
+	}
+}
@@ -0,0 +1,194 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// ServiceName is the full name of the admin gRPC service.
+	ServiceName = "cadre.admin.v1.Admin"
+
+	// TokenMetadataKey is the gRPC metadata key carrying the admin token.
+	TokenMetadataKey = "x-admin-token"
+)
+
+// AdminServer is the server API of the admin service.
+type AdminServer interface {
+	GetStatus(context.Context, *emptypb.Empty) (*structpb.Struct, error)
+	GetLogLevel(context.Context, *emptypb.Empty) (*wrapperspb.StringValue, error)
+	SetLogLevel(context.Context, *wrapperspb.StringValue) (*wrapperspb.StringValue, error)
+	GetConfig(context.Context, *emptypb.Empty) (*structpb.Struct, error)
+	ListInstances(context.Context, *wrapperspb.StringValue) (*structpb.Struct, error)
+	ReloadConfig(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+}
+
+// ServiceDesc describes the admin service; it is written by hand as the service uses well-known types only.
+var ServiceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unary("GetStatus", newEmpty, AdminServer.GetStatus),
+		unary("GetLogLevel", newEmpty, AdminServer.GetLogLevel),
+		unary("SetLogLevel", newString, AdminServer.SetLogLevel),
+		unary("GetConfig", newEmpty, AdminServer.GetConfig),
+		unary("ListInstances", newString, AdminServer.ListInstances),
+		unary("ReloadConfig", newEmpty, AdminServer.ReloadConfig),
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "cadre/admin/v1/admin.proto",
+}
+
+func newEmpty() *emptypb.Empty {
+	return &emptypb.Empty{}
+}
+
+func newString() *wrapperspb.StringValue {
+	return &wrapperspb.StringValue{}
+}
+
+// unary describes the method the same way protoc-gen-go-grpc does.
+func unary[Req, Resp proto.Message](
+	name string,
+	newReq func() Req,
+	call func(AdminServer, context.Context, Req) (Resp, error),
+) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(
+			srv any,
+			ctx context.Context,
+			dec func(any) error,
+			interceptor grpc.UnaryServerInterceptor,
+		) (any, error) {
+			in := newReq()
+
+			err := dec(in)
+			if err != nil {
+				return nil, err
+			}
+
+			if interceptor == nil {
+				return call(srv.(AdminServer), ctx, in)
+			}
+
+			info := &grpc.UnaryServerInfo{
+				Server:     srv,
+				FullMethod: "/" + ServiceName + "/" + name,
+			}
+
+			return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+				return call(srv.(AdminServer), ctx, req.(Req))
+			})
+		},
+	}
+}
+
+// Register registers the admin service to the registrar (e.g. grpc server).
+func Register(r grpc.ServiceRegistrar, s AdminServer) {
+	r.RegisterService(&ServiceDesc, s)
+}
+
+// UnaryServerInterceptor rejects calls of the admin service without the admin token with codes.Unauthenticated.
+// Calls of other services pass through.
+func (s *Server) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !strings.HasPrefix(info.FullMethod, "/"+ServiceName+"/") {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		values := md.Get(TokenMetadataKey)
+		if len(values) == 0 || subtle.ConstantTimeCompare([]byte(values[0]), []byte(s.token)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid admin token")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Client calls the admin service, attaching the admin token to every call.
+type Client struct {
+	cc    grpc.ClientConnInterface
+	token string
+}
+
+func NewClient(cc grpc.ClientConnInterface, token string) *Client {
+	return &Client{cc: cc, token: token}
+}
+
+func (c *Client) invoke(ctx context.Context, method string, req, resp proto.Message, opts ...grpc.CallOption) error {
+	ctx = metadata.AppendToOutgoingContext(ctx, TokenMetadataKey, c.token)
+
+	return c.cc.Invoke(ctx, "/"+ServiceName+"/"+method, req, resp, opts...)
+}
+
+// GetStatus returns the status report.
+func (c *Client) GetStatus(ctx context.Context, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	resp := &structpb.Struct{}
+
+	err := c.invoke(ctx, "GetStatus", &emptypb.Empty{}, resp, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// GetLogLevel returns the global log level.
+func (c *Client) GetLogLevel(ctx context.Context, opts ...grpc.CallOption) (string, error) {
+	resp := &wrapperspb.StringValue{}
+
+	err := c.invoke(ctx, "GetLogLevel", &emptypb.Empty{}, resp, opts...)
+
+	return resp.GetValue(), err
+}
+
+// SetLogLevel sets the global log level and returns the previous one.
+func (c *Client) SetLogLevel(ctx context.Context, level string, opts ...grpc.CallOption) (string, error) {
+	resp := &wrapperspb.StringValue{}
+
+	err := c.invoke(ctx, "SetLogLevel", wrapperspb.String(level), resp, opts...)
+
+	return resp.GetValue(), err
+}
+
+// GetConfig returns the configuration with secrets redacted.
+func (c *Client) GetConfig(ctx context.Context, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	resp := &structpb.Struct{}
+
+	err := c.invoke(ctx, "GetConfig", &emptypb.Empty{}, resp, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ListInstances lists instances of the service in the registry.
+func (c *Client) ListInstances(ctx context.Context, service string, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	resp := &structpb.Struct{}
+
+	err := c.invoke(ctx, "ListInstances", wrapperspb.String(service), resp, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ReloadConfig triggers a configuration reload.
+func (c *Client) ReloadConfig(ctx context.Context, opts ...grpc.CallOption) error {
+	return c.invoke(ctx, "ReloadConfig", &emptypb.Empty{}, &emptypb.Empty{}, opts...)
+}
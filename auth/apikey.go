@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/moderntv/cadre/config"
+)
+
+// APIKey describes an API key. Only the SHA-256 hash of the key is stored.
+type APIKey struct {
+	Name string `json:"name" yaml:"name"`
+	// Hash is the hex encoded SHA-256 of the key, optionally prefixed by `sha256:`. See HashAPIKey.
+	Hash    string   `json:"hash"              yaml:"hash"`
+	Subject string   `json:"subject"           yaml:"subject"`
+	Roles   []string `json:"roles,omitempty"   yaml:"roles,omitempty"`
+	Scopes  []string `json:"scopes,omitempty"  yaml:"scopes,omitempty"`
+}
+
+// APIKeysConfig is the reloadable API keys configuration.
+type APIKeysConfig struct {
+	Keys []APIKey `json:"keys" yaml:"keys"`
+}
+
+func (c *APIKeysConfig) PostLoad() error {
+	_, err := indexAPIKeys(c.Keys)
+
+	return err
+}
+
+// HashAPIKey returns the hash of the API key in the format expected by APIKey.Hash.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// APIKeyAuthenticator authenticates API keys.
+type APIKeyAuthenticator struct {
+	mu   sync.RWMutex
+	keys map[[sha256.Size]byte]APIKey
+}
+
+// NewAPIKeyAuthenticator creates an authenticator accepting the API keys.
+func NewAPIKeyAuthenticator(keys ...APIKey) (a *APIKeyAuthenticator, err error) {
+	a = &APIKeyAuthenticator{}
+
+	err = a.SetKeys(keys)
+
+	return
+}
+
+// SetKeys atomically replaces accepted API keys.
+func (a *APIKeyAuthenticator) SetKeys(keys []APIKey) error {
+	index, err := indexAPIKeys(keys)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.keys = index
+	a.mu.Unlock()
+
+	return nil
+}
+
+// WatchConfig keeps the API keys in sync with the configuration manager using config.Watch.
+// Keys failing to load are reported to onError and the previous keys stay in use.
+func (a *APIKeyAuthenticator) WatchConfig(ctx context.Context, m *config.Manager, onError func(error)) error {
+	return config.Watch(ctx, m, func() error { return a.loadConfig(m) }, onError)
+}
+
+func (a *APIKeyAuthenticator) loadConfig(m *config.Manager) (err error) {
+	cfg := &APIKeysConfig{}
+
+	err = m.Load(cfg)
+	if err != nil {
+		err = fmt.Errorf("cannot load api keys config: %w", err)
+		return
+	}
+
+	return a.SetKeys(cfg.Keys)
+}
+
+func (a *APIKeyAuthenticator) Authenticate(_ context.Context, credentials Credentials) (*Principal, error) {
+	if credentials.APIKey == "" {
+		return nil, ErrNoCredentials
+	}
+
+	a.mu.RLock()
+	key, ok := a.keys[sha256.Sum256([]byte(credentials.APIKey))]
+	a.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown api key", ErrInvalidCredentials)
+	}
+
+	return &Principal{
+		Subject: key.Subject,
+		Method:  MethodAPIKey,
+		Roles:   key.Roles,
+		Scopes:  key.Scopes,
+	}, nil
+}
+
+func indexAPIKeys(keys []APIKey) (index map[[sha256.Size]byte]APIKey, err error) {
+	index = make(map[[sha256.Size]byte]APIKey, len(keys))
+
+	for _, key := range keys {
+		if key.Subject == "" {
+			err = fmt.Errorf("api key `%s` has no subject", key.Name)
+			return
+		}
+
+		var d []byte
+
+		d, err = hex.DecodeString(strings.TrimPrefix(key.Hash, "sha256:"))
+		if err != nil || len(d) != sha256.Size {
+			err = errors.Join(fmt.Errorf("api key `%s` has invalid hash", key.Name), err)
+			return
+		}
+
+		index[[sha256.Size]byte(d)] = key
+	}
+
+	return
+}
@@ -0,0 +1,193 @@
+// Package auth provides authentication of gRPC calls and HTTP requests.
+//
+// Callers are authenticated by JWT bearer tokens validated against a JWKS (loaded from a file or URL
+// and refreshed to follow key rotation) or by API keys stored as SHA-256 hashes in a reloadable file.
+// The authenticated Principal is stored in the request context. Public gRPC methods and HTTP routes
+// can be exempted from authentication.
+//
+// Use the interceptors and middleware with cadre's WithUnaryInterceptors, WithStreamInterceptors and WithGlobalMiddleware.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+)
+
+var (
+	// ErrNoCredentials is returned when the request carries no credentials.
+	ErrNoCredentials = errors.New("no credentials provided")
+	// ErrInvalidCredentials is returned when the credentials were rejected.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+const (
+	MethodJWT    = "jwt"
+	MethodAPIKey = "api_key"
+)
+
+// Principal is the authenticated caller.
+type Principal struct {
+	Subject string         `json:"subject"`
+	Method  string         `json:"method"` // jwt or api_key
+	Roles   []string       `json:"roles,omitempty"`
+	Scopes  []string       `json:"scopes,omitempty"`
+	Claims  map[string]any `json:"claims,omitempty"`
+}
+
+// HasRole reports whether the principal has the role.
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// HasScope reports whether the principal has the scope.
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+type ctxKey struct{}
+
+// NewContext returns a new context carrying the principal.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext returns the principal stored in the context.
+func FromContext(ctx context.Context) (p *Principal, ok bool) {
+	p, ok = ctx.Value(ctxKey{}).(*Principal)
+
+	return
+}
+
+// Subject returns the subject of the authenticated principal or an empty string. Usable as a rate limiting key.
+func Subject(ctx context.Context) string {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	return p.Subject
+}
+
+// Credentials extracted from the request.
+type Credentials struct {
+	BearerToken string
+	APIKey      string
+}
+
+func (c Credentials) empty() bool {
+	return c.BearerToken == "" && c.APIKey == ""
+}
+
+// Authenticator verifies credentials. It returns ErrNoCredentials if the credentials it handles are missing.
+type Authenticator interface {
+	Authenticate(ctx context.Context, credentials Credentials) (*Principal, error)
+}
+
+// Auth authenticates requests using the configured authenticators.
+type Auth struct {
+	authenticators []Authenticator
+	publicPatterns []string
+}
+
+type Option func(*Auth) error
+
+// New creates a new Auth. At least one authenticator has to be configured.
+func New(opts ...Option) (a *Auth, err error) {
+	a = &Auth{}
+
+	for _, opt := range opts {
+		err = opt(a)
+		if err != nil {
+			err = fmt.Errorf("cannot apply auth option: %w", err)
+			return
+		}
+	}
+
+	if len(a.authenticators) == 0 {
+		err = errors.New("no authenticator configured")
+		return
+	}
+
+	return
+}
+
+// WithAuthenticator adds an authenticator. Authenticators are tried in order they were added.
+func WithAuthenticator(authenticator Authenticator) Option {
+	return func(a *Auth) error {
+		if authenticator == nil {
+			return errors.New("authenticator cannot be nil")
+		}
+
+		a.authenticators = append(a.authenticators, authenticator)
+
+		return nil
+	}
+}
+
+// WithPublic exempts gRPC methods and HTTP routes from authentication. Patterns use path.Match syntax
+// and are matched against gRPC full methods (`/grpc.health.v1.Health/*`) and `METHOD /route` of HTTP requests (`GET /status`).
+// Valid credentials sent to public endpoints are still authenticated.
+func WithPublic(patterns ...string) Option {
+	return func(a *Auth) error {
+		for _, pattern := range patterns {
+			_, err := path.Match(pattern, "")
+			if err != nil {
+				return fmt.Errorf("invalid public pattern `%s`: %w", pattern, err)
+			}
+		}
+
+		a.publicPatterns = append(a.publicPatterns, patterns...)
+
+		return nil
+	}
+}
+
+// Authenticate returns the principal of the first authenticator accepting the credentials.
+func (a *Auth) Authenticate(ctx context.Context, credentials Credentials) (*Principal, error) {
+	if credentials.empty() {
+		return nil, ErrNoCredentials
+	}
+
+	for _, authenticator := range a.authenticators {
+		p, err := authenticator.Authenticate(ctx, credentials)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return p, nil
+	}
+
+	return nil, ErrInvalidCredentials
+}
+
+func (a *Auth) isPublic(route string) bool {
+	for _, pattern := range a.publicPatterns {
+		if ok, _ := path.Match(pattern, route); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// authenticate returns the context with the authenticated principal.
+// Failed authentication of public routes is ignored.
+func (a *Auth) authenticate(ctx context.Context, route string, credentials Credentials) (context.Context, error) {
+	p, err := a.Authenticate(ctx, credentials)
+	if err != nil {
+		if a.isPublic(route) {
+			return ctx, nil
+		}
+
+		return ctx, err
+	}
+
+	return NewContext(ctx, p), nil
+}
@@ -0,0 +1,402 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+type testKey struct {
+	kid  string
+	priv ed25519.PrivateKey
+}
+
+func newTestKey(t *testing.T, kid string) testKey {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testKey{kid: kid, priv: priv}
+}
+
+func (k testKey) x() string {
+	return base64.RawURLEncoding.EncodeToString(k.priv.Public().(ed25519.PublicKey))
+}
+
+func (k testKey) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = k.kid
+
+	s, err := token.SignedString(k.priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func writeJWKS(t *testing.T, path string, keys ...testKey) {
+	t.Helper()
+
+	set := map[string][]map[string]string{}
+	for _, k := range keys {
+		set["keys"] = append(set["keys"], map[string]string{"kty": "OKP", "crv": "Ed25519", "kid": k.kid, "x": k.x()})
+	}
+
+	d, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(path, d, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestAuth returns auth accepting JWTs signed by the keys of the returned key set file and the API key
+// `secret` of subject `ci`.
+func newTestAuth(t *testing.T, keys ...testKey) (*Auth, string) {
+	t.Helper()
+
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksPath, keys...)
+
+	jwks, err := NewJWKSFromFile(t.Context(), jwksPath, WithMinRefreshInterval(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwtAuthenticator, err := NewJWTAuthenticator(jwks, WithIssuer("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apiKeyAuthenticator, err := NewAPIKeyAuthenticator(APIKey{
+		Name:    "ci",
+		Hash:    HashAPIKey("secret"),
+		Subject: "ci",
+		Roles:   []string{"deployer"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := New(
+		WithAuthenticator(jwtAuthenticator),
+		WithAuthenticator(apiKeyAuthenticator),
+		WithPublic("/grpc.health.v1.Health/*", "GET /status"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a, jwksPath
+}
+
+func TestAuth_Authenticate(t *testing.T) {
+	key := newTestKey(t, "k1")
+	a, _ := newTestAuth(t, key)
+
+	claims := func(iss string, exp time.Duration) jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":   "user-1",
+			"iss":   iss,
+			"exp":   time.Now().Add(exp).Unix(),
+			"roles": []string{"admin"},
+			"scope": "read write",
+		}
+	}
+
+	tests := []struct {
+		name        string
+		credentials Credentials
+		want        Principal
+		wantErr     error
+	}{
+		{
+			name:        "jwt",
+			credentials: Credentials{BearerToken: key.sign(t, claims("test", time.Hour))},
+			want:        Principal{Subject: "user-1", Method: MethodJWT, Roles: []string{"admin"}},
+		},
+		{
+			name:        "expired jwt",
+			credentials: Credentials{BearerToken: key.sign(t, claims("test", -time.Hour))},
+			wantErr:     ErrInvalidCredentials,
+		},
+		{
+			name:        "jwt of other issuer",
+			credentials: Credentials{BearerToken: key.sign(t, claims("other", time.Hour))},
+			wantErr:     ErrInvalidCredentials,
+		},
+		{
+			name:        "api key",
+			credentials: Credentials{APIKey: "secret"},
+			want:        Principal{Subject: "ci", Method: MethodAPIKey, Roles: []string{"deployer"}},
+		},
+		{name: "wrong api key", credentials: Credentials{APIKey: "wrong"}, wantErr: ErrInvalidCredentials},
+		{name: "no credentials", wantErr: ErrNoCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := a.Authenticate(t.Context(), tt.credentials)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if p.Subject != tt.want.Subject || p.Method != tt.want.Method || !slices.Equal(p.Roles, tt.want.Roles) {
+				t.Errorf("Authenticate() = %+v, want %+v", p, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuth_JWTKeyRotation(t *testing.T) {
+	oldKey := newTestKey(t, "old")
+	newKey := newTestKey(t, "new")
+	a, jwksPath := newTestAuth(t, oldKey)
+
+	token := newKey.sign(t, jwt.MapClaims{"sub": "user-1", "iss": "test", "exp": time.Now().Add(time.Hour).Unix()})
+
+	_, err := a.Authenticate(t.Context(), Credentials{BearerToken: token})
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Authenticate() error = %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	// unknown kid triggers refresh of the key set
+	writeJWKS(t, jwksPath, oldKey, newKey)
+
+	_, err = a.Authenticate(t.Context(), Credentials{BearerToken: token})
+	if err != nil {
+		t.Errorf("Authenticate() after rotation error = %v", err)
+	}
+}
+
+func TestAPIKeysConfig_PostLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     APIKey
+		wantErr bool
+	}{
+		{name: "valid", key: APIKey{Name: "a", Hash: HashAPIKey("a"), Subject: "a"}},
+		{name: "invalid hash", key: APIKey{Name: "a", Hash: "sha256:abc", Subject: "a"}, wantErr: true},
+		{name: "no subject", key: APIKey{Name: "a", Hash: HashAPIKey("a")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := APIKeysConfig{Keys: []APIKey{tt.key}}
+			if err := cfg.PostLoad(); (err != nil) != tt.wantErr {
+				t.Errorf("PostLoad() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuth_UnaryServerInterceptor(t *testing.T) {
+	a, _ := newTestAuth(t, newTestKey(t, "k1"))
+
+	tests := []struct {
+		name        string
+		method      string
+		md          metadata.MD
+		wantCode    codes.Code
+		wantSubject string
+	}{
+		{
+			name:        "api key",
+			method:      "/example.Service/Method",
+			md:          metadata.Pairs(APIKeyMetadataKey, "secret"),
+			wantSubject: "ci",
+		},
+		{name: "no credentials", method: "/example.Service/Method", wantCode: codes.Unauthenticated},
+		{
+			name:     "invalid token",
+			method:   "/example.Service/Method",
+			md:       metadata.Pairs("authorization", "Bearer invalid"),
+			wantCode: codes.Unauthenticated,
+		},
+		{name: "public method", method: "/grpc.health.v1.Health/Check"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subject string
+
+			ctx := metadata.NewIncomingContext(t.Context(), tt.md)
+
+			_, err := a.UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method},
+				func(ctx context.Context, _ any) (any, error) {
+					subject = Subject(ctx)
+					return nil, nil
+				},
+			)
+			if status.Code(err) != tt.wantCode || subject != tt.wantSubject {
+				t.Errorf("code = %v, subject = %q, want %v, %q", status.Code(err), subject, tt.wantCode, tt.wantSubject)
+			}
+		})
+	}
+}
+
+func TestAuth_Middleware(t *testing.T) {
+	a, _ := newTestAuth(t, newTestKey(t, "k1"))
+
+	handler := func(c *gin.Context) {
+		p, ok := GetPrincipal(c)
+		if !ok {
+			c.String(http.StatusOK, "anonymous")
+			return
+		}
+
+		c.String(http.StatusOK, p.Subject)
+	}
+
+	r := gin.New()
+	r.Use(a.Middleware())
+	r.GET("/status", handler)
+	r.GET("/private", handler)
+
+	tests := []struct {
+		name   string
+		path   string
+		apiKey string
+		code   int
+		body   string
+	}{
+		{name: "authenticated", path: "/private", apiKey: "secret", code: http.StatusOK, body: "ci"},
+		{name: "no credentials", path: "/private", code: http.StatusUnauthorized},
+		{name: "invalid credentials", path: "/private", apiKey: "wrong", code: http.StatusUnauthorized},
+		{name: "public", path: "/status", code: http.StatusOK, body: "anonymous"},
+		{name: "public authenticated", path: "/status", apiKey: "secret", code: http.StatusOK, body: "ci"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tt.apiKey)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.code || tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("response = %d %q, want %d %q", w.Code, w.Body.String(), tt.code, tt.body)
+			}
+		})
+	}
+}
+
+func TestJWKS_KeyUnavailableSource(t *testing.T) {
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksPath, newTestKey(t, "k1"))
+
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		http.ServeFile(w, r, jwksPath)
+	}))
+	defer server.Close()
+
+	jwks, err := NewJWKSFromURL(t.Context(), server.URL, WithMinRefreshInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pretend the interval since the initial load passed
+	jwks.lastAttempt = time.Time{}
+
+	// the first miss fails to refresh, the following ones do not retry until the interval passes again
+	_, err = jwks.Key(t.Context(), "unknown")
+	if err == nil || errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Key() error = %v, want refresh error", err)
+	}
+
+	_, err = jwks.Key(t.Context(), "unknown")
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Key() error = %v, want %v", err, ErrKeyNotFound)
+	}
+
+	if got := requests.Load(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	x := newTestKey(t, "k1").x()
+
+	tests := []struct {
+		name    string
+		jwks    string
+		kids    []string
+		wantErr bool
+	}{
+		{
+			name: "ed25519",
+			jwks: `{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"k1","x":"` + x + `"}]}`,
+			kids: []string{"k1"},
+		},
+		{
+			name: "unsupported keys are skipped",
+			jwks: `{"keys":[{"kty":"OKP","crv":"X25519","kid":"enc","x":"` + x + `"},{"kty":"oct","kid":"hmac"},` +
+				`{"kty":"EC","crv":"secp256k1","kid":"k256"},` +
+				`{"kty":"OKP","crv":"Ed25519","kid":"k1","x":"` + x + `"}]}`,
+			kids: []string{"k1"},
+		},
+		{
+			name: "encryption keys are skipped",
+			jwks: `{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"k1","use":"enc","x":"` + x + `"}]}`,
+		},
+		{
+			name:    "invalid supported key",
+			jwks:    `{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"k1","x":"AAAA"}]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := parseJWKS([]byte(tt.jwks))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseJWKS() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if kids := slices.Sorted(maps.Keys(keys)); !slices.Equal(kids, tt.kids) {
+				t.Errorf("parseJWKS() kids = %v, want %v", kids, tt.kids)
+			}
+		})
+	}
+}
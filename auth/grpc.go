@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// APIKeyMetadataKey is the gRPC metadata key carrying the API key.
+const APIKeyMetadataKey = "x-api-key"
+
+// UnaryServerInterceptor authenticates unary calls. Calls failing authentication are rejected with codes.Unauthenticated.
+func (a *Auth) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.authenticateGRPC(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor authenticates streams. Streams failing authentication are rejected with codes.Unauthenticated.
+func (a *Auth) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticateGRPC(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (a *Auth) authenticateGRPC(ctx context.Context, fullMethod string) (context.Context, error) {
+	var credentials Credentials
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			credentials.BearerToken = bearerToken(values[0])
+		}
+
+		if values := md.Get(APIKeyMetadataKey); len(values) > 0 {
+			credentials.APIKey = values[0]
+		}
+	}
+
+	ctx, err := a.authenticate(ctx, fullMethod, credentials)
+	if err != nil {
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return ctx, nil
+}
+
+func bearerToken(authorization string) string {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+type serverStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
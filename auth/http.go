@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/http/responses"
+	"github.com/moderntv/cadre/http/route"
+)
+
+// APIKeyHeader is the HTTP header carrying the API key.
+const APIKeyHeader = "X-API-Key"
+
+// Middleware returns gin middleware authenticating requests. Requests failing authentication are rejected with 401.
+func (a *Auth) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		credentials := Credentials{
+			BearerToken: bearerToken(c.GetHeader("Authorization")),
+			APIKey:      c.GetHeader(APIKeyHeader),
+		}
+
+		ctx, err := a.authenticate(c.Request.Context(), route.Method(c), credentials)
+		if err != nil {
+			errType := "INVALID_CREDENTIALS"
+			if errors.Is(err, ErrNoCredentials) {
+				errType = "NO_CREDENTIALS"
+			}
+
+			responses.Unauthorized(c, responses.Error{
+				Type:    errType,
+				Message: err.Error(),
+			})
+
+			return
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// GetPrincipal returns the authenticated principal of the request handled by gin.
+func GetPrincipal(c *gin.Context) (*Principal, bool) {
+	return FromContext(c.Request.Context())
+}
@@ -0,0 +1,397 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	ErrKeyNotFound = errors.New("signing key not found")
+
+	errUnsupportedKey = errors.New("unsupported key")
+)
+
+// JWKS is a JSON Web Key Set used to verify JWT signatures. It is periodically reloaded from its source
+// and also on demand when a token signed by an unknown key arrives, so that key rotation is followed.
+type JWKS struct {
+	load func(ctx context.Context) ([]byte, error)
+
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+	httpClient         *http.Client
+
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	lastAttempt time.Time
+	onError     func(error)
+
+	refreshes singleflight.Group
+}
+
+type JWKSOption func(*JWKS) error
+
+// NewJWKSFromFile loads the key set from a file. Refreshing stops when the context is done.
+func NewJWKSFromFile(ctx context.Context, path string, opts ...JWKSOption) (j *JWKS, err error) {
+	j, err = newJWKS(opts...)
+	if err != nil {
+		return
+	}
+
+	j.load = func(context.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	}
+
+	err = j.start(ctx)
+
+	return
+}
+
+// NewJWKSFromURL loads the key set from an URL (e.g. `https://issuer/.well-known/jwks.json`).
+// Refreshing stops when the context is done.
+func NewJWKSFromURL(ctx context.Context, url string, opts ...JWKSOption) (j *JWKS, err error) {
+	j, err = newJWKS(opts...)
+	if err != nil {
+		return
+	}
+
+	j.load = func(ctx context.Context) (d []byte, err error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return
+		}
+
+		res, err := j.httpClient.Do(req)
+		if err != nil {
+			return
+		}
+
+		defer func() {
+			_ = res.Body.Close()
+		}()
+
+		if res.StatusCode != http.StatusOK {
+			err = fmt.Errorf("unexpected jwks response status: %s", res.Status)
+			return
+		}
+
+		return io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	}
+
+	err = j.start(ctx)
+
+	return
+}
+
+func newJWKS(opts ...JWKSOption) (j *JWKS, err error) {
+	j = &JWKS{
+		refreshInterval:    time.Hour,
+		minRefreshInterval: time.Minute,
+		httpClient:         &http.Client{Timeout: 10 * time.Second},
+		keys:               map[string]crypto.PublicKey{},
+	}
+
+	for _, opt := range opts {
+		err = opt(j)
+		if err != nil {
+			err = fmt.Errorf("cannot apply jwks option: %w", err)
+			return
+		}
+	}
+
+	return
+}
+
+// start loads the key set and keeps refreshing it in background.
+func (j *JWKS) start(ctx context.Context) (err error) {
+	err = j.Refresh(ctx)
+	if err != nil {
+		return
+	}
+
+	go j.refreshLoop(ctx)
+
+	return
+}
+
+// WithRefreshInterval configures how often the key set is reloaded. Default is one hour.
+func WithRefreshInterval(d time.Duration) JWKSOption {
+	return func(j *JWKS) error {
+		if d <= 0 {
+			return errors.New("refresh interval has to be positive")
+		}
+
+		j.refreshInterval = d
+
+		return nil
+	}
+}
+
+// WithMinRefreshInterval limits how often an unknown key may trigger reloading of the key set. Default is one minute.
+func WithMinRefreshInterval(d time.Duration) JWKSOption {
+	return func(j *JWKS) error {
+		j.minRefreshInterval = d
+
+		return nil
+	}
+}
+
+// WithHTTPClient configures the http client used to fetch the key set from an URL.
+func WithHTTPClient(client *http.Client) JWKSOption {
+	return func(j *JWKS) error {
+		if client == nil {
+			return errors.New("http client cannot be nil")
+		}
+
+		j.httpClient = client
+
+		return nil
+	}
+}
+
+// WithRefreshErrorHandler configures a callback receiving errors of background refreshes.
+func WithRefreshErrorHandler(onError func(error)) JWKSOption {
+	return func(j *JWKS) error {
+		j.onError = onError
+
+		return nil
+	}
+}
+
+// Refresh reloads the key set from its source. Concurrent calls share a single load.
+func (j *JWKS) Refresh(ctx context.Context) error {
+	_, err, _ := j.refreshes.Do("", func() (any, error) {
+		return nil, j.refresh(ctx)
+	})
+
+	return err
+}
+
+func (j *JWKS) refresh(ctx context.Context) (err error) {
+	// the attempt is recorded even if it fails, so that unknown keys cannot make us hammer an unavailable source
+	j.mu.Lock()
+	j.lastAttempt = time.Now()
+	j.mu.Unlock()
+
+	d, err := j.load(ctx)
+	if err != nil {
+		err = fmt.Errorf("cannot load jwks: %w", err)
+		return
+	}
+
+	keys, err := parseJWKS(d)
+	if err != nil {
+		return
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.mu.Unlock()
+
+	return
+}
+
+// Key returns the public key with the key ID. An unknown key ID triggers a (rate limited) reload of the key set,
+// concurrent misses wait for the same reload. If kid is empty and the set contains a single key, that key is returned.
+func (j *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	key, ok := j.lookup(kid)
+	if ok {
+		return key, nil
+	}
+
+	_, err, _ := j.refreshes.Do("", func() (any, error) {
+		j.mu.RLock()
+		canRefresh := time.Since(j.lastAttempt) >= j.minRefreshInterval
+		j.mu.RUnlock()
+
+		if !canRefresh {
+			return nil, nil
+		}
+
+		// the load is shared, a canceled caller must not fail the others
+		return nil, j.refresh(context.WithoutCancel(ctx))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok = j.lookup(kid)
+	if ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: kid `%s`", ErrKeyNotFound, kid)
+}
+
+func (j *JWKS) lookup(kid string) (key crypto.PublicKey, ok bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	if kid == "" && len(j.keys) == 1 {
+		for _, key = range j.keys {
+			return key, true
+		}
+	}
+
+	key, ok = j.keys[kid]
+
+	return
+}
+
+func (j *JWKS) refreshLoop(ctx context.Context) {
+	t := time.NewTicker(j.refreshInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			err := j.Refresh(ctx)
+			if err != nil && j.onError != nil {
+				j.onError(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func parseJWKS(d []byte) (keys map[string]crypto.PublicKey, err error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	err = json.Unmarshal(d, &set)
+	if err != nil {
+		err = fmt.Errorf("cannot decode jwks: %w", err)
+		return
+	}
+
+	keys = map[string]crypto.PublicKey{}
+
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+
+		key, err = k.publicKey()
+		if errors.Is(err, errUnsupportedKey) {
+			// keys of other algorithms may be published alongside, they cannot sign tokens we accept
+			err = nil
+			continue
+		}
+
+		if err != nil {
+			err = fmt.Errorf("invalid jwk `%s`: %w", k.Kid, err)
+			return
+		}
+
+		keys[k.Kid] = key
+	}
+
+	return
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("rsa exponent too large")
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(exponent.Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("%w: curve `%s`", errUnsupportedKey, k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) > size || len(y) > size {
+			return nil, errors.New("invalid ec point")
+		}
+
+		point := make([]byte, 1+2*size)
+		point[0] = 4 // uncompressed
+		copy(point[1+size-len(x):], x)
+		copy(point[1+2*size-len(y):], y)
+
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("%w: curve `%s`", errUnsupportedKey, k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key size")
+		}
+
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("%w: key type `%s`", errUnsupportedKey, k.Kty)
+	}
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTAuthenticator authenticates bearer tokens signed by keys from a JWKS.
+type JWTAuthenticator struct {
+	keys          *JWKS
+	parserOptions []jwt.ParserOption
+	algorithms    []string
+	rolesClaim    string
+}
+
+type JWTOption func(*JWTAuthenticator) error
+
+// NewJWTAuthenticator creates an authenticator validating JWT signatures against the key set.
+// Only asymmetric signing algorithms are accepted (RS*, PS*, ES* and EdDSA) unless configured otherwise.
+func NewJWTAuthenticator(keys *JWKS, opts ...JWTOption) (a *JWTAuthenticator, err error) {
+	if keys == nil {
+		err = errors.New("jwks cannot be nil")
+		return
+	}
+
+	a = &JWTAuthenticator{
+		keys: keys,
+		algorithms: []string{
+			"RS256", "RS384", "RS512",
+			"PS256", "PS384", "PS512",
+			"ES256", "ES384", "ES512",
+			"EdDSA",
+		},
+		rolesClaim: "roles",
+	}
+
+	for _, opt := range opts {
+		err = opt(a)
+		if err != nil {
+			err = fmt.Errorf("cannot apply jwt option: %w", err)
+			return
+		}
+	}
+
+	return
+}
+
+// WithIssuer requires tokens to be issued by the issuer.
+func WithIssuer(issuer string) JWTOption {
+	return func(a *JWTAuthenticator) error {
+		a.parserOptions = append(a.parserOptions, jwt.WithIssuer(issuer))
+
+		return nil
+	}
+}
+
+// WithAudience requires tokens to be issued for (one of) the audience(s).
+func WithAudience(audience ...string) JWTOption {
+	return func(a *JWTAuthenticator) error {
+		a.parserOptions = append(a.parserOptions, jwt.WithAudience(audience...))
+
+		return nil
+	}
+}
+
+// WithLeeway configures tolerated clock skew when validating token times.
+func WithLeeway(leeway time.Duration) JWTOption {
+	return func(a *JWTAuthenticator) error {
+		a.parserOptions = append(a.parserOptions, jwt.WithLeeway(leeway))
+
+		return nil
+	}
+}
+
+// WithAlgorithms restricts accepted signing algorithms.
+func WithAlgorithms(algorithms ...string) JWTOption {
+	return func(a *JWTAuthenticator) error {
+		if len(algorithms) == 0 {
+			return errors.New("at least one algorithm has to be accepted")
+		}
+
+		a.algorithms = algorithms
+
+		return nil
+	}
+}
+
+// WithRolesClaim configures the claim containing the principal's roles. Default is `roles`.
+func WithRolesClaim(claim string) JWTOption {
+	return func(a *JWTAuthenticator) error {
+		a.rolesClaim = claim
+
+		return nil
+	}
+}
+
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, credentials Credentials) (*Principal, error) {
+	if credentials.BearerToken == "" {
+		return nil, ErrNoCredentials
+	}
+
+	opts := append([]jwt.ParserOption{
+		jwt.WithValidMethods(a.algorithms),
+		jwt.WithExpirationRequired(),
+	}, a.parserOptions...)
+
+	claims := jwt.MapClaims{}
+
+	_, err := jwt.ParseWithClaims(credentials.BearerToken, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		return a.keys.Key(ctx, kid)
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+
+	return &Principal{
+		Subject: subject,
+		Method:  MethodJWT,
+		Roles:   stringsClaim(claims[a.rolesClaim]),
+		Scopes:  scopes(claims),
+		Claims:  claims,
+	}, nil
+}
+
+// scopes reads the space delimited `scope` claim (RFC 8693) or the `scp` list.
+func scopes(claims jwt.MapClaims) []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+
+	return stringsClaim(claims["scp"])
+}
+
+func stringsClaim(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		s := make([]string, 0, len(v))
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				s = append(s, str)
+			}
+		}
+
+		return s
+	default:
+		return nil
+	}
+}
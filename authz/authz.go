@@ -0,0 +1,282 @@
+// Package authz provides policy based authorization of gRPC calls and HTTP requests.
+//
+// Rules grant roles or scopes of the principal authenticated by the auth package access to routes -
+// gRPC full methods (`/package.Service/Method`) or HTTP method and route (`GET /users/:id`) matched
+// using path.Match patterns. A request is allowed if any rule matching its route grants access to the principal.
+// Denied gRPC calls fail with codes.PermissionDenied, denied HTTP requests with 403.
+//
+// In dry-run mode denials are only logged and requests are let through.
+// Rules are usually loaded from a configuration file and hot-reloaded using WatchConfig.
+//
+// Use the interceptors and middleware after the auth ones with cadre's WithUnaryInterceptors,
+// WithStreamInterceptors and WithGlobalMiddleware.
+package authz
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sync"
+
+	"github.com/moderntv/cadre/auth"
+	"github.com/rs/zerolog"
+)
+
+var ErrInvalidRule = errors.New("invalid authorization rule")
+
+// Rule grants access to routes matching any of the patterns.
+type Rule struct {
+	// Name identifies the rule in decisions and logs. Defaults to `rule #<index>`.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Routes are path.Match patterns matched against the gRPC full method or `METHOD /route` of HTTP requests.
+	Routes []string `json:"routes" yaml:"routes"`
+	// Roles granted access. Principal needs to have at least one of them.
+	Roles []string `json:"roles,omitempty" yaml:"roles,omitempty"`
+	// Scopes granted access. Principal needs to have at least one of them.
+	Scopes []string `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+	// Public grants access to everyone including unauthenticated callers.
+	// Rules without roles, scopes and public flag grant access to any authenticated principal.
+	Public bool `json:"public,omitempty" yaml:"public,omitempty"`
+}
+
+func (r Rule) validate() error {
+	if len(r.Routes) == 0 {
+		return fmt.Errorf("%w: `%s`: no routes", ErrInvalidRule, r.Name)
+	}
+
+	for _, route := range r.Routes {
+		_, err := path.Match(route, "")
+		if err != nil {
+			return fmt.Errorf("%w: `%s`: route `%s`: %w", ErrInvalidRule, r.Name, route, err)
+		}
+	}
+
+	return nil
+}
+
+func (r Rule) matches(route string) bool {
+	for _, pattern := range r.Routes {
+		if ok, _ := path.Match(pattern, route); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r Rule) grants(p *auth.Principal) bool {
+	if r.Public {
+		return true
+	}
+
+	if p == nil {
+		return false
+	}
+
+	if len(r.Roles) == 0 && len(r.Scopes) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(r.Roles, p.HasRole) || slices.ContainsFunc(r.Scopes, p.HasScope)
+}
+
+// Config is the reloadable authorization configuration.
+type Config struct {
+	Rules []Rule `json:"rules" yaml:"rules"`
+	// DefaultAllow allows requests to routes not matched by any rule.
+	DefaultAllow bool `json:"default_allow,omitempty" yaml:"default_allow,omitempty"`
+	// DryRun logs denials instead of rejecting requests.
+	DryRun bool `json:"dry_run,omitempty" yaml:"dry_run,omitempty"`
+}
+
+func (c *Config) PostLoad() error {
+	for _, r := range c.Rules {
+		err := r.validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Decision is the result of the authorization of a request.
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	DryRun  bool   `json:"dry_run,omitempty"`
+	Route   string `json:"route"`
+	Subject string `json:"subject,omitempty"`
+	// Rule is the name of the rule that granted access.
+	Rule string `json:"rule,omitempty"`
+	// MatchedRules are names of all rules matching the route.
+	MatchedRules []string `json:"matched_rules,omitempty"`
+	Reason       string   `json:"reason"`
+}
+
+// Enforced reports whether the request has to be rejected.
+func (d Decision) Enforced() bool {
+	return !d.Allowed && !d.DryRun
+}
+
+type Authorizer struct {
+	mu     sync.RWMutex
+	config Config
+
+	logger zerolog.Logger
+}
+
+type Option func(*Authorizer) error
+
+// New creates a new authorizer. Without any rules all requests are denied unless WithDefaultAllow is used.
+func New(logger zerolog.Logger, opts ...Option) (a *Authorizer, err error) {
+	a = &Authorizer{
+		logger: logger.With().Str("component", "authz").Logger(),
+	}
+
+	for _, opt := range opts {
+		err = opt(a)
+		if err != nil {
+			err = fmt.Errorf("cannot apply authorizer option: %w", err)
+			return
+		}
+	}
+
+	err = a.Reload(&a.config)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// WithRules configures the initial authorization rules.
+func WithRules(rules ...Rule) Option {
+	return func(a *Authorizer) error {
+		a.config.Rules = append(a.config.Rules, rules...)
+
+		return nil
+	}
+}
+
+// WithDefaultAllow allows requests to routes not matched by any rule.
+func WithDefaultAllow() Option {
+	return func(a *Authorizer) error {
+		a.config.DefaultAllow = true
+
+		return nil
+	}
+}
+
+// WithDryRun only logs denials instead of rejecting requests.
+func WithDryRun() Option {
+	return func(a *Authorizer) error {
+		a.config.DryRun = true
+
+		return nil
+	}
+}
+
+// Reload atomically replaces the authorization configuration.
+func (a *Authorizer) Reload(cfg *Config) error {
+	err := cfg.PostLoad()
+	if err != nil {
+		return err
+	}
+
+	rules := make([]Rule, len(cfg.Rules))
+	for i, r := range cfg.Rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule #%d", i)
+		}
+
+		rules[i] = r
+	}
+
+	a.mu.Lock()
+	a.config = Config{
+		Rules:        rules,
+		DefaultAllow: cfg.DefaultAllow,
+		DryRun:       cfg.DryRun,
+	}
+	a.mu.Unlock()
+
+	return nil
+}
+
+// Config returns the currently applied configuration.
+func (a *Authorizer) Config() Config {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	cfg := a.config
+	cfg.Rules = slices.Clone(cfg.Rules)
+
+	return cfg
+}
+
+// Explain evaluates the rules for the principal (nil if unauthenticated) and route without enforcing the decision.
+func (a *Authorizer) Explain(p *auth.Principal, route string) (d Decision) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	d = Decision{
+		DryRun: a.config.DryRun,
+		Route:  route,
+	}
+	if p != nil {
+		d.Subject = p.Subject
+	}
+
+	for _, r := range a.config.Rules {
+		if !r.matches(route) {
+			continue
+		}
+
+		d.MatchedRules = append(d.MatchedRules, r.Name)
+
+		if !d.Allowed && r.grants(p) {
+			d.Allowed = true
+			d.Rule = r.Name
+		}
+	}
+
+	switch {
+	case d.Allowed:
+		d.Reason = fmt.Sprintf("granted by rule `%s`", d.Rule)
+	case len(d.MatchedRules) > 0 && p == nil:
+		d.Reason = "unauthenticated caller is not granted access by any matching rule"
+	case len(d.MatchedRules) > 0:
+		d.Reason = "principal has none of the roles or scopes required by matching rules"
+	case a.config.DefaultAllow:
+		d.Allowed = true
+		d.Reason = "no rule matches the route, allowed by default"
+	default:
+		d.Reason = "no rule matches the route, denied by default"
+	}
+
+	return
+}
+
+// Authorize evaluates the rules for the principal and route and logs denials.
+func (a *Authorizer) Authorize(p *auth.Principal, transport, route string) Decision {
+	d := a.Explain(p, route)
+	if d.Allowed {
+		return d
+	}
+
+	// would-be denials are what dry-run mode is for, make them stand out
+	e, msg := a.logger.Info(), "request denied: "
+	if d.DryRun {
+		e, msg = a.logger.Warn(), "request would be denied: "
+	}
+
+	e.
+		Str("transport", transport).
+		Str("route", route).
+		Str("subject", d.Subject).
+		Strs("matched_rules", d.MatchedRules).
+		Msg(msg + d.Reason)
+
+	return d
+}
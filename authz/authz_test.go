@@ -0,0 +1,264 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/auth"
+	"github.com/moderntv/cadre/config"
+	"github.com/moderntv/cadre/config/encoder/yaml"
+	"github.com/moderntv/cadre/config/source/file"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+var testRules = []Rule{
+	{Name: "health", Routes: []string{"/grpc.health.v1.Health/*", "GET /status"}, Public: true},
+	{Name: "readers", Routes: []string{"/example.UserService/Get*", "GET /users/*"}, Scopes: []string{"users:read"}},
+	{Name: "admins", Routes: []string{"/example.UserService/*", "* /users/*"}, Roles: []string{"admin"}},
+	{Name: "profile", Routes: []string{"GET /me"}},
+}
+
+var (
+	admin  = &auth.Principal{Subject: "admin", Roles: []string{"admin"}}
+	reader = &auth.Principal{Subject: "reader", Scopes: []string{"users:read"}}
+)
+
+func TestAuthorizer_Explain(t *testing.T) {
+	tests := []struct {
+		name         string
+		defaultAllow bool
+		principal    *auth.Principal
+		route        string
+		allowed      bool
+		rule         string
+	}{
+		{name: "public", route: "/grpc.health.v1.Health/Check", allowed: true, rule: "health"},
+		{name: "scope", principal: reader, route: "/example.UserService/GetUser", allowed: true, rule: "readers"},
+		{name: "missing role", principal: reader, route: "/example.UserService/DeleteUser"},
+		{name: "role", principal: admin, route: "/example.UserService/DeleteUser", allowed: true, rule: "admins"},
+		{name: "http role", principal: admin, route: "DELETE /users/:id", allowed: true, rule: "admins"},
+		{name: "unauthenticated", route: "GET /users/:id"},
+		{name: "any authenticated", principal: reader, route: "GET /me", allowed: true, rule: "profile"},
+		{name: "unauthenticated any authenticated", route: "GET /me"},
+		{name: "no rule", principal: admin, route: "/example.OtherService/Method"},
+		{name: "default allow", defaultAllow: true, route: "/example.OtherService/Method", allowed: true},
+		{name: "default allow matched rule", defaultAllow: true, route: "GET /users/:id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []Option{WithRules(testRules...)}
+			if tt.defaultAllow {
+				opts = append(opts, WithDefaultAllow())
+			}
+
+			a, err := New(zerolog.Nop(), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			d := a.Explain(tt.principal, tt.route)
+			if d.Allowed != tt.allowed || d.Rule != tt.rule {
+				t.Errorf("Explain() = %v by %q (%s), want %v by %q", d.Allowed, d.Rule, d.Reason, tt.allowed, tt.rule)
+			}
+		})
+	}
+}
+
+func TestNew_invalidRule(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{name: "no routes", rule: Rule{Name: "invalid"}},
+		{name: "invalid route", rule: Rule{Routes: []string{"[a-"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(zerolog.Nop(), WithRules(tt.rule))
+			if !errors.Is(err, ErrInvalidRule) {
+				t.Errorf("New() error = %v, want %v", err, ErrInvalidRule)
+			}
+		})
+	}
+}
+
+func TestAuthorizer_UnaryServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		dryRun    bool
+		wantCode  codes.Code
+		wantLog   string
+	}{
+		{name: "allowed", principal: admin},
+		{name: "denied", principal: reader, wantCode: codes.PermissionDenied},
+		{name: "dry run", principal: reader, dryRun: true, wantLog: "request would be denied"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+
+			opts := []Option{WithRules(testRules...)}
+			if tt.dryRun {
+				opts = append(opts, WithDryRun())
+			}
+
+			a, err := New(zerolog.New(&logs), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = a.UnaryServerInterceptor()(
+				auth.NewContext(t.Context(), tt.principal),
+				nil,
+				&grpc.UnaryServerInfo{FullMethod: "/example.UserService/DeleteUser"},
+				func(context.Context, any) (any, error) { return nil, nil },
+			)
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("code = %v, want %v", status.Code(err), tt.wantCode)
+			}
+
+			if !strings.Contains(logs.String(), tt.wantLog) {
+				t.Errorf("logs = %q, want %q", logs.String(), tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestAuthorizer_Middleware(t *testing.T) {
+	a, err := New(zerolog.Nop(), WithRules(testRules...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		want      int
+	}{
+		{name: "allowed", principal: admin, want: http.StatusNoContent},
+		{name: "denied", principal: reader, want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), tt.principal))
+			}, a.Middleware())
+			r.DELETE("/users/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizer_ExplainHandler(t *testing.T) {
+	a, err := New(zerolog.Nop(), WithRules(testRules...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.POST("/authz/explain", a.ExplainHandler())
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantRule string
+	}{
+		{
+			name:     "explained",
+			body:     `{"principal":{"subject":"x","roles":["admin"]},"route":"DELETE /users/:id"}`,
+			wantCode: http.StatusOK,
+			wantRule: "admins",
+		},
+		{name: "no route", body: `{}`, wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/authz/explain", strings.NewReader(tt.body)))
+
+			var resp struct {
+				Data Decision `json:"data"`
+			}
+
+			_ = json.Unmarshal(w.Body.Bytes(), &resp)
+
+			if w.Code != tt.wantCode || resp.Data.Rule != tt.wantRule {
+				t.Errorf("response = %d %s, want %d with rule %q", w.Code, w.Body.String(), tt.wantCode, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestAuthorizer_WatchConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "authz.yaml")
+	writeRules := func(route string) {
+		err := os.WriteFile(path, []byte("rules:\n  - routes: [\""+route+"\"]\n    public: true\n"), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeRules("GET /a")
+
+	src, err := file.NewSource(path, yaml.NewEncoder())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := config.NewManager(config.WithSource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := New(zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = a.WatchConfig(t.Context(), m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !a.Explain(nil, "GET /a").Allowed || a.Explain(nil, "GET /b").Allowed {
+		t.Fatal("initial rules are not applied")
+	}
+
+	writeRules("GET /b")
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if a.Explain(nil, "GET /b").Allowed && !a.Explain(nil, "GET /a").Allowed {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("changed rules are not applied")
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/moderntv/cadre/config"
+)
+
+// WatchConfig hot-reloads the rules from the configuration manager (see config.Watch); invalid rules are reported
+// to onError while the previous ones remain effective.
+func (a *Authorizer) WatchConfig(ctx context.Context, m *config.Manager, onError func(error)) error {
+	return config.Watch(ctx, m, func() error { return a.loadConfig(m) }, onError)
+}
+
+func (a *Authorizer) loadConfig(m *config.Manager) (err error) {
+	cfg := &Config{}
+
+	err = m.Load(cfg)
+	if err != nil {
+		err = fmt.Errorf("cannot load authorization config: %w", err)
+		return
+	}
+
+	return a.Reload(cfg)
+}
@@ -0,0 +1,47 @@
+package authz
+
+import (
+	"context"
+
+	"github.com/moderntv/cadre/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const transportGRPC = "grpc"
+
+// UnaryServerInterceptor rejects unauthorized unary calls with codes.PermissionDenied.
+func (a *Authorizer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		err := a.authorizeGRPC(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor rejects unauthorized streams with codes.PermissionDenied.
+func (a *Authorizer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := a.authorizeGRPC(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func (a *Authorizer) authorizeGRPC(ctx context.Context, fullMethod string) error {
+	p, _ := auth.FromContext(ctx)
+
+	d := a.Authorize(p, transportGRPC, fullMethod)
+	if d.Enforced() {
+		return status.Error(codes.PermissionDenied, d.Reason)
+	}
+
+	return nil
+}
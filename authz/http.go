@@ -0,0 +1,55 @@
+package authz
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/auth"
+	"github.com/moderntv/cadre/http/responses"
+	"github.com/moderntv/cadre/http/route"
+)
+
+const transportHTTP = "http"
+
+// Middleware returns gin middleware rejecting unauthorized requests with 403 Forbidden.
+// Requests are matched by `METHOD /route` where route is the registered gin route (e.g. `GET /users/:id`)
+// or the pattern of the grpc-gateway handler (see package http/route).
+func (a *Authorizer) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, _ := auth.GetPrincipal(c)
+
+		d := a.Authorize(p, transportHTTP, route.Method(c))
+		if d.Enforced() {
+			responses.Forbidden(c, responses.Error{
+				Type:    "PERMISSION_DENIED",
+				Message: d.Reason,
+			})
+
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ExplainRequest is the body of the explain endpoint.
+type ExplainRequest struct {
+	// Principal to evaluate the rules for. Unauthenticated caller if omitted.
+	Principal *auth.Principal `json:"principal"`
+	// Route is the gRPC full method or `METHOD /route` of HTTP request.
+	Route string `json:"route" binding:"required"`
+}
+
+// ExplainHandler returns an admin handler explaining the authorization decision for the principal and route
+// in the ExplainRequest body. It should be exposed only on an internal server or behind authorization itself.
+func (a *Authorizer) ExplainHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req ExplainRequest
+
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			responses.CannotBind(c, err)
+			return
+		}
+
+		responses.Ok(c, a.Explain(req.Principal, req.Route))
+	}
+}
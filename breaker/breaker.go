@@ -0,0 +1,418 @@
+// Package breaker provides a circuit breaker protecting outgoing gRPC calls and HTTP requests.
+//
+// The breaker is closed while the downstream is healthy. It opens when the error rate over a rolling
+// window exceeds the threshold (once there are enough requests in the window) or when too many requests
+// fail in a row. While open, requests fail fast with ErrOpen (codes.Unavailable for gRPC). After the open
+// timeout the breaker lets a limited number of probes through (half-open) - it closes once they all succeed
+// and opens again when any of them fails.
+//
+// Every breaker reports a status component (WARN while open or half-open) and exports its state and state
+// transitions as metrics.
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/moderntv/cadre/metrics"
+	"github.com/moderntv/cadre/status"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrOpen is returned for requests rejected by an open breaker.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State of a breaker.
+type State int
+
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+var stateNames = map[State]string{
+	StateClosed:   "closed",
+	StateHalfOpen: "half_open",
+	StateOpen:     "open",
+}
+
+func (s State) String() string {
+	return stateNames[s]
+}
+
+// Outcome of a request admitted by the breaker.
+type Outcome int
+
+const (
+	// Success is recorded for requests the downstream handled.
+	Success Outcome = iota
+	// Failure is recorded for requests failed because of the downstream.
+	Failure
+	// Ignore releases the request without recording an outcome (e.g. canceled by the caller). An ignored
+	// half-open probe frees its slot for another probe.
+	Ignore
+)
+
+type bucket struct {
+	index     int64
+	successes int
+	failures  int
+}
+
+type Breaker struct {
+	mu sync.Mutex
+
+	name       string
+	state      State
+	generation uint64 // incremented on every transition; outcomes of older requests are ignored
+	openedAt   time.Time
+
+	// closed state
+	buckets        []bucket
+	bucketDuration time.Duration
+	consecutive    int
+
+	// half-open state
+	probes         int // in flight
+	probeSuccesses int
+
+	errorRate           float64
+	minRequests         int
+	consecutiveFailures int
+	openTimeout         time.Duration
+	halfOpenProbes      int
+
+	componentName string
+	component     *status.ComponentStatus
+
+	now func() time.Time
+
+	stateGauge  *prometheus.GaugeVec
+	transitions *prometheus.CounterVec
+	rejected    *prometheus.CounterVec
+}
+
+type Option func(*Breaker) error
+
+// New creates a new closed breaker exporting its state to the metrics registry and to a component
+// of the status.
+func New(name string, metricsRegistry *metrics.Registry, st *status.Status, opts ...Option) (b *Breaker, err error) {
+	if name == "" {
+		err = errors.New("breaker name cannot be empty")
+		return
+	}
+
+	b = &Breaker{
+		name:                name,
+		buckets:             make([]bucket, 10),
+		bucketDuration:      time.Second,
+		errorRate:           0.5,
+		minRequests:         20,
+		consecutiveFailures: 5,
+		openTimeout:         30 * time.Second,
+		halfOpenProbes:      1,
+		componentName:       "breaker_" + name,
+		now:                 time.Now,
+	}
+
+	for _, opt := range opts {
+		err = opt(b)
+		if err != nil {
+			err = fmt.Errorf("cannot apply breaker option: %w", err)
+			return
+		}
+	}
+
+	b.component, err = st.RegisterOrGet(b.componentName)
+	if err != nil {
+		err = fmt.Errorf("cannot register breaker status: %w", err)
+		return
+	}
+
+	b.component.SetStatus(status.OK, "closed")
+
+	b.stateGauge, err = metricsRegistry.RegisterOrGetNewGaugeVec(
+		"breaker_state",
+		prometheus.GaugeOpts{
+			Subsystem: "breaker",
+			Name:      "state",
+			Help:      "Current state of the circuit breaker (0 closed, 1 half-open, 2 open)",
+		},
+		[]string{"breaker"},
+	)
+	if err != nil {
+		err = fmt.Errorf("cannot register breaker metrics: %w", err)
+		return
+	}
+
+	b.transitions, err = metricsRegistry.RegisterOrGetNewCounterVec(
+		"breaker_transitions_total",
+		prometheus.CounterOpts{
+			Subsystem: "breaker",
+			Name:      "transitions_total",
+			Help:      "State transitions of the circuit breaker",
+		},
+		[]string{"breaker", "from", "to"},
+	)
+	if err != nil {
+		err = fmt.Errorf("cannot register breaker metrics: %w", err)
+		return
+	}
+
+	b.rejected, err = metricsRegistry.RegisterOrGetNewCounterVec(
+		"breaker_rejected_total",
+		prometheus.CounterOpts{
+			Subsystem: "breaker",
+			Name:      "rejected_total",
+			Help:      "Requests rejected by the circuit breaker",
+		},
+		[]string{"breaker"},
+	)
+	if err != nil {
+		err = fmt.Errorf("cannot register breaker metrics: %w", err)
+		return
+	}
+
+	b.stateGauge.WithLabelValues(name).Set(float64(StateClosed))
+
+	return
+}
+
+// WithErrorRate opens the breaker when at least rate (0, 1] of requests in the window fail and there
+// were at least minRequests requests. Defaults to 0.5 and 20.
+func WithErrorRate(rate float64, minRequests int) Option {
+	return func(b *Breaker) error {
+		if rate <= 0 || rate > 1 || minRequests < 1 {
+			return errors.New("error rate has to be in (0, 1] and min requests positive")
+		}
+
+		b.errorRate = rate
+		b.minRequests = minRequests
+
+		return nil
+	}
+}
+
+// WithWindow configures the rolling window of the error rate split into the number of buckets.
+// Defaults to 10s in 10 buckets.
+func WithWindow(window time.Duration, buckets int) Option {
+	return func(b *Breaker) error {
+		if buckets < 1 || window < time.Duration(buckets) {
+			return errors.New("window has to be split into at least one bucket")
+		}
+
+		b.buckets = make([]bucket, buckets)
+		b.bucketDuration = window / time.Duration(buckets)
+
+		return nil
+	}
+}
+
+// WithConsecutiveFailures opens the breaker after n failures in a row; zero disables the threshold.
+// Defaults to 5.
+func WithConsecutiveFailures(n int) Option {
+	return func(b *Breaker) error {
+		if n < 0 {
+			return errors.New("consecutive failures cannot be negative")
+		}
+
+		b.consecutiveFailures = n
+
+		return nil
+	}
+}
+
+// WithOpenTimeout configures how long the breaker stays open before probing. Defaults to 30s.
+func WithOpenTimeout(timeout time.Duration) Option {
+	return func(b *Breaker) error {
+		if timeout <= 0 {
+			return errors.New("open timeout has to be positive")
+		}
+
+		b.openTimeout = timeout
+
+		return nil
+	}
+}
+
+// WithHalfOpenProbes configures how many concurrent probes are let through in the half-open state;
+// the breaker closes once all of them succeed. Defaults to 1.
+func WithHalfOpenProbes(n int) Option {
+	return func(b *Breaker) error {
+		if n < 1 {
+			return errors.New("half-open probes have to be positive")
+		}
+
+		b.halfOpenProbes = n
+
+		return nil
+	}
+}
+
+// WithComponentName configures the name of the status component. Defaults to breaker_<name>.
+func WithComponentName(name string) Option {
+	return func(b *Breaker) error {
+		if name == "" {
+			return errors.New("component name cannot be empty")
+		}
+
+		b.componentName = name
+
+		return nil
+	}
+}
+
+// Name returns the name of the breaker.
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.checkTimeout()
+
+	return b.state
+}
+
+// Allow admits a request or rejects it with ErrOpen. The returned done function has to be called with
+// the outcome of the admitted request; requests which should not count (e.g. canceled by the caller)
+// are reported with Ignore.
+func (b *Breaker) Allow() (done func(outcome Outcome), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.checkTimeout()
+
+	switch b.state {
+	case StateOpen:
+		b.rejected.WithLabelValues(b.name).Inc()
+		return nil, ErrOpen
+
+	case StateHalfOpen:
+		if b.probes >= b.halfOpenProbes-b.probeSuccesses {
+			b.rejected.WithLabelValues(b.name).Inc()
+			return nil, ErrOpen
+		}
+
+		b.probes++
+	}
+
+	generation := b.generation
+
+	var once sync.Once
+
+	return func(outcome Outcome) {
+		once.Do(func() {
+			b.done(generation, outcome)
+		})
+	}, nil
+}
+
+func (b *Breaker) done(generation uint64, outcome Outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case StateClosed:
+		if outcome != Ignore {
+			b.record(outcome == Success)
+		}
+
+	case StateHalfOpen:
+		b.probes--
+
+		switch outcome {
+		case Ignore:
+			return
+		case Failure:
+			b.transition(StateOpen, "probe failed")
+			return
+		}
+
+		b.probeSuccesses++
+		if b.probeSuccesses >= b.halfOpenProbes {
+			b.transition(StateClosed, "closed")
+		}
+	}
+}
+
+// record adds the outcome to the window and opens the breaker when a threshold is exceeded.
+func (b *Breaker) record(success bool) {
+	index := b.now().UnixNano() / int64(b.bucketDuration)
+
+	bk := &b.buckets[index%int64(len(b.buckets))]
+	if bk.index != index {
+		*bk = bucket{index: index}
+	}
+
+	if success {
+		bk.successes++
+		b.consecutive = 0
+
+		return
+	}
+
+	bk.failures++
+	b.consecutive++
+
+	if b.consecutiveFailures > 0 && b.consecutive >= b.consecutiveFailures {
+		b.transition(StateOpen, fmt.Sprintf("%d consecutive failures", b.consecutive))
+		return
+	}
+
+	var total, failures int
+
+	for _, bk := range b.buckets {
+		if bk.index > index-int64(len(b.buckets)) {
+			total += bk.successes + bk.failures
+			failures += bk.failures
+		}
+	}
+
+	if total >= b.minRequests && float64(failures)/float64(total) >= b.errorRate {
+		b.transition(StateOpen, fmt.Sprintf("error rate %.2f", float64(failures)/float64(total)))
+	}
+}
+
+// checkTimeout moves an open breaker to half-open after the open timeout.
+func (b *Breaker) checkTimeout() {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.openTimeout {
+		b.transition(StateHalfOpen, "probing")
+	}
+}
+
+func (b *Breaker) transition(to State, reason string) {
+	from := b.state
+
+	b.state = to
+	b.generation++
+	b.consecutive = 0
+	b.probes = 0
+	b.probeSuccesses = 0
+
+	switch to {
+	case StateClosed:
+		clear(b.buckets)
+		b.component.SetStatus(status.OK, reason)
+
+	case StateOpen:
+		b.openedAt = b.now()
+		b.component.SetStatus(status.WARN, "open: "+reason)
+
+	case StateHalfOpen:
+		b.component.SetStatus(status.WARN, "half-open: "+reason)
+	}
+
+	b.stateGauge.WithLabelValues(b.name).Set(float64(to))
+	b.transitions.WithLabelValues(b.name, from.String(), to.String()).Inc()
+}
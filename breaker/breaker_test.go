@@ -0,0 +1,350 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moderntv/cadre/metrics"
+	"github.com/moderntv/cadre/status"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// newTestBreaker returns a breaker with a fake clock advanced by the returned function.
+func newTestBreaker(t *testing.T, opts ...Option) (*Breaker, *status.Status, func(time.Duration)) {
+	t.Helper()
+
+	registry, err := metrics.NewRegistry("test", prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := status.NewStatus("test")
+
+	b, err := New("users", registry, st, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1000, 0)
+	b.now = func() time.Time { return now }
+
+	return b, st, func(d time.Duration) { now = now.Add(d) }
+}
+
+// open trips the breaker configured with one consecutive failure and a second open timeout, moving it to
+// half-open if probe is set.
+func open(t *testing.T, b *Breaker, advance func(time.Duration), probe bool) {
+	t.Helper()
+
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done(Failure)
+
+	if probe {
+		advance(time.Second)
+	}
+}
+
+func TestBreaker_Allow(t *testing.T) {
+	type step struct {
+		advance  time.Duration
+		outcome  Outcome
+		rejected bool
+	}
+
+	s, f, i := step{outcome: Success}, step{outcome: Failure}, step{outcome: Ignore}
+	later := func(s step) step {
+		s.advance = time.Second
+		return s
+	}
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		steps      []step
+		wantState  State
+		wantStatus status.StatusType
+	}{
+		{
+			name:       "failures interrupted by success",
+			opts:       []Option{WithConsecutiveFailures(3)},
+			steps:      []step{f, f, s, f, f},
+			wantState:  StateClosed,
+			wantStatus: status.OK,
+		},
+		{
+			name:       "consecutive failures",
+			opts:       []Option{WithConsecutiveFailures(3)},
+			steps:      []step{f, f, s, f, f, f, {rejected: true}},
+			wantState:  StateOpen,
+			wantStatus: status.WARN,
+		},
+		{
+			name:       "ignored outcomes",
+			opts:       []Option{WithConsecutiveFailures(2)},
+			steps:      []step{f, i, i},
+			wantState:  StateClosed,
+			wantStatus: status.OK,
+		},
+		{
+			name:       "error rate",
+			opts:       []Option{WithConsecutiveFailures(0), WithErrorRate(0.5, 10)},
+			steps:      []step{s, f, s, f, s, f, s, f, s, f},
+			wantState:  StateOpen,
+			wantStatus: status.WARN,
+		},
+		{
+			name:       "failures outside of window",
+			opts:       []Option{WithConsecutiveFailures(0), WithErrorRate(0.5, 4), WithWindow(time.Second, 2)},
+			steps:      []step{f, f, s, later(s), f, s},
+			wantState:  StateClosed,
+			wantStatus: status.OK,
+		},
+		{
+			name:       "probe succeeded",
+			opts:       []Option{WithConsecutiveFailures(1), WithOpenTimeout(time.Second)},
+			steps:      []step{f, later(s)},
+			wantState:  StateClosed,
+			wantStatus: status.OK,
+		},
+		{
+			name:       "probe failed",
+			opts:       []Option{WithConsecutiveFailures(1), WithOpenTimeout(time.Second)},
+			steps:      []step{f, later(f), {rejected: true}},
+			wantState:  StateOpen,
+			wantStatus: status.WARN,
+		},
+		{
+			name:       "probe ignored",
+			opts:       []Option{WithConsecutiveFailures(1), WithOpenTimeout(time.Second)},
+			steps:      []step{f, later(i)},
+			wantState:  StateHalfOpen,
+			wantStatus: status.WARN,
+		},
+		{
+			name:       "all probes succeeded",
+			opts:       []Option{WithConsecutiveFailures(1), WithOpenTimeout(time.Second), WithHalfOpenProbes(2)},
+			steps:      []step{f, later(s), i, s},
+			wantState:  StateClosed,
+			wantStatus: status.OK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, st, advance := newTestBreaker(t, tt.opts...)
+
+			for n, step := range tt.steps {
+				advance(step.advance)
+
+				done, err := b.Allow()
+				if step.rejected != errors.Is(err, ErrOpen) {
+					t.Fatalf("step %d: Allow() error = %v, want rejected %v", n, err, step.rejected)
+				}
+
+				if err == nil {
+					done(step.outcome)
+				}
+			}
+
+			if got := b.State(); got != tt.wantState {
+				t.Errorf("State() = %v, want %v", got, tt.wantState)
+			}
+
+			if got := st.Report().Components["breaker_users"].Status; got != tt.wantStatus {
+				t.Errorf("component status = %v, want %v", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestBreaker_AllowHalfOpen(t *testing.T) {
+	b, _, advance := newTestBreaker(t, WithConsecutiveFailures(1), WithOpenTimeout(time.Second))
+
+	stale, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	open(t, b, advance, true)
+
+	probe, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the probe slot is taken until the probe is done
+	_, err = b.Allow()
+	if !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow() error = %v, want %v", err, ErrOpen)
+	}
+
+	// outcomes of requests admitted before the breaker opened do not count
+	stale(Success)
+
+	probe(Ignore)
+
+	if got := b.State(); got != StateHalfOpen {
+		t.Fatalf("State() = %v, want %v", got, StateHalfOpen)
+	}
+
+	probe, err = b.Allow()
+	if err != nil {
+		t.Fatalf("Allow() after ignored probe error = %v", err)
+	}
+
+	probe(Success)
+
+	if got := b.State(); got != StateClosed {
+		t.Errorf("State() = %v, want %v", got, StateClosed)
+	}
+}
+
+func TestBreaker_UnaryClientInterceptor(t *testing.T) {
+	tests := []struct {
+		name      string
+		halfOpen  bool
+		canceled  bool
+		code      codes.Code
+		wantCode  codes.Code
+		wantState State
+	}{
+		{name: "call outcome", code: codes.NotFound, wantCode: codes.NotFound, wantState: StateClosed},
+		{name: "downstream failure", code: codes.Unavailable, wantCode: codes.Unavailable, wantState: StateOpen},
+		{
+			name:      "downstream deadline",
+			code:      codes.DeadlineExceeded,
+			wantCode:  codes.DeadlineExceeded,
+			wantState: StateOpen,
+		},
+		{
+			name:      "caller deadline",
+			canceled:  true,
+			code:      codes.DeadlineExceeded,
+			wantCode:  codes.DeadlineExceeded,
+			wantState: StateClosed,
+		},
+		{name: "probe succeeded", halfOpen: true, code: codes.OK, wantCode: codes.OK, wantState: StateClosed},
+		{
+			name:      "probe canceled",
+			halfOpen:  true,
+			canceled:  true,
+			code:      codes.Canceled,
+			wantCode:  codes.Canceled,
+			wantState: StateHalfOpen,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _, advance := newTestBreaker(t, WithConsecutiveFailures(1), WithOpenTimeout(time.Second))
+			if tt.halfOpen {
+				open(t, b, advance, true)
+			}
+
+			ctx := t.Context()
+
+			if tt.canceled {
+				var cancel context.CancelFunc
+
+				ctx, cancel = context.WithCancel(ctx)
+				cancel()
+			}
+
+			err := b.UnaryClientInterceptor()(ctx, "/users.v1.Users/Get", nil, nil, nil,
+				func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+					return grpcstatus.Error(tt.code, "")
+				},
+			)
+			if grpcstatus.Code(err) != tt.wantCode {
+				t.Errorf("code = %v, want %v", grpcstatus.Code(err), tt.wantCode)
+			}
+
+			if got := b.State(); got != tt.wantState {
+				t.Errorf("State() = %v, want %v", got, tt.wantState)
+			}
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestBreaker_Transport(t *testing.T) {
+	errTransport := errors.New("connection refused")
+
+	tests := []struct {
+		name      string
+		open      bool
+		canceled  bool
+		status    int
+		err       error
+		wantErr   error
+		wantState State
+	}{
+		{name: "client error", status: http.StatusNotFound, wantState: StateClosed},
+		{name: "server error", status: http.StatusBadGateway, wantState: StateOpen},
+		{name: "transport error", err: errTransport, wantErr: errTransport, wantState: StateOpen},
+		{
+			name:      "caller canceled",
+			canceled:  true,
+			err:       context.Canceled,
+			wantErr:   context.Canceled,
+			wantState: StateClosed,
+		},
+		{name: "open", open: true, wantErr: ErrOpen, wantState: StateOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _, advance := newTestBreaker(t, WithConsecutiveFailures(1), WithOpenTimeout(time.Second))
+			if tt.open {
+				open(t, b, advance, false)
+			}
+
+			ctx := t.Context()
+
+			if tt.canceled {
+				var cancel context.CancelFunc
+
+				ctx, cancel = context.WithCancel(ctx)
+				cancel()
+			}
+
+			transport := b.Transport(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+				if tt.err != nil {
+					return nil, tt.err
+				}
+
+				return &http.Response{StatusCode: tt.status, Body: http.NoBody}, nil
+			}))
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://users.internal/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := transport.RoundTrip(req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RoundTrip() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if resp != nil {
+				_ = resp.Body.Close()
+			}
+
+			if got := b.State(); got != tt.wantState {
+				t.Errorf("State() = %v, want %v", got, tt.wantState)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package breaker
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcFailures are status codes indicating the downstream is unhealthy; other codes are outcomes
+// of the particular call (e.g. codes.NotFound) and count as successes.
+var grpcFailures = map[codes.Code]bool{
+	codes.Unknown:           true,
+	codes.DeadlineExceeded:  true,
+	codes.ResourceExhausted: true,
+	codes.Internal:          true,
+	codes.Unavailable:       true,
+	codes.DataLoss:          true,
+}
+
+// UnaryClientInterceptor rejects calls with codes.Unavailable while the breaker is open.
+func (b *Breaker) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) (err error) {
+		done, err := b.Allow()
+		if err != nil {
+			return b.openError()
+		}
+
+		defer func() {
+			done(grpcOutcome(ctx, err))
+		}()
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor rejects streams with codes.Unavailable while the breaker is open. Only failures
+// to establish the stream are counted.
+func (b *Breaker) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (cs grpc.ClientStream, err error) {
+		done, err := b.Allow()
+		if err != nil {
+			return nil, b.openError()
+		}
+
+		defer func() {
+			done(grpcOutcome(ctx, err))
+		}()
+
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+func (b *Breaker) openError() error {
+	return status.Errorf(codes.Unavailable, "%s: %s", b.name, ErrOpen)
+}
+
+// grpcOutcome returns the outcome of the call. Calls canceled or timed out by the caller's context
+// are ignored.
+func grpcOutcome(ctx context.Context, err error) Outcome {
+	switch {
+	case err == nil:
+		return Success
+	case ctx.Err() != nil:
+		return Ignore
+	case grpcFailures[status.Code(err)]:
+		return Failure
+	default:
+		return Success
+	}
+}
@@ -0,0 +1,47 @@
+package breaker
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type transport struct {
+	breaker *Breaker
+	next    http.RoundTripper
+}
+
+// Transport wraps the round tripper (http.DefaultTransport if nil) rejecting requests with ErrOpen while
+// the breaker is open. Transport errors and 5xx responses count as failures, while requests canceled by the
+// caller are ignored.
+func (b *Breaker) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &transport{breaker: b, next: next}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	done, err := t.breaker.Allow()
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+
+		return nil, fmt.Errorf("%s: %w", t.breaker.name, err)
+	}
+
+	resp, err = t.next.RoundTrip(req)
+
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		// requests canceled or timed out by the caller do not count
+		done(Ignore)
+	case err != nil || resp.StatusCode >= http.StatusInternalServerError:
+		done(Failure)
+	default:
+		done(Success)
+	}
+
+	return
+}
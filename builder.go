@@ -15,12 +15,22 @@ import (
 
 	"github.com/gin-gonic/gin"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/moderntv/cadre/admin"
+	"github.com/moderntv/cadre/grpc/grpcmetrics"
+	"github.com/moderntv/cadre/grpc/grpcorca"
+	"github.com/moderntv/cadre/grpc/inprocess"
 	"github.com/moderntv/cadre/http"
 	"github.com/moderntv/cadre/http/responses"
 	"github.com/moderntv/cadre/metrics"
+	"github.com/moderntv/cadre/proxy"
+	"github.com/moderntv/cadre/recovery"
+	"github.com/moderntv/cadre/requestid"
 	"github.com/moderntv/cadre/status"
+	"github.com/moderntv/cadre/timeout"
+	"github.com/moderntv/cadre/tracing"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/quic-go/quic-go/http3"
 	channelz "github.com/rantav/go-grpc-channelz"
 	grpc_middleware "github.com/rkollar/go-grpc-middleware"
 	grpc_zerolog "github.com/rkollar/go-grpc-middleware/logging/zerolog"
@@ -34,8 +44,14 @@ import (
 	"google.golang.org/grpc/reflection"
 )
 
+// ServiceRegistrator registers services directly to the *grpc.Server. Services registered this way cannot be called
+// through InProcessConn; use ServiceRegistration for new services.
 type ServiceRegistrator func(*grpc.Server)
 
+// ServiceRegistration registers services through grpc.ServiceRegistrar (e.g. using generated RegisterXServer
+// functions). Unlike ServiceRegistrator, services registered this way are callable also through InProcessConn.
+type ServiceRegistration func(grpc.ServiceRegistrar)
+
 type Finisher func(sig os.Signal)
 
 type Builder struct {
@@ -59,9 +75,21 @@ type Builder struct {
 	prometheusRegistry    *prometheus.Registry
 	metricsHTTPServerAddr string
 	metricsPath           string
+	latencyHistogram      metrics.HistogramConfig
 
 	// logging
 	loggingIgnorePatterns []*regexp.Regexp
+	enableRequestID       bool
+
+	// proxies allowed to pass the original client address
+	trustedProxies proxy.TrustedProxies
+
+	// tracing
+	tracing *tracing.Tracing
+
+	// panic recovery shared by grpc and http servers
+	crashReportSinks []recovery.Sink
+	recovery         *recovery.Recovery
 
 	grpcOptions *grpcOptions
 	httpOptions []*httpOptions
@@ -74,7 +102,8 @@ func NewBuilder(name string, options ...Option) (b *Builder, err error) {
 		ctx:         context.Background(),
 		handledSigs: []os.Signal{syscall.SIGINT, syscall.SIGTERM},
 
-		logger: zerolog.Nop(),
+		logger:          zerolog.Nop(),
+		enableRequestID: true,
 
 		statusPath:  "/status",
 		metricsPath: "/metrics",
@@ -116,6 +145,9 @@ func (b *Builder) Build() (c *cadre, err error) {
 		metrics: b.metrics,
 
 		httpServers: make(map[string]*stdhttp.Server),
+
+		trustedProxies:     b.trustedProxies,
+		proxyProtocolAddrs: map[string]bool{},
 	}
 
 	if b.httpOptions == nil && b.grpcOptions == nil {
@@ -123,6 +155,17 @@ func (b *Builder) Build() (c *cadre, err error) {
 		return
 	}
 
+	recoveryOptions := []recovery.Option{recovery.WithService(b.name)}
+	for _, sink := range b.crashReportSinks {
+		recoveryOptions = append(recoveryOptions, recovery.WithSink(sink))
+	}
+
+	b.recovery, err = recovery.New(b.logger, b.metrics, recoveryOptions...)
+	if err != nil {
+		err = fmt.Errorf("cannot create panic recovery: %w", err)
+		return
+	}
+
 	// extra http services init
 	if b.metricsHTTPServerAddr != "" {
 		err = WithHTTP(
@@ -191,16 +234,36 @@ func (b *Builder) Build() (c *cadre, err error) {
 		}
 	}
 
+	// grpc-gateway handlers call the services over the in-process channel
+	if b.needsGatewayConn() {
+		if c.grpcServer == nil {
+			err = errors.New("grpc-gateway requires grpc server to be enabled")
+			return
+		}
+
+		if len(b.grpcOptions.services) > 0 {
+			err = errors.New(
+				"grpc-gateway cannot call services registered with WithService, use WithServiceRegistration instead",
+			)
+
+			return
+		}
+	}
+
 	// create and configure http server
-	var httpServers map[string]*http.HttpServer
+	var (
+		httpServers       map[string]*http.HttpServer
+		mergedHTTPOptions map[string]*httpOptions
+	)
 	if b.httpOptions != nil {
-		httpServers, err = b.buildHTTP(c, ctx)
+		httpServers, mergedHTTPOptions, err = b.buildHTTP(c, ctx)
 		if err != nil {
 			return
 		}
 	}
 
 	c.httpServers = map[string]*stdhttp.Server{}
+	c.http3Servers = map[string]*http3.Server{}
 	for addr, httpServer := range httpServers {
 		httpServer.LogRegisteredRoutes()
 
@@ -228,9 +291,27 @@ func (b *Builder) Build() (c *cadre, err error) {
 			c.grpcListener = nil
 		}
 
+		options := mergedHTTPOptions[addr]
+		if options.enableProxyProtocol {
+			c.proxyProtocolAddrs[addr] = true
+		}
+
+		// http/3 - serve the same handler over quic and advertise it to tcp clients
+		if options.enableHTTP3 {
+			http3Server := &http3.Server{
+				Addr:      addr,
+				Handler:   h,
+				TLSConfig: http3.ConfigureTLSConfig(options.tlsConfig),
+			}
+			c.http3Servers[addr] = http3Server
+
+			h = altSvcHandler(http3Server, h)
+		}
+
 		c.httpServers[addr] = &stdhttp.Server{
 			Addr:              addr,
 			Handler:           h,
+			TLSConfig:         options.tlsConfig,
 			ReadHeaderTimeout: 5 * time.Second,
 		}
 	}
@@ -265,6 +346,11 @@ func (b *Builder) ensure() (err error) {
 		if err != nil {
 			return
 		}
+
+		if b.grpcOptions.enableProxyProtocol && len(b.trustedProxies) == 0 {
+			err = errors.New("grpc server cannot accept PROXY protocol without trusted proxies")
+			return
+		}
 	}
 
 	// http checks
@@ -273,6 +359,14 @@ func (b *Builder) ensure() (err error) {
 		if err != nil {
 			return
 		}
+
+		if httpServerOptions.enableProxyProtocol && len(b.trustedProxies) == 0 {
+			err = fmt.Errorf(
+				"http server `%s` cannot accept PROXY protocol without trusted proxies",
+				httpServerOptions.serverName,
+			)
+			return
+		}
 	}
 
 	// configure metrics + status endpoint http servers
@@ -307,6 +401,18 @@ func (b *Builder) buildGrpc(c *cadre) (err error) {
 	unaryInterceptors := []grpc.UnaryServerInterceptor{}
 	streamInterceptors := []grpc.StreamServerInterceptor{}
 
+	// real client address has to be resolved before anything else looks at the peer
+	if len(b.trustedProxies) > 0 {
+		unaryInterceptors = append(unaryInterceptors, proxy.UnaryServerInterceptor(b.trustedProxies))
+		streamInterceptors = append(streamInterceptors, proxy.StreamServerInterceptor(b.trustedProxies))
+	}
+
+	// binary log - before the other interceptors, so that it records calls as received and their final statuses
+	if b.grpcOptions.binaryLogger != nil {
+		unaryInterceptors = append(unaryInterceptors, b.grpcOptions.binaryLogger.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, b.grpcOptions.binaryLogger.StreamServerInterceptor())
+	}
+
 	// logging
 	if b.grpcOptions.enableLoggingMiddleware {
 		unaryInterceptors = append(
@@ -325,17 +431,76 @@ func (b *Builder) buildGrpc(c *cadre) (err error) {
 		)
 	}
 
-	// TODO: add tracing middleware
-	// metrics middleware
-	unaryInterceptors = append(unaryInterceptors, grpcMetrics.UnaryServerInterceptor())
-	streamInterceptors = append(streamInterceptors, grpcMetrics.StreamServerInterceptor())
+	// request id - after logging so that it gets into the call logs
+	if b.enableRequestID {
+		unaryInterceptors = append(unaryInterceptors, requestid.UnaryServerInterceptor(b.logger))
+		streamInterceptors = append(streamInterceptors, requestid.StreamServerInterceptor(b.logger))
+	}
+
+	// payload log - after request id so that the payloads can be paired with the call logs
+	if b.grpcOptions.payloadLogger != nil {
+		unaryInterceptors = append(unaryInterceptors, b.grpcOptions.payloadLogger.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, b.grpcOptions.payloadLogger.StreamServerInterceptor())
+	}
+
+	// tracing
+	if b.tracing != nil {
+		unaryInterceptors = append(unaryInterceptors, b.tracing.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, b.tracing.StreamServerInterceptor())
+	}
+
+	// metrics middleware - latency after tracing so that observations carry trace exemplars
+	grpcLatency, err := grpcmetrics.NewLatency(b.metrics, b.latencyHistogram)
+	if err != nil {
+		return
+	}
+
+	unaryInterceptors = append(
+		unaryInterceptors,
+		grpcMetrics.UnaryServerInterceptor(),
+		grpcLatency.UnaryServerInterceptor(),
+	)
+	streamInterceptors = append(
+		streamInterceptors,
+		grpcMetrics.StreamServerInterceptor(),
+		grpcLatency.StreamServerInterceptor(),
+	)
+
+	// deadlines - after metrics so that exceeded calls are counted with their final code
+	if b.grpcOptions.enableMethodTimeouts {
+		var timeouts *timeout.Timeouts
+
+		timeouts, err = timeout.New(b.metrics, b.grpcOptions.methodTimeouts, b.grpcOptions.defaultMethodTimeout)
+		if err != nil {
+			err = fmt.Errorf("cannot create grpc method timeouts: %w", err)
+			return
+		}
+
+		unaryInterceptors = append(unaryInterceptors, timeouts.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, timeouts.StreamServerInterceptor())
+	}
+
+	// admin service - rejects admin calls without the admin token
+	var adminServer *admin.Server
+	if b.grpcOptions.enableAdmin {
+		adminServer, err = admin.New(b.status, b.grpcOptions.adminToken, b.grpcOptions.adminOptions...)
+		if err != nil {
+			err = fmt.Errorf("cannot create admin service: %w", err)
+			return
+		}
+
+		unaryInterceptors = append(unaryInterceptors, adminServer.UnaryServerInterceptor())
+	}
 
 	// add extra interceptors
 	unaryInterceptors = append(unaryInterceptors, b.grpcOptions.extraUnaryInterceptors...)
 	streamInterceptors = append(streamInterceptors, b.grpcOptions.extraStreamInterceptors...)
 
-	// recovery middleware
-	if b.grpcOptions.enableRecoveryMiddleware {
+	// recovery middleware - custom grpc_recovery options keep using grpc_recovery
+	if b.grpcOptions.enableRecoveryMiddleware && len(b.grpcOptions.recoveryMiddlewareOptions) == 0 {
+		unaryInterceptors = append(unaryInterceptors, b.recovery.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, b.recovery.StreamServerInterceptor())
+	} else if b.grpcOptions.enableRecoveryMiddleware {
 		unaryInterceptors = append(
 			unaryInterceptors,
 			grpc_recovery.UnaryServerInterceptor(b.grpcOptions.recoveryMiddlewareOptions...),
@@ -346,12 +511,48 @@ func (b *Builder) buildGrpc(c *cadre) (err error) {
 		)
 	}
 
-	// create grpc server
-	c.grpcAddr = b.grpcOptions.listeningAddress
-	c.grpcServer = grpc.NewServer(
+	// active streams, message counts and sizes
+	grpcStreams, err := grpcmetrics.NewServerStreams(b.metrics, metrics.HistogramConfig{})
+	if err != nil {
+		return
+	}
+
+	serverOptions := []grpc.ServerOption{
 		grpc_middleware.WithUnaryServerChain(unaryInterceptors...),
 		grpc_middleware.WithStreamServerChain(streamInterceptors...),
-	)
+		grpc.StatsHandler(grpcStreams),
+	}
+
+	// ORCA load reports - per request in trailers, after the interceptor chain
+	if b.grpcOptions.enableORCA {
+		c.orca, err = grpcorca.New(b.metrics, b.grpcOptions.orcaOptions...)
+		if err != nil {
+			err = fmt.Errorf("cannot create orca reporter: %w", err)
+			return
+		}
+
+		serverOptions = append(serverOptions, c.orca.ServerOptions()...)
+	}
+
+	// create grpc server
+	c.grpcAddr = b.grpcOptions.listeningAddress
+	c.grpcServer = grpc.NewServer(serverOptions...)
+
+	// in-process channel calling the services through the same interceptors
+	var registrar grpc.ServiceRegistrar = c.grpcServer
+	if b.grpcOptions.enableInProcessConn || b.needsGatewayConn() {
+		unaryClientInterceptors, streamClientInterceptors := b.inProcessClientInterceptors()
+
+		c.inProcess, err = inprocess.New(
+			inprocess.WithServerInterceptors(unaryInterceptors, streamInterceptors),
+			inprocess.WithClientInterceptors(unaryClientInterceptors, streamClientInterceptors),
+		)
+		if err != nil {
+			return
+		}
+
+		registrar = c.inProcess.Registrar(c.grpcServer)
+	}
 
 	// replace gRPC logger
 	// grpc_zerolog.ReplaceGrpcLoggerV2(b.logger.Level(zerolog.ErrorLevel))
@@ -359,9 +560,33 @@ func (b *Builder) buildGrpc(c *cadre) (err error) {
 	// register services
 	// health service
 	if b.grpcOptions.enableHealthService {
-		healthService := health.NewServer()
+		c.grpcHealthService = health.NewServer()
+
+		healthpb.RegisterHealthServer(registrar, c.grpcHealthService)
 
-		healthpb.RegisterHealthServer(c.grpcServer, healthService)
+		c.services = append(c.services, newService("", nil, c.grpcHealthService))
+		for name := range b.grpcOptions.services {
+			c.services = append(c.services, newService(name, b.grpcOptions.serviceHealth[name], c.grpcHealthService))
+		}
+
+		for name := range b.grpcOptions.registrations {
+			c.services = append(c.services, newService(name, b.grpcOptions.serviceHealth[name], c.grpcHealthService))
+		}
+
+		c.updateHealth(b.status.Report())
+	}
+
+	// admin service
+	if adminServer != nil {
+		admin.Register(registrar, adminServer)
+	}
+
+	// ORCA service - out-of-band load reports
+	if c.orca != nil {
+		err = c.orca.Register(c.grpcServer)
+		if err != nil {
+			return
+		}
 	}
 
 	// reflection
@@ -374,6 +599,10 @@ func (b *Builder) buildGrpc(c *cadre) (err error) {
 		registrator(c.grpcServer)
 	}
 
+	for _, registration := range b.grpcOptions.registrations {
+		registration(registrar)
+	}
+
 	// grpc listener
 	if b.grpcOptions.listeningAddress != "" && !b.grpcOptions.multiplexWithHTTP {
 		var lc net.ListenConfig
@@ -382,18 +611,55 @@ func (b *Builder) buildGrpc(c *cadre) (err error) {
 		if err != nil {
 			return
 		}
+
+		if b.grpcOptions.enableProxyProtocol {
+			c.grpcListener = proxy.NewListener(c.grpcListener, b.trustedProxies)
+		}
+	}
+
+	return
+}
+
+func (b *Builder) needsGatewayConn() bool {
+	for _, options := range b.httpOptions {
+		if len(options.gateways) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inProcessClientInterceptors returns client interceptors of in-process connections propagating request ids
+// and trace context to the handlers.
+func (b *Builder) inProcessClientInterceptors() (
+	unaryInterceptors []grpc.UnaryClientInterceptor,
+	streamInterceptors []grpc.StreamClientInterceptor,
+) {
+	if b.enableRequestID {
+		unaryInterceptors = append(unaryInterceptors, requestid.UnaryClientInterceptor())
+		streamInterceptors = append(streamInterceptors, requestid.StreamClientInterceptor())
+	}
+
+	if b.tracing != nil {
+		unaryInterceptors = append(unaryInterceptors, b.tracing.UnaryClientInterceptor())
+		streamInterceptors = append(streamInterceptors, b.tracing.StreamClientInterceptor())
 	}
 
 	return
 }
 
 func (b *Builder) buildHTTP(
-	_ *cadre,
+	c *cadre,
 	cadreContext context.Context,
-) (httpServers map[string]*http.HttpServer, err error) {
+) (httpServers map[string]*http.HttpServer, mergedHTTPOptions map[string]*httpOptions, err error) {
 	httpServers = map[string]*http.HttpServer{}
+	mergedHTTPOptions = map[string]*httpOptions{}
 
-	mergedHTTPOptions := map[string]*httpOptions{}
+	var gatewayConn grpc.ClientConnInterface
+	if c.inProcess != nil {
+		gatewayConn = c.inProcess
+	}
 
 	for _, newServer := range b.httpOptions {
 		addr := newServer.listeningAddress
@@ -415,6 +681,13 @@ func (b *Builder) buildHTTP(
 			b.logger,
 			b.metrics,
 			b.loggingIgnorePatterns,
+			b.trustedProxies,
+			b.enableRequestID,
+			b.tracing,
+			b.recovery,
+			b.latencyHistogram,
+			c.grpcServer,
+			gatewayConn,
 		)
 		if err != nil {
 			return
@@ -423,3 +696,14 @@ func (b *Builder) buildHTTP(
 
 	return
 }
+
+// altSvcHandler advertises the http/3 endpoint to clients connected over tcp.
+func altSvcHandler(http3Server *http3.Server, next stdhttp.Handler) stdhttp.Handler {
+	return stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if r.ProtoMajor < 3 {
+			_ = http3Server.SetQUICHeaders(w.Header())
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
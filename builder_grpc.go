@@ -2,7 +2,13 @@ package cadre
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/moderntv/cadre/admin"
+	"github.com/moderntv/cadre/grpc/binlog"
+	"github.com/moderntv/cadre/grpc/grpcorca"
+	"github.com/moderntv/cadre/grpc/payloadlog"
 	grpc_zerolog "github.com/rkollar/go-grpc-middleware/logging/zerolog"
 	grpc_recovery "github.com/rkollar/go-grpc-middleware/recovery"
 	"google.golang.org/grpc"
@@ -13,8 +19,14 @@ type grpcOptions struct {
 	listeningAddress string
 	// whether the grpc server should be on the same http server as the main http server
 	multiplexWithHTTP bool
+	// whether the standalone listener expects PROXY protocol headers
+	enableProxyProtocol bool
 
-	services map[string]ServiceRegistrator
+	services      map[string]ServiceRegistrator
+	registrations map[string]ServiceRegistration
+
+	// whether the registrations are callable through InProcessConn
+	enableInProcessConn bool
 
 	// whether enable recovery middleware
 	enableRecoveryMiddleware  bool
@@ -26,6 +38,7 @@ type grpcOptions struct {
 
 	// whether to register the grpc health service
 	enableHealthService bool
+	serviceHealth       map[string][]string // service name -> status components
 
 	// whether to enable reflection
 	enableReflection bool
@@ -34,6 +47,26 @@ type grpcOptions struct {
 	enableChannelz   bool
 	channelzHttpAddr string
 
+	// server-side deadlines
+	enableMethodTimeouts bool
+	methodTimeouts       map[string]time.Duration
+	defaultMethodTimeout time.Duration
+
+	// binary log of selected methods
+	binaryLogger *binlog.Logger
+
+	// debug log of sampled request and response payloads
+	payloadLogger *payloadlog.Logger
+
+	// ORCA load reports
+	enableORCA  bool
+	orcaOptions []grpcorca.Option
+
+	// token-gated admin service
+	enableAdmin  bool
+	adminToken   string
+	adminOptions []admin.Option
+
 	// allow registration of custom interceptors
 	extraUnaryInterceptors  []grpc.UnaryServerInterceptor
 	extraStreamInterceptors []grpc.StreamServerInterceptor
@@ -42,6 +75,8 @@ type grpcOptions struct {
 func defaultGRPCOptions() *grpcOptions {
 	return &grpcOptions{
 		services:                 map[string]ServiceRegistrator{},
+		registrations:            map[string]ServiceRegistration{},
+		serviceHealth:            map[string][]string{},
 		enableRecoveryMiddleware: true,
 		enableLoggingMiddleware:  true,
 		enableHealthService:      true,
@@ -70,6 +105,28 @@ func (g *grpcOptions) ensure() (err error) {
 		return
 	}
 
+	if g.enableProxyProtocol && g.multiplexWithHTTP {
+		err = errors.New("proxy protocol of multiplexed grpc server has to be configured on the http server")
+
+		return
+	}
+
+	if g.enableInProcessConn && len(g.services) > 0 {
+		err = errors.New(
+			"in-process connection cannot call services registered with WithService, use WithServiceRegistration instead",
+		)
+
+		return
+	}
+
+	for name := range g.serviceHealth {
+		if !g.hasService(name) {
+			err = fmt.Errorf("health of unknown grpc service `%s`", name)
+
+			return
+		}
+	}
+
 	return
 }
 
@@ -111,11 +168,22 @@ func WithGRPCMultiplex() GRPCOption {
 	}
 }
 
-// WithService registers a new gRPC service to the Cadre's gRPC server.
+// WithGRPCProxyProtocol configures gRPC's standalone listener to parse PROXY protocol (v1/v2) headers.
+// Headers are accepted only from the trusted proxies, so it requires WithTrustedProxies.
+// When multiplexed with HTTP, use WithProxyProtocol on the HTTP server instead.
+func WithGRPCProxyProtocol() GRPCOption {
+	return func(g *grpcOptions) error {
+		g.enableProxyProtocol = true
+
+		return nil
+	}
+}
+
+// WithService registers a new gRPC service to the Cadre's gRPC server. The service cannot be called through
+// InProcessConn, so it cannot be combined with WithInProcessConn; use WithServiceRegistration for that.
 func WithService(name string, registrator ServiceRegistrator) GRPCOption {
 	return func(g *grpcOptions) error {
-		_, ok := g.services[name]
-		if ok {
+		if g.hasService(name) {
 			return errors.New("service already registered to grpc server")
 		}
 
@@ -125,6 +193,120 @@ func WithService(name string, registrator ServiceRegistrator) GRPCOption {
 	}
 }
 
+// WithServiceRegistration registers a new gRPC service to the Cadre's gRPC server and to its in-process
+// connection (see WithInProcessConn).
+func WithServiceRegistration(name string, registration ServiceRegistration) GRPCOption {
+	return func(g *grpcOptions) error {
+		if g.hasService(name) {
+			return errors.New("service already registered to grpc server")
+		}
+
+		g.registrations[name] = registration
+
+		return nil
+	}
+}
+
+// WithInProcessConn enables the in-process connection returned by InProcessConn. Build fails if any service
+// is registered with WithService, as those services could not be called through it.
+func WithInProcessConn() GRPCOption {
+	return func(g *grpcOptions) error {
+		g.enableInProcessConn = true
+
+		return nil
+	}
+}
+
+// WithServiceHealth makes the serving status of the service (registered with WithService or WithServiceRegistration)
+// in the grpc health service depend on the status components: it is NOT_SERVING if any of them is in ERROR
+// or not registered. Services without components follow the overall status, same as the "" service.
+func WithServiceHealth(name string, components ...string) GRPCOption {
+	return func(g *grpcOptions) error {
+		if len(components) == 0 {
+			return errors.New("service health has to depend on at least one status component")
+		}
+
+		g.serviceHealth[name] = components
+
+		return nil
+	}
+}
+
+func (g *grpcOptions) hasService(name string) bool {
+	_, registrator := g.services[name]
+	_, registration := g.registrations[name]
+
+	return registrator || registration
+}
+
+// WithMethodTimeouts applies server-side deadlines to gRPC calls. Timeouts are keyed by full method
+// (`/package.Service/Method`) or its path.Match pattern, methods without a timeout use the default one (zero means none).
+// The deadline is applied only if the incoming one is missing or longer. Exceeded calls fail with codes.DeadlineExceeded.
+func WithMethodTimeouts(timeouts map[string]time.Duration, defaultTimeout time.Duration) GRPCOption {
+	return func(g *grpcOptions) error {
+		g.enableMethodTimeouts = true
+		g.methodTimeouts = timeouts
+		g.defaultMethodTimeout = defaultTimeout
+
+		return nil
+	}
+}
+
+// WithBinaryLog records calls of the methods selected by the logger in the gRPC binary log format
+// (see package grpc/binlog). The methods can be changed at runtime through the logger, which is not closed
+// by the cadre - default off.
+func WithBinaryLog(l *binlog.Logger) GRPCOption {
+	return func(g *grpcOptions) error {
+		if l == nil {
+			return errors.New("binary logger cannot be nil")
+		}
+
+		g.binaryLogger = l
+
+		return nil
+	}
+}
+
+// WithPayloadLog logs redacted request and response payloads of sampled calls (see package grpc/payloadlog).
+// Logging is enabled and sampled at runtime through the logger - default off.
+func WithPayloadLog(l *payloadlog.Logger) GRPCOption {
+	return func(g *grpcOptions) error {
+		if l == nil {
+			return errors.New("payload logger cannot be nil")
+		}
+
+		g.payloadLogger = l
+
+		return nil
+	}
+}
+
+// WithORCA reports load metrics of the server to clients balancing calls with lb/wrr, both per request
+// and out of band (see package grpc/grpcorca) - default off.
+func WithORCA(opts ...grpcorca.Option) GRPCOption {
+	return func(g *grpcOptions) error {
+		g.enableORCA = true
+		g.orcaOptions = opts
+
+		return nil
+	}
+}
+
+// WithAdmin registers the token-gated admin gRPC service (see package admin) - default off.
+func WithAdmin(token string, opts ...admin.Option) GRPCOption {
+	return func(g *grpcOptions) error {
+		if token == "" {
+			return errors.New("admin token cannot be empty")
+		}
+
+		g.enableAdmin = true
+		g.adminToken = token
+		g.adminOptions = opts
+
+		return nil
+	}
+}
+
 // WithoutLogging disables logging middleware - default on.
 func WithoutLogging() GRPCOption {
 	return func(g *grpcOptions) error {
@@ -174,6 +356,8 @@ func WithoutRecovery() GRPCOption {
 }
 
 // WithRecoveryOptions configures gRPC's recovery middleware with custom options.
+// Custom options switch the gRPC server from cadre's recovery back to grpc_recovery,
+// so panics are neither counted nor written to crash report sinks.
 func WithRecoveryOptions(opts []grpc_recovery.Option) GRPCOption {
 	return func(g *grpcOptions) error {
 		g.recoveryMiddlewareOptions = opts
@@ -2,15 +2,25 @@ package cadre
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
 	"regexp"
 
 	"github.com/gin-gonic/gin"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/moderntv/cadre/http"
+	"github.com/moderntv/cadre/http/gateway"
+	"github.com/moderntv/cadre/http/grpcweb"
 	"github.com/moderntv/cadre/http/middleware"
 	"github.com/moderntv/cadre/metrics"
+	"github.com/moderntv/cadre/proxy"
+	"github.com/moderntv/cadre/recovery"
+	"github.com/moderntv/cadre/requestid"
+	"github.com/moderntv/cadre/tracing"
 	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
 )
 
 // HTTP Options.
@@ -22,10 +32,26 @@ type httpOptions struct {
 	enableLoggingMiddleware bool
 	enableMetricsMiddleware bool
 
+	// tls configuration of the listener; required for http/3
+	tlsConfig *tls.Config
+	// whether the same router should also be served over QUIC (http/3)
+	enableHTTP3 bool
+	// whether the listener expects PROXY protocol headers
+	enableProxyProtocol bool
+
 	routerOptions      []gin.OptionFunc
 	globalMiddleware   []gin.HandlerFunc
 	metricsAggregation bool
+	metricsProtoLabel  bool
 	routingGroups      map[string]http.RoutingGroup
+
+	// grpc-gateway handlers served for requests not matched by any route
+	gateways          []gateway.Registrator
+	gatewayMuxOptions []runtime.ServeMuxOption
+
+	// whether gRPC-Web requests should be translated to the grpc server
+	enableGRPCWeb  bool
+	grpcWebOptions []grpcweb.Option
 }
 
 func (h *httpOptions) ensure() (err error) {
@@ -33,6 +59,10 @@ func (h *httpOptions) ensure() (err error) {
 		return fmt.Errorf("no listening address for http server `%s`", h.serverName)
 	}
 
+	if h.enableHTTP3 && h.tlsConfig == nil {
+		return fmt.Errorf("http server `%s` cannot serve http/3 without tls configuration", h.serverName)
+	}
+
 	return
 }
 
@@ -47,10 +77,31 @@ func (h *httpOptions) merge(other *httpOptions) (hh *httpOptions, err error) {
 		enableLoggingMiddleware: h.enableLoggingMiddleware,
 		enableMetricsMiddleware: h.enableMetricsMiddleware,
 
+		tlsConfig:   h.tlsConfig,
+		enableHTTP3: h.enableHTTP3 || other.enableHTTP3,
+
+		enableProxyProtocol: h.enableProxyProtocol || other.enableProxyProtocol,
+
 		routerOptions:      append(h.routerOptions, other.routerOptions...),
 		globalMiddleware:   append(h.globalMiddleware, other.globalMiddleware...),
 		metricsAggregation: h.metricsAggregation,
+		metricsProtoLabel:  h.metricsProtoLabel,
 		routingGroups:      h.routingGroups,
+
+		gateways:          append(h.gateways, other.gateways...),
+		gatewayMuxOptions: append(h.gatewayMuxOptions, other.gatewayMuxOptions...),
+
+		enableGRPCWeb:  h.enableGRPCWeb || other.enableGRPCWeb,
+		grpcWebOptions: append(h.grpcWebOptions, other.grpcWebOptions...),
+	}
+
+	if other.tlsConfig != nil {
+		if hh.tlsConfig != nil && hh.tlsConfig != other.tlsConfig {
+			err = errors.New("conflicting tls configurations for the same listening address")
+			return
+		}
+
+		hh.tlsConfig = other.tlsConfig
 	}
 
 	for _, othersRoutingGroup := range other.routingGroups {
@@ -68,13 +119,53 @@ func (h *httpOptions) build(
 	logger zerolog.Logger,
 	metricsRegistry *metrics.Registry,
 	loggingIgnorePatterns []*regexp.Regexp,
+	trustedProxies proxy.TrustedProxies,
+	enableRequestID bool,
+	tracer *tracing.Tracing,
+	recoverer *recovery.Recovery,
+	latencyHistogram metrics.HistogramConfig,
+	grpcServer *grpc.Server,
+	gatewayConn grpc.ClientConnInterface,
 ) (httpServer *http.HttpServer, err error) {
+	var gatewayMux *runtime.ServeMux
+
+	if len(h.gateways) > 0 {
+		if gatewayConn == nil {
+			err = fmt.Errorf("http server `%s` cannot serve grpc-gateway without grpc server", h.serverName)
+			return
+		}
+
+		gatewayMux = gateway.NewServeMux(h.gatewayMuxOptions...)
+		for _, register := range h.gateways {
+			err = register(cadreContext, gatewayMux, gatewayConn)
+			if err != nil {
+				err = fmt.Errorf("registering grpc-gateway handlers failed: %w", err)
+				return
+			}
+		}
+	}
+
 	serverMiddlewares := []gin.HandlerFunc{}
 	{
+		// gateway requests have to be matched to their routes before anything else looks at them
+		if gatewayMux != nil {
+			serverMiddlewares = append(serverMiddlewares, gateway.RouteMiddleware(gatewayMux))
+		}
+
 		if h.enableMetricsMiddleware {
 			var metricsMiddleware gin.HandlerFunc
 
-			metricsMiddleware, err = middleware.NewMetrics(metricsRegistry, h.serverName, h.metricsAggregation)
+			metricsOptions := []middleware.MetricsOption{middleware.WithLatencyHistogram(latencyHistogram)}
+			if h.metricsProtoLabel {
+				metricsOptions = append(metricsOptions, middleware.WithProtoLabel())
+			}
+
+			metricsMiddleware, err = middleware.NewMetrics(
+				metricsRegistry,
+				h.serverName,
+				h.metricsAggregation,
+				metricsOptions...,
+			)
 			if err != nil {
 				return
 			}
@@ -86,7 +177,29 @@ func (h *httpOptions) build(
 			serverMiddlewares = append(serverMiddlewares, middleware.NewLogger(logger, loggingIgnorePatterns))
 		}
 
-		serverMiddlewares = append(serverMiddlewares, gin.Recovery())
+		if enableRequestID {
+			serverMiddlewares = append(serverMiddlewares, requestid.Middleware(logger))
+		}
+
+		if tracer != nil {
+			serverMiddlewares = append(serverMiddlewares, tracer.Middleware())
+		}
+
+		serverMiddlewares = append(serverMiddlewares, recoverer.Middleware())
+
+		// grpc-web requests are served by the grpc server (and its interceptors) => skip custom http middleware
+		if h.enableGRPCWeb {
+			var grpcWeb *grpcweb.GRPCWeb
+
+			grpcWeb, err = grpcweb.New(grpcServer, h.grpcWebOptions...)
+			if err != nil {
+				err = fmt.Errorf("http server `%s` cannot serve grpc-web: %w", h.serverName, err)
+				return
+			}
+
+			serverMiddlewares = append(serverMiddlewares, grpcWeb.Middleware())
+		}
+
 		serverMiddlewares = append(serverMiddlewares, h.globalMiddleware...)
 	}
 
@@ -101,6 +214,13 @@ func (h *httpOptions) build(
 		return
 	}
 
+	if len(trustedProxies) > 0 {
+		err = httpServer.SetTrustedProxies(trustedProxies.Strings())
+		if err != nil {
+			return
+		}
+	}
+
 	for _, group := range h.routingGroups {
 		err = httpServer.RegisterRouteGroup(group)
 		if err != nil {
@@ -108,6 +228,10 @@ func (h *httpOptions) build(
 		}
 	}
 
+	if gatewayMux != nil {
+		httpServer.SetNoRoute(gateway.Handler(gatewayMux))
+	}
+
 	return
 }
 
@@ -157,6 +281,70 @@ func WithHTTPListeningAddress(addr string) HTTPOption {
 	}
 }
 
+// WithTLSConfig configures the HTTP server to serve TLS using the given configuration.
+func WithTLSConfig(tlsConfig *tls.Config) HTTPOption {
+	return func(h *httpOptions) error {
+		if tlsConfig == nil {
+			return errors.New("tls configuration cannot be nil")
+		}
+
+		h.tlsConfig = tlsConfig
+
+		return nil
+	}
+}
+
+// WithHTTP3 configures the HTTP server to also serve the same router over QUIC (HTTP/3) on the listening address' UDP port.
+// Responses served over TCP advertise the HTTP/3 endpoint using the Alt-Svc header. Requires WithTLSConfig.
+func WithHTTP3() HTTPOption {
+	return func(h *httpOptions) error {
+		h.enableHTTP3 = true
+
+		return nil
+	}
+}
+
+// WithProxyProtocol configures the HTTP server's listener to parse PROXY protocol (v1/v2) headers.
+// Headers are accepted only from the trusted proxies, so it requires WithTrustedProxies.
+func WithProxyProtocol() HTTPOption {
+	return func(h *httpOptions) error {
+		h.enableProxyProtocol = true
+
+		return nil
+	}
+}
+
+// WithGRPCGateway mounts grpc-gateway handlers registered by register on the HTTP server. The handlers serve
+// requests not matched by any route and call the gRPC services over the in-process channel, so they pass through
+// both the HTTP middleware and the gRPC interceptors. Only services registered with WithServiceRegistration
+// can be called. Errors are rendered as the responses package does.
+// May be used multiple times; all handlers of the server share one mux configured by given options.
+func WithGRPCGateway(register gateway.Registrator, muxOptions ...runtime.ServeMuxOption) HTTPOption {
+	return func(h *httpOptions) error {
+		if register == nil {
+			return errors.New("grpc-gateway registrator cannot be nil")
+		}
+
+		h.gateways = append(h.gateways, register)
+		h.gatewayMuxOptions = append(h.gatewayMuxOptions, muxOptions...)
+
+		return nil
+	}
+}
+
+// WithGRPCWeb makes the HTTP server translate gRPC-Web and gRPC-Web-text requests to the grpc server,
+// so that browsers can call gRPC services on the same listener as the REST routes. CORS is handled
+// according to the options; cross-origin requests are denied unless allowed by grpcweb.WithAllowedOrigins.
+// The requests are labelled `grpc-web` in HTTP metrics when WithMetricsProtoLabel is enabled.
+func WithGRPCWeb(options ...grpcweb.Option) HTTPOption {
+	return func(h *httpOptions) error {
+		h.enableGRPCWeb = true
+		h.grpcWebOptions = append(h.grpcWebOptions, options...)
+
+		return nil
+	}
+}
+
 // WithMetricsAggregation enables path aggregation of endpoint.
 // For example when using asterisk (*) in path and endpoint unpacks all possible values
 // it will aggregate it back to asterisk (*).
@@ -167,8 +355,17 @@ func WithMetricsAggregation() HTTPOption {
 	}
 }
 
+// WithMetricsProtoLabel adds the `proto` label with the protocol of the request (e.g. HTTP/1.1, HTTP/3.0, grpc-web)
+// to the HTTP metrics. It changes the label sets of the existing series, so it is disabled by default.
+func WithMetricsProtoLabel() HTTPOption {
+	return func(h *httpOptions) error {
+		h.metricsProtoLabel = true
+		return nil
+	}
+}
+
 // WithGlobalMiddleware adds new global middleware to the HTTP server
-// default - metrics, logging and recovery (in this order).
+// default - metrics, logging, request id, tracing (if enabled) and recovery (in this order).
 func WithGlobalMiddleware(middleware ...gin.HandlerFunc) HTTPOption {
 	return func(h *httpOptions) error {
 		h.globalMiddleware = append(h.globalMiddleware, middleware...)
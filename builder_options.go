@@ -8,7 +8,10 @@ import (
 	"regexp"
 
 	"github.com/moderntv/cadre/metrics"
+	"github.com/moderntv/cadre/proxy"
+	"github.com/moderntv/cadre/recovery"
 	"github.com/moderntv/cadre/status"
+	"github.com/moderntv/cadre/tracing"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog"
 )
@@ -121,3 +124,69 @@ func WithLoggingIgnorePaths(patterns ...string) Option {
 		return nil
 	}
 }
+
+// WithLatencyHistogram configures buckets of gRPC and HTTP latency histograms, including Prometheus native histograms.
+// Observations of traced requests carry trace ID exemplars.
+func WithLatencyHistogram(config metrics.HistogramConfig) Option {
+	return func(options *Builder) error {
+		options.latencyHistogram = config
+
+		return nil
+	}
+}
+
+// WithTrustedProxies configures networks (CIDRs or plain IP addresses) of proxies and load balancers
+// allowed to pass the original client address. They are applied to gin's trusted proxies,
+// to PROXY protocol listeners and to the resolution of gRPC peer addresses from x-forwarded-for metadata.
+func WithTrustedProxies(cidrs ...string) Option {
+	return func(options *Builder) error {
+		trustedProxies, err := proxy.ParseTrustedProxies(cidrs...)
+		if err != nil {
+			return err
+		}
+
+		options.trustedProxies = append(options.trustedProxies, trustedProxies...)
+
+		return nil
+	}
+}
+
+// WithTracing enables OpenTelemetry tracing of gRPC calls and HTTP requests handled by cadre.
+// Trace and span IDs are added to the request-scoped loggers and to the request logs.
+// The caller is responsible for shutting the tracing down to flush remaining spans.
+func WithTracing(t *tracing.Tracing) Option {
+	return func(options *Builder) error {
+		if t == nil {
+			return errors.New("tracing cannot be nil")
+		}
+
+		options.tracing = t
+
+		return nil
+	}
+}
+
+// WithCrashReports writes crash reports of panics recovered in gRPC and HTTP handlers to the sink,
+// e.g. recovery.NewDirSink.
+func WithCrashReports(sink recovery.Sink) Option {
+	return func(options *Builder) error {
+		if sink == nil {
+			return errors.New("crash report sink cannot be nil")
+		}
+
+		options.crashReportSinks = append(options.crashReportSinks, sink)
+
+		return nil
+	}
+}
+
+// WithoutRequestID disables request ID propagation. By default the X-Request-ID header
+// (x-request-id metadata for gRPC) is accepted or generated, echoed in responses
+// and attached to request-scoped loggers.
+func WithoutRequestID() Option {
+	return func(options *Builder) error {
+		options.enableRequestID = false
+
+		return nil
+	}
+}
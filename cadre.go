@@ -2,16 +2,21 @@ package cadre
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	stdhttp "net/http"
 	"os"
 	"os/signal"
 	"sync"
-	"time"
 
+	"github.com/moderntv/cadre/grpc/grpcorca"
+	"github.com/moderntv/cadre/grpc/inprocess"
 	"github.com/moderntv/cadre/metrics"
+	"github.com/moderntv/cadre/proxy"
 	"github.com/moderntv/cadre/status"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
@@ -22,6 +27,17 @@ type Cadre interface {
 	Shutdown() error
 }
 
+// InProcessConnProvider provides the in-process connection of the cadre built by Builder (see InProcessConn).
+// It is not part of Cadre, so that existing implementations of Cadre remain valid.
+type InProcessConnProvider interface {
+	InProcessConn() grpc.ClientConnInterface
+}
+
+var (
+	_ Cadre                 = (*cadre)(nil)
+	_ InProcessConnProvider = (*cadre)(nil)
+)
+
 type cadre struct {
 	ctx              context.Context
 	ctxCancel        func()
@@ -34,13 +50,23 @@ type cadre struct {
 	metrics *metrics.Registry
 
 	grpcHealthService *health.Server
+	services          []*service // serving statuses in the health service
+
+	orca *grpcorca.Reporter // can be nil
 
 	swg          sync.WaitGroup // services wait group
 	grpcAddr     string
 	grpcServer   *grpc.Server
 	grpcListener net.Listener
 
-	httpServers map[string]*stdhttp.Server
+	// services registered with WithServiceRegistration called without the network
+	inProcess *inprocess.Channel
+
+	httpServers  map[string]*stdhttp.Server
+	http3Servers map[string]*http3.Server
+
+	trustedProxies     proxy.TrustedProxies
+	proxyProtocolAddrs map[string]bool // http listeners expecting PROXY protocol headers
 }
 
 func (c *cadre) Start() (err error) {
@@ -80,6 +106,23 @@ func (c *cadre) Start() (err error) {
 		go c.startHTTPServer(port, httpServer)
 	}
 
+	// start http/3 servers
+	for port, http3Server := range c.http3Servers {
+		c.swg.Add(1)
+
+		go c.startHTTP3Server(port, http3Server)
+	}
+
+	// keep the health service in sync with the status - also when the grpc server is multiplexed
+	if c.grpcHealthService != nil {
+		go c.healthServerCheck()
+	}
+
+	// sample load metrics reported to clients
+	if c.orca != nil {
+		go c.orca.Run(c.ctx)
+	}
+
 	// start grpc server
 	c.swg.Add(1)
 
@@ -99,6 +142,18 @@ func (c *cadre) Start() (err error) {
 	return
 }
 
+// InProcessConn returns a connection calling the services registered with WithServiceRegistration (and the health
+// service) directly, through the same interceptors as the grpc server but without serialization and the network.
+// It is available right after Build, so that handlers can be constructed with it; it is nil unless enabled
+// with WithInProcessConn (or needed by WithGRPCGateway).
+func (c *cadre) InProcessConn() grpc.ClientConnInterface {
+	if c.inProcess == nil {
+		return nil
+	}
+
+	return c.inProcess
+}
+
 // This function shutdown the Start function that is waiting for sigsDone.
 // The Start function initiates the context cancelation and waits.
 func (c *cadre) Shutdown() error {
@@ -123,7 +178,28 @@ func (c *cadre) startHTTPServer(addr string, httpServer *stdhttp.Server) {
 		_ = httpServer.Shutdown(context.Background())
 	}()
 
-	err := httpServer.ListenAndServe()
+	var lc net.ListenConfig
+
+	listener, err := lc.Listen(c.ctx, "tcp", addr)
+	if err != nil {
+		c.logger.Error().
+			Err(err).
+			Msg("http server failed")
+
+		return
+	}
+
+	if c.proxyProtocolAddrs[addr] {
+		listener = proxy.NewListener(listener, c.trustedProxies)
+	}
+
+	if httpServer.TLSConfig != nil {
+		// certificates are provided by the tls configuration
+		err = httpServer.ServeTLS(listener, "", "")
+	} else {
+		err = httpServer.Serve(listener)
+	}
+
 	if err != nil && err != stdhttp.ErrServerClosed {
 		c.logger.Error().
 			Err(err).
@@ -131,26 +207,56 @@ func (c *cadre) startHTTPServer(addr string, httpServer *stdhttp.Server) {
 	}
 }
 
+func (c *cadre) startHTTP3Server(addr string, http3Server *http3.Server) {
+	defer c.swg.Done()
+
+	c.logger.Debug().
+		Str("addr", addr).
+		Msg("starting http/3 server")
+
+	go func() {
+		// wait for cadre's context to be done and shutdown the http/3 server
+		<-c.ctx.Done()
+
+		_ = http3Server.Shutdown(context.Background())
+	}()
+
+	err := http3Server.ListenAndServe()
+	if err != nil && err != stdhttp.ErrServerClosed && !errors.Is(err, quic.ErrServerClosed) {
+		c.logger.Error().
+			Err(err).
+			Msg("http/3 server failed")
+	}
+}
+
+// healthServerCheck pushes changes of the status to the serving statuses of the health service until the cadre is
+// stopped, then it marks all services as not serving.
 func (c *cadre) healthServerCheck() {
-	t := time.NewTicker(5 * time.Second)
+	reports, stop := c.status.Watch()
+	defer stop()
+
+	// changes between Build and the watch
+	c.updateHealth(c.status.Report())
 
 	for {
 		select {
-		case <-t.C:
-			report := c.status.Report()
-			switch report.Status {
-			case status.OK:
-				c.grpcHealthService.Resume()
-
-			case status.WARN, status.ERROR:
-				c.grpcHealthService.Shutdown()
-			}
+		case report := <-reports:
+			c.updateHealth(report)
+
 		case <-c.ctx.Done():
+			c.grpcHealthService.Shutdown()
+
 			return
 		}
 	}
 }
 
+func (c *cadre) updateHealth(report status.Report) {
+	for _, s := range c.services {
+		s.Update(report)
+	}
+}
+
 func (c *cadre) startGRPC() {
 	defer c.swg.Done()
 
@@ -168,10 +274,6 @@ func (c *cadre) startGRPC() {
 		Str("addr", c.grpcAddr).
 		Msg("starting grpc server")
 
-	if c.grpcHealthService != nil {
-		go c.healthServerCheck()
-	}
-
 	go func() {
 		// wait for cadre's context to be done and shutdown the grpc server
 		<-c.ctx.Done()
@@ -0,0 +1,471 @@
+package cadre
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	stdhttp "net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/moderntv/cadre/status"
+	"github.com/quic-go/quic-go/http3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	testgrpc "google.golang.org/grpc/interop/grpc_testing"
+)
+
+// testCertificate creates a self-signed certificate for 127.0.0.1 and a pool trusting it.
+func testCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "cadre test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+// freeAddr returns a local address with both the tcp and the udp port free.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	for {
+		udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		addr := udp.LocalAddr().String()
+
+		tcp, err := net.Listen("tcp", addr)
+		_ = udp.Close()
+
+		if err == nil {
+			_ = tcp.Close()
+
+			return addr
+		}
+	}
+}
+
+// build returns the error of creating or building the cadre.
+func build(options ...Option) error {
+	b, err := NewBuilder("test", options...)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.Build()
+
+	return err
+}
+
+// startCadre builds and starts the cadre until the end of the test.
+func startCadre(t *testing.T, options ...Option) *cadre {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(t.Context())
+
+	b, err := NewBuilder("test", append([]Option{WithContext(ctx)}, options...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+
+	go func() { done <- c.Start() }()
+
+	t.Cleanup(func() {
+		cancel()
+
+		err := <-done
+		if err != nil {
+			t.Errorf("Start() error = %v", err)
+		}
+	})
+
+	return c
+}
+
+// eventually retries f until it succeeds, the servers are started asynchronously.
+func eventually(t *testing.T, f func() error) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		err := f()
+		if err == nil {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func get(t *testing.T, client *stdhttp.Client, url string) (resp *stdhttp.Response, body string) {
+	t.Helper()
+
+	eventually(t, func() error {
+		r, err := client.Get(url)
+		if err != nil {
+			return err
+		}
+
+		defer r.Body.Close()
+
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+
+		resp, body = r, string(b)
+
+		return nil
+	})
+
+	return
+}
+
+func TestBuilder_HTTPOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []HTTPOption
+		wantErr string
+	}{
+		{
+			name:    "http/3 without tls",
+			options: []HTTPOption{WithHTTPListeningAddress("127.0.0.1:0"), WithHTTP3()},
+			wantErr: "cannot serve http/3 without tls configuration",
+		},
+		{
+			name:    "nil tls configuration",
+			options: []HTTPOption{WithTLSConfig(nil)},
+			wantErr: "tls configuration cannot be nil",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := build(WithHTTP("api", tt.options...))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("build error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCadre_TLS(t *testing.T) {
+	cert, pool := testCertificate(t)
+
+	tests := []struct {
+		name       string
+		http3      bool
+		quic       bool
+		wantProto  string
+		wantAltSvc bool
+	}{
+		{name: "tls", wantProto: "HTTP/1.1"},
+		{name: "http/3 advertised", http3: true, wantProto: "HTTP/1.1", wantAltSvc: true},
+		{name: "http/3", http3: true, quic: true, wantProto: "HTTP/3.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := freeAddr(t)
+			options := []HTTPOption{
+				WithHTTPListeningAddress(addr),
+				WithTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}),
+				WithRoute("GET", "/proto", func(c *gin.Context) {
+					c.String(stdhttp.StatusOK, c.Request.Proto)
+				}),
+			}
+
+			if tt.http3 {
+				options = append(options, WithHTTP3())
+			}
+
+			startCadre(t, WithHTTP("api", options...))
+
+			var transport stdhttp.RoundTripper = &stdhttp.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+			if tt.quic {
+				quic := &http3.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+				t.Cleanup(func() { _ = quic.Close() })
+
+				transport = quic
+			}
+
+			resp, body := get(t, &stdhttp.Client{Transport: transport}, "https://"+addr+"/proto")
+			if resp.StatusCode != stdhttp.StatusOK || body != tt.wantProto {
+				t.Errorf("response = %d %q, want %d %q", resp.StatusCode, body, stdhttp.StatusOK, tt.wantProto)
+			}
+
+			_, port, _ := net.SplitHostPort(addr)
+
+			wantAltSvc := ""
+			if tt.wantAltSvc {
+				wantAltSvc = fmt.Sprintf(`h3=":%s"; ma=2592000`, port)
+			}
+
+			if got := resp.Header.Get("Alt-Svc"); got != wantAltSvc {
+				t.Errorf("Alt-Svc = %q, want %q", got, wantAltSvc)
+			}
+		})
+	}
+}
+
+func TestBuilder_ProxyProtocol(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		wantErr bool
+	}{
+		{name: "without trusted proxies", wantErr: true},
+		{name: "trusted proxies", options: []Option{WithTrustedProxies("10.0.0.0/8")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := build(append(tt.options,
+				WithHTTP("api", WithHTTPListeningAddress("127.0.0.1:0"), WithProxyProtocol()),
+			)...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("build error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuilder_GRPCGateway(t *testing.T) {
+	register := func(context.Context, *runtime.ServeMux, grpc.ClientConnInterface) error { return nil }
+
+	tests := []struct {
+		name    string
+		service GRPCOption
+		wantErr string
+	}{
+		{
+			name: "service registration",
+			service: WithServiceRegistration("registration", func(s grpc.ServiceRegistrar) {
+				testgrpc.RegisterTestServiceServer(s, testService{})
+			}),
+		},
+		{
+			name: "service",
+			service: WithService("registrator", func(s *grpc.Server) {
+				testgrpc.RegisterTestServiceServer(s, testService{})
+			}),
+			wantErr: "grpc-gateway cannot call services registered with WithService",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := build(
+				WithGRPC(WithGRPCListeningAddress("127.0.0.1:0"), tt.service),
+				WithHTTP("api", WithHTTPListeningAddress("127.0.0.1:0"), WithGRPCGateway(register)),
+			)
+			if (err == nil) != (tt.wantErr == "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("build error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type testService struct {
+	testgrpc.UnimplementedTestServiceServer
+}
+
+func (testService) EmptyCall(context.Context, *testgrpc.Empty) (*testgrpc.Empty, error) {
+	return &testgrpc.Empty{}, nil
+}
+
+func TestBuilder_InProcessConn(t *testing.T) {
+	tests := []struct {
+		name    string
+		service GRPCOption
+		wantErr string
+	}{
+		{
+			name: "service registration",
+			service: WithServiceRegistration("registration", func(s grpc.ServiceRegistrar) {
+				testgrpc.RegisterTestServiceServer(s, testService{})
+			}),
+		},
+		{
+			name: "service",
+			service: WithService("registrator", func(s *grpc.Server) {
+				testgrpc.RegisterTestServiceServer(s, testService{})
+			}),
+			wantErr: "in-process connection cannot call services registered with WithService",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := build(WithGRPC(WithGRPCListeningAddress("127.0.0.1:0"), tt.service, WithInProcessConn()))
+			if (err == nil) != (tt.wantErr == "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("build error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCadre_InProcessConn(t *testing.T) {
+	registration := WithServiceRegistration("registration", func(s grpc.ServiceRegistrar) {
+		testgrpc.RegisterTestServiceServer(s, testService{})
+	})
+
+	c := startCadre(t, WithGRPC(WithGRPCListeningAddress("127.0.0.1:0"), registration, WithInProcessConn()))
+
+	var provider InProcessConnProvider = c
+
+	_, err := testgrpc.NewTestServiceClient(provider.InProcessConn()).EmptyCall(t.Context(), &testgrpc.Empty{})
+	if err != nil {
+		t.Errorf("EmptyCall() error = %v", err)
+	}
+
+	// the connection is opt-in
+	c = startCadre(t, WithGRPC(WithGRPCListeningAddress("127.0.0.1:0"), registration))
+	if conn := c.InProcessConn(); conn != nil {
+		t.Errorf("InProcessConn() = %v, want nil", conn)
+	}
+}
+
+func TestCadre_Health(t *testing.T) {
+	st := status.NewStatus("test")
+
+	database, err := st.Register("database")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := st.Register("cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	database.SetStatus(status.OK, "")
+	cache.SetStatus(status.OK, "")
+
+	c := startCadre(t,
+		WithStatus(st),
+		WithGRPC(
+			WithGRPCListeningAddress("127.0.0.1:0"),
+			WithServiceRegistration("users", func(s grpc.ServiceRegistrar) {
+				testgrpc.RegisterTestServiceServer(s, testService{})
+			}),
+			WithServiceHealth("users", "database"),
+		),
+	)
+
+	conn, err := grpc.NewClient(
+		c.grpcListener.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	client := healthpb.NewHealthClient(conn)
+
+	const (
+		serving    = healthpb.HealthCheckResponse_SERVING
+		notServing = healthpb.HealthCheckResponse_NOT_SERVING
+	)
+
+	// steps are applied in order
+	tests := []struct {
+		name        string
+		database    status.StatusType
+		cache       status.StatusType
+		wantOverall healthpb.HealthCheckResponse_ServingStatus
+		wantUsers   healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{name: "ok", database: status.OK, cache: status.OK, wantOverall: serving, wantUsers: serving},
+		{name: "degraded", database: status.WARN, cache: status.OK, wantOverall: serving, wantUsers: serving},
+		{
+			name:        "independent component",
+			database:    status.WARN,
+			cache:       status.ERROR,
+			wantOverall: notServing,
+			wantUsers:   serving,
+		},
+		{
+			name:        "service component",
+			database:    status.ERROR,
+			cache:       status.ERROR,
+			wantOverall: notServing,
+			wantUsers:   notServing,
+		},
+		{name: "recovered", database: status.OK, cache: status.WARN, wantOverall: serving, wantUsers: serving},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database.SetStatus(tt.database, "")
+			cache.SetStatus(tt.cache, "")
+
+			eventually(t, func() error {
+				overall, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: ""})
+				if err != nil {
+					return err
+				}
+
+				users, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "users"})
+				if err != nil {
+					return err
+				}
+
+				if overall.GetStatus() != tt.wantOverall || users.GetStatus() != tt.wantUsers {
+					return fmt.Errorf("serving status = %v, users %v, want %v, users %v",
+						overall.GetStatus(), users.GetStatus(), tt.wantOverall, tt.wantUsers)
+				}
+
+				return nil
+			})
+		})
+	}
+}
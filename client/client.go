@@ -0,0 +1,201 @@
+// Package client creates preconfigured gRPC client connections to cadre services.
+//
+// NewConn resolves service names through a registry.Registry, balances calls across the instances
+// (round robin or by shard key), checks health of the instances and attaches client interceptors
+// propagating request IDs, tracing the calls, logging them and exporting metrics:
+//
+//	conn, err := client.NewConn("users",
+//		client.WithRegistry(reg),
+//		client.WithLoadBalancing(client.Shard),
+//		client.WithMetrics(metricsRegistry),
+//		client.WithLogger(logger),
+//	)
+//
+// The shard and weighted round robin policies are provided by packages lb/shard and lb/wrr which have to be
+// imported to register them.
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/moderntv/cadre/grpc/grpcmetrics"
+	"github.com/moderntv/cadre/metrics"
+	"github.com/moderntv/cadre/registry"
+	"github.com/moderntv/cadre/requestid"
+	grpc_zerolog "github.com/rkollar/go-grpc-middleware/logging/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/roundrobin"
+	_ "google.golang.org/grpc/health" // registers client health checking
+)
+
+const (
+	// RoundRobin load balancing policy.
+	RoundRobin = roundrobin.Name
+	// Shard load balancing policy picking instances by the shard key of the call. Requires importing lb/shard.
+	Shard = "shard"
+	// WeightedRoundRobin load balancing policy weighting instances by their ORCA load reports.
+	// Requires importing lb/wrr.
+	WeightedRoundRobin = "wrr"
+)
+
+// NewConn creates a client connection to the target. Targets without scheme are resolved
+// by the registry when WithRegistry is used.
+func NewConn(target string, opts ...Option) (conn *grpc.ClientConn, err error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		err = opt(o)
+		if err != nil {
+			err = fmt.Errorf("cannot apply client option: %w", err)
+			return
+		}
+	}
+
+	if balancer.Get(o.loadBalancing) == nil {
+		err = fmt.Errorf("load balancing policy `%s` is not registered", o.loadBalancing)
+		return
+	}
+
+	dialOptions, err := o.buildDialOptions()
+	if err != nil {
+		return
+	}
+
+	if o.registry != nil && !strings.Contains(target, "://") {
+		target = registry.Scheme + ":///" + target
+	}
+
+	conn, err = grpc.NewClient(target, dialOptions...)
+	if err != nil {
+		err = fmt.Errorf("cannot create client connection to `%s`: %w", target, err)
+		return
+	}
+
+	return
+}
+
+func (o *options) buildDialOptions() (dialOptions []grpc.DialOption, err error) {
+	ps, err := newPolicies(o.policies)
+	if err != nil {
+		return
+	}
+
+	serviceConfig, err := o.serviceConfig(ps).JSON()
+	if err != nil {
+		err = fmt.Errorf("cannot render service config: %w", err)
+		return
+	}
+
+	var cp *callPolicies
+	if len(ps.byName) > 0 {
+		cp = &callPolicies{
+			policies: ps,
+			budget:   newBudget(o.retryBudget),
+		}
+
+		cp.metrics, err = newPolicyMetrics(o.metrics)
+		if err != nil {
+			return
+		}
+	}
+
+	unaryInterceptors, streamInterceptors, err := o.interceptors(cp)
+	if err != nil {
+		return
+	}
+
+	dialOptions = []grpc.DialOption{
+		grpc.WithTransportCredentials(o.transportCredentials),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithChainUnaryInterceptor(unaryInterceptors...),
+		grpc.WithChainStreamInterceptor(streamInterceptors...),
+	}
+
+	if cp != nil {
+		dialOptions = append(dialOptions, grpc.WithStatsHandler(cp))
+	}
+
+	if o.metrics != nil {
+		var streams *grpcmetrics.Streams
+
+		streams, err = grpcmetrics.NewClientStreams(o.metrics, metrics.HistogramConfig{})
+		if err != nil {
+			return
+		}
+
+		dialOptions = append(dialOptions, grpc.WithStatsHandler(streams))
+	}
+
+	if o.registry != nil {
+		dialOptions = append(dialOptions, grpc.WithResolvers(registry.NewResolverBuilder(o.registry)))
+	}
+
+	dialOptions = append(dialOptions, o.dialOptions...)
+
+	return
+}
+
+// interceptors - always in order: request id, tracing, logging, metrics, hedging, custom, attempts tracking.
+func (o *options) interceptors(cp *callPolicies) (
+	unaryInterceptors []grpc.UnaryClientInterceptor,
+	streamInterceptors []grpc.StreamClientInterceptor,
+	err error,
+) {
+	unaryInterceptors = []grpc.UnaryClientInterceptor{requestid.UnaryClientInterceptor()}
+	streamInterceptors = []grpc.StreamClientInterceptor{requestid.StreamClientInterceptor()}
+
+	if o.tracing != nil {
+		unaryInterceptors = append(unaryInterceptors, o.tracing.UnaryClientInterceptor())
+		streamInterceptors = append(streamInterceptors, o.tracing.StreamClientInterceptor())
+	}
+
+	if o.logger != nil {
+		unaryInterceptors = append(unaryInterceptors, grpc_zerolog.UnaryClientInterceptor(*o.logger))
+		streamInterceptors = append(streamInterceptors, grpc_zerolog.StreamClientInterceptor(*o.logger))
+	}
+
+	if o.metrics != nil {
+		var clientMetrics *grpc_prometheus.ClientMetrics
+
+		clientMetrics, err = registerClientMetrics(o)
+		if err != nil {
+			return
+		}
+
+		unaryInterceptors = append(unaryInterceptors, clientMetrics.UnaryClientInterceptor())
+		streamInterceptors = append(streamInterceptors, clientMetrics.StreamClientInterceptor())
+	}
+
+	if cp != nil {
+		unaryInterceptors = append(unaryInterceptors, cp.HedgingUnaryClientInterceptor())
+	}
+
+	unaryInterceptors = append(unaryInterceptors, o.unaryInterceptors...)
+	streamInterceptors = append(streamInterceptors, o.streamInterceptors...)
+
+	if cp != nil {
+		unaryInterceptors = append(unaryInterceptors, cp.AttemptsUnaryClientInterceptor())
+		streamInterceptors = append(streamInterceptors, cp.AttemptsStreamClientInterceptor())
+	}
+
+	return
+}
+
+// registerClientMetrics registers client metrics shared by all connections reporting into the registry.
+func registerClientMetrics(o *options) (clientMetrics *grpc_prometheus.ClientMetrics, err error) {
+	collector, err := o.metrics.RegisterOrGet("grpc_client", grpc_prometheus.NewClientMetrics())
+	if err != nil {
+		err = fmt.Errorf("cannot register grpc client metrics to metrics registry: %w", err)
+		return
+	}
+
+	clientMetrics, ok := collector.(*grpc_prometheus.ClientMetrics)
+	if !ok {
+		err = fmt.Errorf("metric `grpc_client` is already registered as %T", collector)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,220 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/moderntv/cadre/metrics"
+	"github.com/moderntv/cadre/registry/static"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type testServer struct {
+	addr   string
+	health *health.Server
+	calls  atomic.Int64
+}
+
+// newTestServer starts a server with the health service. The hook is called before every call
+// with its number (starting at 1) and may fail the call.
+func newTestServer(t *testing.T, hook ...func(ctx context.Context, n int64) error) *testServer {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{
+		addr:   lis.Addr().String(),
+		health: health.NewServer(),
+	}
+
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		n := s.calls.Add(1)
+		for _, h := range hook {
+			err := h(ctx, n)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return handler(ctx, req)
+	}))
+	healthpb.RegisterHealthServer(server, s.health)
+
+	go func() { _ = server.Serve(lis) }()
+
+	t.Cleanup(server.Stop)
+
+	return s
+}
+
+func check(t *testing.T, conn *grpc.ClientConn, n int) {
+	t.Helper()
+
+	client := healthpb.NewHealthClient(conn)
+	for range n {
+		_, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "users"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// eventually retries f until it succeeds or the time is out.
+func eventually(t *testing.T, f func() error) {
+	t.Helper()
+
+	var err error
+
+	for range 100 {
+		err = f()
+		if err == nil {
+			return
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	t.Fatal(err)
+}
+
+func TestNewConn(t *testing.T) {
+	s1 := newTestServer(t)
+	s2 := newTestServer(t)
+
+	for _, s := range []*testServer{s1, s2} {
+		s.health.SetServingStatus("users", healthpb.HealthCheckResponse_SERVING)
+	}
+
+	reg, err := static.NewRegistry(map[string][]string{
+		"users": {s1.addr, s2.addr},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metricsRegistry, err := metrics.NewRegistry("test", prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := NewConn("users", WithRegistry(reg), WithMetrics(metricsRegistry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	check(t, conn, 10)
+
+	// round robin
+	if s1.calls.Load() == 0 || s2.calls.Load() == 0 {
+		t.Errorf("calls = %d, %d, want both instances called", s1.calls.Load(), s2.calls.Load())
+	}
+
+	// unhealthy instance is not picked
+	s2.health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	eventually(t, func() error {
+		before := s2.calls.Load()
+		check(t, conn, 4)
+
+		if s2.calls.Load() != before {
+			return errors.New("unhealthy instance called")
+		}
+
+		return nil
+	})
+
+	families, err := metricsRegistry.GetPrometheusRegistry().Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+
+	for _, name := range []string{"grpc_client_handled_total", "test_grpc_client_stream_messages_sent_total"} {
+		if !slices.Contains(names, name) {
+			t.Errorf("metrics = %v, want %s", names, name)
+		}
+	}
+
+	// client metrics are shared by connections reporting into the same registry
+	other, err := NewConn("users", WithRegistry(reg), WithMetrics(metricsRegistry))
+	if err != nil {
+		t.Fatalf("NewConn() with shared metrics error = %v", err)
+	}
+
+	_ = other.Close()
+}
+
+func TestNewConnWithoutHealthCheck(t *testing.T) {
+	s := newTestServer(t)
+	s.health.SetServingStatus("users", healthpb.HealthCheckResponse_SERVING)
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	conn, err := NewConn(s.addr, WithoutHealthCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	check(t, conn, 1)
+
+	if got := s.calls.Load(); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestNewConnUnknownPolicy(t *testing.T) {
+	_, err := NewConn("localhost:5000", WithLoadBalancing("unknown"))
+	if err == nil {
+		t.Error("NewConn() with unknown policy succeeded")
+	}
+}
+
+func TestServiceConfig(t *testing.T) {
+	tests := []struct {
+		healthCheck bool
+		want        string
+	}{
+		{healthCheck: true, want: `{"loadBalancingConfig":[{"shard":{}}],"healthCheckConfig":{"serviceName":"users"}}`},
+		{healthCheck: false, want: `{"loadBalancingConfig":[{"shard":{}}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.healthCheck), func(t *testing.T) {
+			o := defaultOptions()
+			o.loadBalancing = Shard
+			o.healthCheck = tt.healthCheck
+			o.healthCheckService = "users"
+
+			got, err := o.serviceConfig(nil).JSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("JSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
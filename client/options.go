@@ -0,0 +1,197 @@
+package client
+
+import (
+	"errors"
+
+	"github.com/moderntv/cadre/metrics"
+	"github.com/moderntv/cadre/registry"
+	"github.com/moderntv/cadre/tracing"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type options struct {
+	registry registry.Registry
+
+	loadBalancing      string
+	healthCheck        bool
+	healthCheckService string
+
+	logger  *zerolog.Logger
+	metrics *metrics.Registry
+	tracing *tracing.Tracing
+
+	policies    []MethodPolicy
+	retryBudget *RetryBudget
+
+	transportCredentials credentials.TransportCredentials
+	unaryInterceptors    []grpc.UnaryClientInterceptor
+	streamInterceptors   []grpc.StreamClientInterceptor
+	dialOptions          []grpc.DialOption
+}
+
+func defaultOptions() *options {
+	return &options{
+		loadBalancing:        RoundRobin,
+		healthCheck:          true,
+		transportCredentials: insecure.NewCredentials(),
+	}
+}
+
+type Option func(*options) error
+
+// WithRegistry resolves targets without scheme (or with the `registry` scheme) as services in the registry.
+func WithRegistry(r registry.Registry) Option {
+	return func(o *options) error {
+		if r == nil {
+			return errors.New("registry cannot be nil")
+		}
+
+		o.registry = r
+
+		return nil
+	}
+}
+
+// WithLoadBalancing sets the load balancing policy - RoundRobin (default), Shard or WeightedRoundRobin.
+// Any other registered balancer may be used as well.
+func WithLoadBalancing(policy string) Option {
+	return func(o *options) error {
+		o.loadBalancing = policy
+
+		return nil
+	}
+}
+
+// WithHealthCheckService sets the service name used in client health checks. Empty name (default)
+// checks the overall health of the server.
+func WithHealthCheckService(service string) Option {
+	return func(o *options) error {
+		o.healthCheckService = service
+
+		return nil
+	}
+}
+
+// WithoutHealthCheck disables client health checking; all connected backends are picked.
+func WithoutHealthCheck() Option {
+	return func(o *options) error {
+		o.healthCheck = false
+
+		return nil
+	}
+}
+
+// WithMethodPolicies sets retry or hedging policies of the methods. Retries are performed by grpc according
+// to the rendered service config, hedging by the client (grpc-go does not implement hedging policies).
+// Retries, hedges and exhaustion of the retry budget are recorded in grpc_client_* metrics.
+func WithMethodPolicies(policies ...MethodPolicy) Option {
+	return func(o *options) error {
+		o.policies = append(o.policies, policies...)
+
+		return nil
+	}
+}
+
+// WithRetryBudget limits retries and hedges of the connection to prevent retry storms.
+func WithRetryBudget(b RetryBudget) Option {
+	return func(o *options) error {
+		err := b.normalize()
+		if err != nil {
+			return err
+		}
+
+		o.retryBudget = &b
+
+		return nil
+	}
+}
+
+// WithPolicies sets method policies and retry budget from the config.
+func WithPolicies(config PoliciesConfig) Option {
+	return func(o *options) error {
+		err := WithMethodPolicies(config.Methods...)(o)
+		if err != nil || config.RetryBudget == nil {
+			return err
+		}
+
+		return WithRetryBudget(*config.RetryBudget)(o)
+	}
+}
+
+// WithLogger enables logging of finished calls.
+func WithLogger(logger zerolog.Logger) Option {
+	return func(o *options) error {
+		o.logger = &logger
+
+		return nil
+	}
+}
+
+// WithMetrics enables client metrics (grpc_client_*, including stream metrics) registered into the registry.
+func WithMetrics(metricsRegistry *metrics.Registry) Option {
+	return func(o *options) error {
+		if metricsRegistry == nil {
+			return errors.New("metrics registry cannot be nil")
+		}
+
+		o.metrics = metricsRegistry
+
+		return nil
+	}
+}
+
+// WithTracing enables client spans and propagation of the trace context to the servers.
+func WithTracing(t *tracing.Tracing) Option {
+	return func(o *options) error {
+		if t == nil {
+			return errors.New("tracing cannot be nil")
+		}
+
+		o.tracing = t
+
+		return nil
+	}
+}
+
+// WithTransportCredentials sets the credentials of the connection. Connections are insecure by default.
+func WithTransportCredentials(creds credentials.TransportCredentials) Option {
+	return func(o *options) error {
+		if creds == nil {
+			return errors.New("transport credentials cannot be nil")
+		}
+
+		o.transportCredentials = creds
+
+		return nil
+	}
+}
+
+// WithUnaryInterceptors adds unary interceptors called after the built-in ones.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryClientInterceptor) Option {
+	return func(o *options) error {
+		o.unaryInterceptors = append(o.unaryInterceptors, interceptors...)
+
+		return nil
+	}
+}
+
+// WithStreamInterceptors adds stream interceptors called after the built-in ones.
+func WithStreamInterceptors(interceptors ...grpc.StreamClientInterceptor) Option {
+	return func(o *options) error {
+		o.streamInterceptors = append(o.streamInterceptors, interceptors...)
+
+		return nil
+	}
+}
+
+// WithDialOptions adds raw grpc dial options applied after the ones configured by the client.
+func WithDialOptions(dialOptions ...grpc.DialOption) Option {
+	return func(o *options) error {
+		o.dialOptions = append(o.dialOptions, dialOptions...)
+
+		return nil
+	}
+}
@@ -0,0 +1,347 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+var ErrInvalidPolicy = errors.New("invalid call policy")
+
+// RetryPolicy retries failed calls with exponential backoff. Zero fields are set to defaults:
+// 3 attempts, 100ms initial backoff, 1s max backoff, multiplier 2 and retrying UNAVAILABLE only.
+// gRPC caps the attempts at 5. Backoffs are configured as duration strings (e.g. `100ms`) or integer nanoseconds.
+type RetryPolicy struct {
+	MaxAttempts       int           `json:"max_attempts,omitempty"       yaml:"max_attempts,omitempty"`
+	InitialBackoff    time.Duration `json:"initial_backoff,omitempty"    yaml:"initial_backoff,omitempty"`
+	MaxBackoff        time.Duration `json:"max_backoff,omitempty"        yaml:"max_backoff,omitempty"`
+	BackoffMultiplier float64       `json:"backoff_multiplier,omitempty" yaml:"backoff_multiplier,omitempty"`
+	// RetryableCodes are names of the status codes, e.g. `UNAVAILABLE` or `RESOURCE_EXHAUSTED`.
+	RetryableCodes []string `json:"retryable_codes,omitempty" yaml:"retryable_codes,omitempty"`
+}
+
+// HedgingPolicy sends up to MaxAttempts copies of a unary call, a new one every HedgingDelay (or immediately
+// after a copy fails with a non-fatal code) until one of them succeeds or fails with a fatal code.
+// Zero MaxAttempts is set to 3. Hedged calls must be idempotent. HedgingDelay is configured as a duration string
+// (e.g. `50ms`) or integer nanoseconds.
+type HedgingPolicy struct {
+	MaxAttempts  int           `json:"max_attempts,omitempty"  yaml:"max_attempts,omitempty"`
+	HedgingDelay time.Duration `json:"hedging_delay,omitempty" yaml:"hedging_delay,omitempty"`
+	// NonFatalCodes are names of the status codes which do not stop hedging, e.g. `UNAVAILABLE`.
+	NonFatalCodes []string `json:"non_fatal_codes,omitempty" yaml:"non_fatal_codes,omitempty"`
+}
+
+// UnmarshalJSON decodes the backoffs also from duration strings.
+func (p *RetryPolicy) UnmarshalJSON(b []byte) error {
+	type plain RetryPolicy
+
+	aux := struct {
+		*plain
+
+		InitialBackoff jsonDuration `json:"initial_backoff,omitempty"`
+		MaxBackoff     jsonDuration `json:"max_backoff,omitempty"`
+	}{
+		plain:          (*plain)(p),
+		InitialBackoff: jsonDuration(p.InitialBackoff),
+		MaxBackoff:     jsonDuration(p.MaxBackoff),
+	}
+
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+
+	p.InitialBackoff = time.Duration(aux.InitialBackoff)
+	p.MaxBackoff = time.Duration(aux.MaxBackoff)
+
+	return nil
+}
+
+// UnmarshalJSON decodes the hedging delay also from a duration string.
+func (p *HedgingPolicy) UnmarshalJSON(b []byte) error {
+	type plain HedgingPolicy
+
+	aux := struct {
+		*plain
+
+		HedgingDelay jsonDuration `json:"hedging_delay,omitempty"`
+	}{
+		plain:        (*plain)(p),
+		HedgingDelay: jsonDuration(p.HedgingDelay),
+	}
+
+	err := json.Unmarshal(b, &aux)
+	if err != nil {
+		return err
+	}
+
+	p.HedgingDelay = time.Duration(aux.HedgingDelay)
+
+	return nil
+}
+
+// jsonDuration decodes a duration string (e.g. `100ms`) or integer nanoseconds.
+type jsonDuration time.Duration
+
+func (d *jsonDuration) UnmarshalJSON(b []byte) error {
+	var s string
+
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		var ns int64
+
+		err = json.Unmarshal(b, &ns)
+		if err != nil {
+			return fmt.Errorf("invalid duration %s: has to be a duration string or integer nanoseconds", b)
+		}
+
+		*d = jsonDuration(ns)
+
+		return nil
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = jsonDuration(v)
+
+	return nil
+}
+
+// MethodPolicy configures either retrying or hedging of calls of the method.
+type MethodPolicy struct {
+	// Service is the full name of the gRPC service, e.g. `users.v1.Users`.
+	Service string `json:"service" yaml:"service"`
+	// Method name; empty applies the policy to all methods of the service without their own policy.
+	Method string `json:"method,omitempty" yaml:"method,omitempty"`
+
+	Retry   *RetryPolicy   `json:"retry,omitempty"   yaml:"retry,omitempty"`
+	Hedging *HedgingPolicy `json:"hedging,omitempty" yaml:"hedging,omitempty"`
+}
+
+// RetryBudget limits retries and hedges when too many calls fail. Every failed attempt (with a retryable
+// or non-fatal code) takes a token, every successful call returns TokenRatio tokens. Retries and hedges
+// are sent only while there are more than MaxTokens/2 tokens. Zero fields are set to 10 tokens and 0.1 ratio.
+type RetryBudget struct {
+	MaxTokens  float64 `json:"max_tokens,omitempty"  yaml:"max_tokens,omitempty"`
+	TokenRatio float64 `json:"token_ratio,omitempty" yaml:"token_ratio,omitempty"`
+}
+
+// PoliciesConfig configures call policies of a client connection.
+type PoliciesConfig struct {
+	Methods     []MethodPolicy `json:"methods"                yaml:"methods"`
+	RetryBudget *RetryBudget   `json:"retry_budget,omitempty" yaml:"retry_budget,omitempty"`
+}
+
+func (c *PoliciesConfig) PostLoad() error {
+	for i := range c.Methods {
+		err := c.Methods[i].normalize()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.RetryBudget != nil {
+		return c.RetryBudget.normalize()
+	}
+
+	return nil
+}
+
+func (p *MethodPolicy) normalize() (err error) {
+	if p.Service == "" {
+		return fmt.Errorf("%w: service cannot be empty", ErrInvalidPolicy)
+	}
+
+	switch {
+	case p.Retry != nil && p.Hedging != nil:
+		err = errors.New("retry and hedging are mutually exclusive")
+	case p.Retry != nil:
+		err = p.Retry.normalize()
+	case p.Hedging != nil:
+		err = p.Hedging.normalize()
+	default:
+		err = errors.New("either retry or hedging has to be set")
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidPolicy, p.name(), err)
+	}
+
+	return nil
+}
+
+func (p *MethodPolicy) name() string {
+	return p.Service + "/" + p.Method
+}
+
+func (p *RetryPolicy) normalize() error {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = 3
+	}
+
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = 100 * time.Millisecond
+	}
+
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = max(time.Second, p.InitialBackoff)
+	}
+
+	if p.BackoffMultiplier == 0 {
+		p.BackoffMultiplier = 2
+	}
+
+	if len(p.RetryableCodes) == 0 {
+		p.RetryableCodes = []string{"UNAVAILABLE"}
+	}
+
+	switch {
+	case p.MaxAttempts < 2:
+		return errors.New("retry max attempts has to be at least 2")
+	case p.InitialBackoff < 0 || p.MaxBackoff < p.InitialBackoff:
+		return errors.New("invalid retry backoff")
+	case p.BackoffMultiplier < 0:
+		return errors.New("retry backoff multiplier cannot be negative")
+	}
+
+	_, err := parseCodes(p.RetryableCodes)
+
+	return err
+}
+
+func (p *HedgingPolicy) normalize() error {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = 3
+	}
+
+	switch {
+	case p.MaxAttempts < 2:
+		return errors.New("hedging max attempts has to be at least 2")
+	case p.HedgingDelay < 0:
+		return errors.New("hedging delay cannot be negative")
+	}
+
+	_, err := parseCodes(p.NonFatalCodes)
+
+	return err
+}
+
+func (b *RetryBudget) normalize() error {
+	if b.MaxTokens == 0 {
+		b.MaxTokens = 10
+	}
+
+	if b.TokenRatio == 0 {
+		b.TokenRatio = 0.1
+	}
+
+	if b.MaxTokens < 0 || b.MaxTokens > 1000 || b.TokenRatio < 0 {
+		return fmt.Errorf(
+			"%w: retry budget max tokens has to be in (0, 1000] and token ratio positive",
+			ErrInvalidPolicy,
+		)
+	}
+
+	return nil
+}
+
+// parseCodes parses names of status codes as used in the service config (e.g. `UNAVAILABLE`).
+func parseCodes(names []string) (map[codes.Code]bool, error) {
+	cs := make(map[codes.Code]bool, len(names))
+
+	for _, name := range names {
+		var c codes.Code
+
+		err := c.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(name))))
+		if err != nil {
+			return nil, fmt.Errorf("unknown status code `%s`", name)
+		}
+
+		cs[c] = true
+	}
+
+	return cs, nil
+}
+
+// policies resolves call policies of methods.
+type policies struct {
+	byName map[string]*methodPolicy
+}
+
+// methodPolicy is a normalized MethodPolicy with parsed codes.
+type methodPolicy struct {
+	service string
+	method  string
+
+	retry   *RetryPolicy
+	hedging *HedgingPolicy
+	codes   map[codes.Code]bool // retryable or non-fatal
+}
+
+func newPolicies(methods []MethodPolicy) (ps *policies, err error) {
+	ps = &policies{byName: map[string]*methodPolicy{}}
+
+	for _, p := range methods {
+		// copy - normalization sets defaults
+		if p.Retry != nil {
+			retry := *p.Retry
+			p.Retry = &retry
+		}
+
+		if p.Hedging != nil {
+			hedging := *p.Hedging
+			p.Hedging = &hedging
+		}
+
+		err = p.normalize()
+		if err != nil {
+			return
+		}
+
+		mp := &methodPolicy{service: p.Service, method: p.Method, retry: p.Retry, hedging: p.Hedging}
+		if p.Retry != nil {
+			mp.codes, _ = parseCodes(p.Retry.RetryableCodes)
+		} else {
+			mp.codes, _ = parseCodes(p.Hedging.NonFatalCodes)
+		}
+
+		if _, ok := ps.byName[p.name()]; ok {
+			err = fmt.Errorf("%w: duplicate policy for %s", ErrInvalidPolicy, p.name())
+			return
+		}
+
+		ps.byName[p.name()] = mp
+	}
+
+	return
+}
+
+// lookup returns the policy of the full method (`/service/method`) or nil.
+func (ps *policies) lookup(fullMethod string) *methodPolicy {
+	service, method := splitMethod(fullMethod)
+
+	if p, ok := ps.byName[service+"/"+method]; ok {
+		return p
+	}
+
+	return ps.byName[service+"/"]
+}
+
+func splitMethod(fullMethod string) (service, method string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+
+	i := strings.LastIndex(fullMethod, "/")
+	if i < 0 {
+		return "unknown", "unknown"
+	}
+
+	return fullMethod[:i], fullMethod[i+1:]
+}
@@ -0,0 +1,337 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/moderntv/cadre/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// budget mirrors the retry throttling of the grpc connection (it cannot be observed from outside), so that
+// exhaustion can be reported and hedges follow the same budget. Nil budget never throttles.
+type budget struct {
+	mu sync.Mutex
+
+	max    float64
+	thresh float64
+	ratio  float64
+	tokens float64
+}
+
+func newBudget(b *RetryBudget) *budget {
+	if b == nil {
+		return nil
+	}
+
+	return &budget{
+		max:    b.MaxTokens,
+		thresh: b.MaxTokens / 2,
+		ratio:  b.TokenRatio,
+		tokens: b.MaxTokens,
+	}
+}
+
+// failure takes a token and returns whether retries are throttled.
+func (b *budget) failure() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = max(b.tokens-1, 0)
+
+	return b.tokens <= b.thresh
+}
+
+func (b *budget) success() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.tokens+b.ratio, b.max)
+}
+
+// throttled returns whether retries and hedges are currently throttled.
+func (b *budget) throttled() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.tokens <= b.thresh
+}
+
+type policyMetrics struct {
+	retries   *prometheus.CounterVec
+	hedges    *prometheus.CounterVec
+	exhausted *prometheus.CounterVec
+}
+
+// newPolicyMetrics registers the metrics into the registry or creates unregistered ones when it is nil.
+func newPolicyMetrics(metricsRegistry *metrics.Registry) (m *policyMetrics, err error) {
+	labels := []string{"grpc_service", "grpc_method"}
+	opts := map[string]prometheus.CounterOpts{
+		"retries": {
+			Subsystem: "grpc_client",
+			Name:      "retries_total",
+			Help:      "Total number of retry attempts of client calls",
+		},
+		"hedges": {
+			Subsystem: "grpc_client",
+			Name:      "hedges_total",
+			Help:      "Total number of hedged attempts of client calls",
+		},
+		"exhausted": {
+			Subsystem: "grpc_client",
+			Name:      "retry_budget_exhausted_total",
+			Help:      "Total number of retries and hedges not sent due to exhausted retry budget",
+		},
+	}
+
+	vecs := map[string]*prometheus.CounterVec{}
+
+	for name, o := range opts {
+		if metricsRegistry == nil {
+			vecs[name] = prometheus.NewCounterVec(o, labels)
+			continue
+		}
+
+		vecs[name], err = metricsRegistry.RegisterOrGetNewCounterVec("grpc_client_"+o.Name, o, labels)
+		if err != nil {
+			err = fmt.Errorf("cannot register grpc client policy metrics: %w", err)
+			return
+		}
+	}
+
+	m = &policyMetrics{
+		retries:   vecs["retries"],
+		hedges:    vecs["hedges"],
+		exhausted: vecs["exhausted"],
+	}
+
+	return
+}
+
+func (m *policyMetrics) inc(vec *prometheus.CounterVec, fullMethod string) {
+	service, method := splitMethod(fullMethod)
+	vec.WithLabelValues(service, method).Inc()
+}
+
+// callPolicies applies hedging policies and records retries, hedges and exhaustion of the retry budget.
+// Retries are performed by grpc according to the service config.
+type callPolicies struct {
+	policies *policies
+	budget   *budget
+	metrics  *policyMetrics
+}
+
+type attemptsKey struct{}
+
+// call tracks attempts of a single grpc call.
+type call struct {
+	method   string
+	policy   *methodPolicy // nil if the method has no policy
+	attempts atomic.Int32
+}
+
+// AttemptsUnaryClientInterceptor marks the call so that its attempts can be counted by the stats handler.
+// It has to be the innermost interceptor, so that every hedged copy is tracked separately.
+func (cp *callPolicies) AttemptsUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		return invoker(cp.withCall(ctx, method), method, req, reply, cc, opts...)
+	}
+}
+
+// AttemptsStreamClientInterceptor marks the stream so that its attempts can be counted by the stats handler.
+func (cp *callPolicies) AttemptsStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return streamer(cp.withCall(ctx, method), desc, cc, method, opts...)
+	}
+}
+
+func (cp *callPolicies) withCall(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, attemptsKey{}, &call{method: method, policy: cp.policies.lookup(method)})
+}
+
+// HedgingUnaryClientInterceptor sends hedged copies of unary calls of methods with a hedging policy.
+func (cp *callPolicies) HedgingUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		policy := cp.policies.lookup(method)
+
+		replyMsg, ok := reply.(proto.Message)
+		if policy == nil || policy.hedging == nil || !ok {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		return cp.hedge(ctx, method, policy, replyMsg, func(ctx context.Context, reply proto.Message) error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})
+	}
+}
+
+type hedgeResult struct {
+	reply proto.Message
+	err   error
+}
+
+func (cp *callPolicies) hedge(
+	ctx context.Context,
+	method string,
+	policy *methodPolicy,
+	reply proto.Message,
+	invoke func(context.Context, proto.Message) error,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult, policy.hedging.MaxAttempts)
+	started, pending := 0, 0
+
+	start := func() {
+		started++
+		pending++
+
+		go func() {
+			r := reply.ProtoReflect().New().Interface()
+			results <- hedgeResult{reply: r, err: invoke(ctx, r)}
+		}()
+	}
+
+	// hedge sends another copy if allowed by the policy and the budget.
+	hedge := func() bool {
+		if started >= policy.hedging.MaxAttempts {
+			return false
+		}
+
+		if cp.budget.throttled() {
+			cp.metrics.inc(cp.metrics.exhausted, method)
+			return false
+		}
+
+		cp.metrics.inc(cp.metrics.hedges, method)
+		start()
+
+		return true
+	}
+
+	start()
+
+	timer := time.NewTimer(policy.hedging.HedgingDelay)
+	defer timer.Stop()
+
+	var lastErr error
+
+	for {
+		select {
+		case <-timer.C:
+			if hedge() {
+				timer.Reset(policy.hedging.HedgingDelay)
+			}
+
+		case res := <-results:
+			pending--
+
+			if res.err == nil {
+				proto.Reset(reply)
+				proto.Merge(reply, res.reply)
+
+				return nil
+			}
+
+			if !policy.codes[status.Code(res.err)] {
+				return res.err
+			}
+
+			lastErr = res.err
+
+			// non-fatal failure => send the next copy immediately
+			if hedge() {
+				timer.Reset(policy.hedging.HedgingDelay)
+			} else if pending == 0 {
+				return lastErr
+			}
+		}
+	}
+}
+
+func (cp *callPolicies) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+// HandleRPC counts attempts of the calls - all but the first one (and transparent retries) are retries - and
+// updates the budget the same way grpc does: successful calls return tokens, attempts failed with a retryable
+// (or non-fatal) code take them.
+func (cp *callPolicies) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	c, ok := ctx.Value(attemptsKey{}).(*call)
+	if !ok {
+		return
+	}
+
+	switch s := s.(type) {
+	case *stats.Begin:
+		if s.IsTransparentRetryAttempt {
+			return
+		}
+
+		if c.attempts.Add(1) > 1 && c.policy != nil && c.policy.retry != nil {
+			cp.metrics.inc(cp.metrics.retries, c.method)
+		}
+
+	case *stats.End:
+		if s.Error == nil {
+			cp.budget.success()
+			return
+		}
+
+		if c.policy == nil || !c.policy.codes[status.Code(s.Error)] {
+			return
+		}
+
+		throttled := cp.budget.failure()
+		if throttled && c.policy.retry != nil && int(c.attempts.Load()) < c.policy.retry.MaxAttempts {
+			cp.metrics.inc(cp.metrics.exhausted, c.method)
+		}
+	}
+}
+
+func (cp *callPolicies) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (cp *callPolicies) HandleConn(context.Context, stats.ConnStats) {}
@@ -0,0 +1,319 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/moderntv/cadre/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v2"
+)
+
+const healthService = "grpc.health.v1.Health"
+
+func counterValue(t *testing.T, vec *prometheus.CounterVec) float64 {
+	t.Helper()
+
+	m := &dto.Metric{}
+
+	err := vec.WithLabelValues(healthService, "Check").Write(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m.GetCounter().GetValue()
+}
+
+func failFirst(n int64, code codes.Code) func(context.Context, int64) error {
+	return func(_ context.Context, call int64) error {
+		if call <= n {
+			return status.Error(code, "failing")
+		}
+
+		return nil
+	}
+}
+
+// slowFirst delays the first call until it is canceled.
+func slowFirst(ctx context.Context, call int64) error {
+	if call == 1 {
+		<-ctx.Done()
+	}
+
+	return nil
+}
+
+func TestMethodPolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		hook          func(context.Context, int64) error
+		policy        MethodPolicy
+		budget        *RetryBudget
+		wantCode      codes.Code
+		wantCalls     int64
+		wantRetries   float64
+		wantExhausted float64
+		wantHedges    float64
+	}{
+		{
+			name:        "retry",
+			hook:        failFirst(2, codes.Unavailable),
+			policy:      MethodPolicy{Service: healthService, Retry: &RetryPolicy{InitialBackoff: time.Millisecond}},
+			wantCalls:   3,
+			wantRetries: 2,
+		},
+		{
+			name: "not retryable code",
+			hook: failFirst(1, codes.Internal),
+			policy: MethodPolicy{
+				Service: healthService,
+				Method:  "Check",
+				Retry:   &RetryPolicy{InitialBackoff: time.Millisecond},
+			},
+			wantCode:  codes.Internal,
+			wantCalls: 1,
+		},
+		{
+			// 4 tokens => the second failure reaches the threshold and stops retries
+			name:          "retry budget",
+			hook:          failFirst(100, codes.Unavailable),
+			policy:        MethodPolicy{Service: healthService, Retry: &RetryPolicy{InitialBackoff: time.Millisecond}},
+			budget:        &RetryBudget{MaxTokens: 4},
+			wantCode:      codes.Unavailable,
+			wantCalls:     2,
+			wantRetries:   1,
+			wantExhausted: 1,
+		},
+		{
+			name: "hedging",
+			hook: slowFirst,
+			policy: MethodPolicy{
+				Service: healthService,
+				Hedging: &HedgingPolicy{MaxAttempts: 2, HedgingDelay: 10 * time.Millisecond},
+			},
+			wantCalls:  2,
+			wantHedges: 1,
+		},
+		{
+			name: "hedging non-fatal code",
+			hook: failFirst(2, codes.Unavailable),
+			policy: MethodPolicy{
+				Service: healthService,
+				Hedging: &HedgingPolicy{
+					MaxAttempts:   3,
+					HedgingDelay:  time.Minute,
+					NonFatalCodes: []string{"UNAVAILABLE"},
+				},
+			},
+			wantCalls:  3,
+			wantHedges: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, tt.hook)
+			s.health.SetServingStatus("users", healthpb.HealthCheckResponse_SERVING)
+
+			metricsRegistry, err := metrics.NewRegistry("test", prometheus.NewRegistry())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			opts := []Option{WithMethodPolicies(tt.policy), WithMetrics(metricsRegistry)}
+			if tt.budget != nil {
+				opts = append(opts, WithRetryBudget(*tt.budget))
+			}
+
+			conn, err := NewConn(s.addr, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer conn.Close()
+
+			// a hedged call does not wait for the slow first copy
+			ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+			defer cancel()
+
+			_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: "users"})
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("Check() error = %v, want %v", err, tt.wantCode)
+			}
+
+			if got := s.calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+
+			m, err := newPolicyMetrics(metricsRegistry) // registered => the same vectors
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			counters := []struct {
+				name string
+				vec  *prometheus.CounterVec
+				want float64
+			}{
+				{name: "retries", vec: m.retries, want: tt.wantRetries},
+				{name: "exhausted", vec: m.exhausted, want: tt.wantExhausted},
+				{name: "hedges", vec: m.hedges, want: tt.wantHedges},
+			}
+			for _, c := range counters {
+				if got := counterValue(t, c.vec); got != c.want {
+					t.Errorf("%s = %v, want %v", c.name, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewPolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy MethodPolicy
+	}{
+		{name: "no policy", policy: MethodPolicy{Service: "users.v1.Users"}},
+		{
+			name:   "retry and hedging",
+			policy: MethodPolicy{Service: "users.v1.Users", Retry: &RetryPolicy{}, Hedging: &HedgingPolicy{}},
+		},
+		{name: "single attempt", policy: MethodPolicy{Service: "users.v1.Users", Retry: &RetryPolicy{MaxAttempts: 1}}},
+		{
+			name:   "unknown code",
+			policy: MethodPolicy{Service: "users.v1.Users", Retry: &RetryPolicy{RetryableCodes: []string{"NOPE"}}},
+		},
+		{name: "no service", policy: MethodPolicy{Hedging: &HedgingPolicy{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newPolicies([]MethodPolicy{tt.policy})
+			if !errors.Is(err, ErrInvalidPolicy) {
+				t.Errorf("newPolicies() error = %v, want %v", err, ErrInvalidPolicy)
+			}
+		})
+	}
+}
+
+func TestPoliciesConfig(t *testing.T) {
+	cfg := PoliciesConfig{
+		Methods: []MethodPolicy{
+			{Service: "users.v1.Users", Method: "Get", Retry: &RetryPolicy{RetryableCodes: []string{"unavailable"}}},
+			{Service: "users.v1.Users", Hedging: &HedgingPolicy{HedgingDelay: 50 * time.Millisecond}},
+		},
+		RetryBudget: &RetryBudget{},
+	}
+
+	err := cfg.PostLoad()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := defaultOptions()
+
+	err = WithPolicies(cfg)(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ps, err := newPolicies(o.policies)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ps.lookup("/users.v1.Users/Get").retry == nil || ps.lookup("/users.v1.Users/List").hedging == nil {
+		t.Error("lookup() did not find the method and service policies")
+	}
+
+	if p := ps.lookup("/orders.v1.Orders/Get"); p != nil {
+		t.Errorf("lookup() of other service = %+v, want nil", p)
+	}
+
+	got, err := o.serviceConfig(ps).JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"loadBalancingConfig":[{"round_robin":{}}],"healthCheckConfig":{"serviceName":""},` +
+		`"methodConfig":[{"name":[{"service":"users.v1.Users","method":"Get"}],"retryPolicy":{"maxAttempts":3,` +
+		`"initialBackoff":"0.1s","maxBackoff":"1s","backoffMultiplier":2,"retryableStatusCodes":["UNAVAILABLE"]}}],` +
+		`"retryThrottling":{"maxTokens":10,"tokenRatio":0.1}}`
+	if got != want {
+		t.Errorf("JSON() = %s, want %s", got, want)
+	}
+}
+
+func TestPoliciesConfig_Unmarshal(t *testing.T) {
+	tests := []struct {
+		name      string
+		unmarshal func([]byte, any) error
+		data      string
+		want      []MethodPolicy
+		wantErr   bool
+	}{
+		{
+			name:      "json durations",
+			unmarshal: json.Unmarshal,
+			data: `{"methods": [
+				{"service": "users", "retry": {"max_attempts": 4, "initial_backoff": "50ms", "max_backoff": "2s"}},
+				{"service": "users", "hedging": {"max_attempts": 2, "hedging_delay": "1.5s"}}
+			]}`,
+			want: []MethodPolicy{
+				{
+					Service: "users",
+					Retry: &RetryPolicy{
+						MaxAttempts:    4,
+						InitialBackoff: 50 * time.Millisecond,
+						MaxBackoff:     2 * time.Second,
+					},
+				},
+				{Service: "users", Hedging: &HedgingPolicy{MaxAttempts: 2, HedgingDelay: 1500 * time.Millisecond}},
+			},
+		},
+		{
+			name:      "json nanoseconds",
+			unmarshal: json.Unmarshal,
+			data:      `{"methods": [{"service": "users", "retry": {"initial_backoff": 1000000}}]}`,
+			want:      []MethodPolicy{{Service: "users", Retry: &RetryPolicy{InitialBackoff: time.Millisecond}}},
+		},
+		{
+			name:      "yaml",
+			unmarshal: yaml.Unmarshal,
+			data:      "methods:\n- service: users\n  hedging:\n    hedging_delay: 20ms\n",
+			want:      []MethodPolicy{{Service: "users", Hedging: &HedgingPolicy{HedgingDelay: 20 * time.Millisecond}}},
+		},
+		{
+			name:      "invalid duration",
+			unmarshal: json.Unmarshal,
+			data:      `{"methods": [{"service": "users", "retry": {"max_backoff": "soon"}}]}`,
+			wantErr:   true,
+		},
+		{
+			name:      "invalid type",
+			unmarshal: json.Unmarshal,
+			data:      `{"methods": [{"service": "users", "hedging": {"hedging_delay": true}}]}`,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg PoliciesConfig
+
+			err := tt.unmarshal([]byte(tt.data), &cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unmarshal error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(cfg.Methods, tt.want) {
+				t.Errorf("methods = %+v, want %+v", cfg.Methods, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// serviceConfig is the JSON representation of the gRPC service config
+// (https://github.com/grpc/grpc/blob/master/doc/service_config.md).
+type serviceConfig struct {
+	LoadBalancingConfig []map[string]any     `json:"loadBalancingConfig,omitempty"`
+	HealthCheckConfig   *healthCheckConfig   `json:"healthCheckConfig,omitempty"`
+	MethodConfig        []methodConfig       `json:"methodConfig,omitempty"`
+	RetryThrottling     *retryThrottlingJSON `json:"retryThrottling,omitempty"`
+}
+
+type healthCheckConfig struct {
+	ServiceName string `json:"serviceName"`
+}
+
+type methodConfig struct {
+	Name        []methodName     `json:"name"`
+	RetryPolicy *retryPolicyJSON `json:"retryPolicy,omitempty"`
+}
+
+type methodName struct {
+	Service string `json:"service"`
+	Method  string `json:"method,omitempty"`
+}
+
+type retryPolicyJSON struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+type retryThrottlingJSON struct {
+	MaxTokens  float64 `json:"maxTokens"`
+	TokenRatio float64 `json:"tokenRatio"`
+}
+
+// serviceConfig renders the options and retry policies. Hedging is not implemented by grpc-go
+// (hedgingPolicy is ignored), so hedging policies are applied by the hedging interceptor instead.
+func (o *options) serviceConfig(ps *policies) *serviceConfig {
+	sc := &serviceConfig{
+		LoadBalancingConfig: []map[string]any{
+			{o.loadBalancing: struct{}{}},
+		},
+	}
+
+	if o.healthCheck {
+		sc.HealthCheckConfig = &healthCheckConfig{ServiceName: o.healthCheckService}
+	}
+
+	if ps != nil {
+		names := make([]string, 0, len(ps.byName))
+		for name := range ps.byName {
+			names = append(names, name)
+		}
+
+		slices.Sort(names)
+
+		for _, name := range names {
+			p := ps.byName[name]
+			if p.retry == nil {
+				continue
+			}
+
+			codes := make([]string, len(p.retry.RetryableCodes))
+			for i, c := range p.retry.RetryableCodes {
+				codes[i] = strings.ToUpper(c)
+			}
+
+			sc.MethodConfig = append(sc.MethodConfig, methodConfig{
+				Name: []methodName{{Service: p.service, Method: p.method}},
+				RetryPolicy: &retryPolicyJSON{
+					MaxAttempts:          p.retry.MaxAttempts,
+					InitialBackoff:       durationJSON(p.retry.InitialBackoff),
+					MaxBackoff:           durationJSON(p.retry.MaxBackoff),
+					BackoffMultiplier:    p.retry.BackoffMultiplier,
+					RetryableStatusCodes: codes,
+				},
+			})
+		}
+	}
+
+	if o.retryBudget != nil {
+		sc.RetryThrottling = &retryThrottlingJSON{
+			MaxTokens:  o.retryBudget.MaxTokens,
+			TokenRatio: o.retryBudget.TokenRatio,
+		}
+	}
+
+	return sc
+}
+
+func (sc *serviceConfig) JSON() (string, error) {
+	b, err := json.Marshal(sc)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// durationJSON formats the duration as the JSON representation of google.protobuf.Duration.
+func durationJSON(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
@@ -0,0 +1,61 @@
+// Command binlog2json decodes gRPC binary logs written by package grpc/binlog to JSON, one entry per line.
+//
+//	binlog2json grpc.binlog grpc.binlog.20260102T150405.000000000 > calls.json
+//
+// Standard input is decoded when no files are given.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/moderntv/cadre/grpc/binlog"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [file ...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	err := run(flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(files []string) error {
+	if len(files) == 0 {
+		return binlog.WriteJSON(os.Stdout, os.Stdin)
+	}
+
+	for _, name := range files {
+		err := decode(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func decode(name string) (err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		err = fmt.Errorf("cannot open binary log: %w", err)
+		return
+	}
+
+	defer f.Close()
+
+	err = binlog.WriteJSON(os.Stdout, f)
+	if err != nil {
+		err = fmt.Errorf("cannot decode `%s`: %w", name, err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,371 @@
+// Package concurrency provides an adaptive concurrency limiter shedding excess gRPC calls and HTTP requests
+// before they queue up.
+//
+// The limit of requests in flight adapts to the observed latency using a gradient algorithm: while latency
+// stays close to the long-term average the limit grows, when requests start queueing and latency rises
+// the limit shrinks proportionally. Requests over the limit fail fast with codes.Unavailable or 503.
+//
+// Requests carry a priority (x-priority metadata, X-Priority header) - critical, high, normal (default) or low.
+// Lower priorities are shed earlier, so critical traffic is shed last. Streams are admitted by the same rules
+// but do not hold a slot for their whole lifetime and do not affect the limit.
+//
+// The limit, requests in flight and shed requests are exported as metrics and the limiter reports
+// a WARN status component while it is shedding.
+package concurrency
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moderntv/cadre/metrics"
+	"github.com/moderntv/cadre/status"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Priority of a request.
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	PriorityCritical
+)
+
+var priorityNames = map[Priority]string{
+	PriorityLow:      "low",
+	PriorityNormal:   "normal",
+	PriorityHigh:     "high",
+	PriorityCritical: "critical",
+}
+
+// share of the limit available to requests of the priority.
+var priorityShares = map[Priority]float64{
+	PriorityLow:      0.5,
+	PriorityNormal:   0.8,
+	PriorityHigh:     0.9,
+	PriorityCritical: 1,
+}
+
+func (p Priority) String() string {
+	return priorityNames[p]
+}
+
+// ParsePriority parses the priority name. Unknown or empty names are PriorityNormal.
+func ParsePriority(s string) Priority {
+	for p, name := range priorityNames {
+		if strings.EqualFold(s, name) {
+			return p
+		}
+	}
+
+	return PriorityNormal
+}
+
+const (
+	defaultComponentName = "concurrency_limiter"
+
+	// number of samples the long-term latency average spans.
+	longWindow = 600
+	// weight of the new limit in the smoothed limit.
+	smoothing = 0.2
+	// how long the limiter reports shedding after the last shed request.
+	sheddingCooldown = 5 * time.Second
+)
+
+type Limiter struct {
+	mu sync.Mutex
+
+	limit     float64
+	minLimit  float64
+	maxLimit  float64
+	tolerance float64
+	inflight  int
+	longRTT   float64 // seconds
+
+	componentName string
+	component     *status.ComponentStatus
+	statusMu      sync.Mutex // serializes reports of the shedding state
+	shedding      bool
+	lastShed      time.Time
+	cooldown      time.Duration
+
+	now func() time.Time
+
+	limitGauge    prometheus.Gauge
+	inflightGauge prometheus.Gauge
+	requests      *prometheus.CounterVec
+}
+
+type Option func(*Limiter) error
+
+// NewLimiter creates a new adaptive concurrency limiter exporting its state to the metrics registry
+// and to a component of the status.
+func NewLimiter(metricsRegistry *metrics.Registry, st *status.Status, opts ...Option) (l *Limiter, err error) {
+	l = &Limiter{
+		limit:         20,
+		minLimit:      1,
+		maxLimit:      1000,
+		tolerance:     1.5,
+		componentName: defaultComponentName,
+		cooldown:      sheddingCooldown,
+		now:           time.Now,
+	}
+
+	for _, opt := range opts {
+		err = opt(l)
+		if err != nil {
+			err = fmt.Errorf("cannot apply concurrency limiter option: %w", err)
+			return
+		}
+	}
+
+	l.component, err = st.RegisterOrGet(l.componentName)
+	if err != nil {
+		err = fmt.Errorf("cannot register concurrency limiter status: %w", err)
+		return
+	}
+
+	l.component.SetStatus(status.OK, "not shedding")
+
+	l.limitGauge, err = metricsRegistry.RegisterOrGetNewGauge(
+		"concurrency_limit",
+		prometheus.GaugeOpts{
+			Subsystem: "concurrency",
+			Name:      "limit",
+			Help:      "Current adaptive concurrency limit",
+		},
+	)
+	if err != nil {
+		err = fmt.Errorf("cannot register concurrency limiter metrics: %w", err)
+		return
+	}
+
+	l.inflightGauge, err = metricsRegistry.RegisterOrGetNewGauge(
+		"concurrency_inflight",
+		prometheus.GaugeOpts{
+			Subsystem: "concurrency",
+			Name:      "inflight",
+			Help:      "Requests in flight counted by the concurrency limiter",
+		},
+	)
+	if err != nil {
+		err = fmt.Errorf("cannot register concurrency limiter metrics: %w", err)
+		return
+	}
+
+	l.requests, err = metricsRegistry.RegisterOrGetNewCounterVec(
+		"concurrency_requests_total",
+		prometheus.CounterOpts{
+			Subsystem: "concurrency",
+			Name:      "requests_total",
+			Help:      "Requests checked by the concurrency limiter",
+		},
+		[]string{"transport", "priority", "result"},
+	)
+	if err != nil {
+		err = fmt.Errorf("cannot register concurrency limiter metrics: %w", err)
+		return
+	}
+
+	l.limitGauge.Set(l.limit)
+
+	return
+}
+
+// WithLimits configures the initial, minimal and maximal concurrency limit. Defaults to 20, 1 and 1000.
+func WithLimits(initial, minimum, maximum int) Option {
+	return func(l *Limiter) error {
+		if minimum < 1 || minimum > initial || initial > maximum {
+			return errors.New("concurrency limits have to satisfy 1 <= minimum <= initial <= maximum")
+		}
+
+		l.limit = float64(initial)
+		l.minLimit = float64(minimum)
+		l.maxLimit = float64(maximum)
+
+		return nil
+	}
+}
+
+// WithTolerance configures how much the latency may exceed its long-term average before the limit
+// starts shrinking. Defaults to 1.5.
+func WithTolerance(tolerance float64) Option {
+	return func(l *Limiter) error {
+		if tolerance < 1 {
+			return errors.New("tolerance has to be at least 1")
+		}
+
+		l.tolerance = tolerance
+
+		return nil
+	}
+}
+
+// WithComponentName configures the name of the status component. Defaults to concurrency_limiter.
+func WithComponentName(name string) Option {
+	return func(l *Limiter) error {
+		if name == "" {
+			return errors.New("component name cannot be empty")
+		}
+
+		l.componentName = name
+
+		return nil
+	}
+}
+
+// Limit returns the current concurrency limit.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int(l.limit)
+}
+
+// Acquire admits a request of the priority and takes a slot. The returned release function has to be called
+// when the request is done; dropped requests (e.g. timed out) do not contribute to the latency samples.
+func (l *Limiter) Acquire(transport string, priority Priority) (release func(dropped bool), ok bool) {
+	if !l.admit(transport, priority, true) {
+		return nil, false
+	}
+
+	start := l.now()
+
+	var once sync.Once
+
+	return func(dropped bool) {
+		once.Do(func() {
+			l.release(l.now().Sub(start), dropped)
+		})
+	}, true
+}
+
+// Admit admits a request of the priority without taking a slot.
+func (l *Limiter) Admit(transport string, priority Priority) bool {
+	return l.admit(transport, priority, false)
+}
+
+func (l *Limiter) admit(transport string, priority Priority, take bool) bool {
+	l.mu.Lock()
+
+	share, known := priorityShares[priority]
+	if !known {
+		share = priorityShares[PriorityNormal]
+	}
+
+	if float64(l.inflight) >= math.Max(1, math.Floor(l.limit*share)) {
+		started := l.shed()
+		l.requests.WithLabelValues(transport, priority.String(), "shed").Inc()
+		l.mu.Unlock()
+
+		if started {
+			l.reportShedding()
+		}
+
+		return false
+	}
+
+	if take {
+		l.inflight++
+		l.inflightGauge.Set(float64(l.inflight))
+	}
+
+	l.requests.WithLabelValues(transport, priority.String(), "admitted").Inc()
+	l.mu.Unlock()
+
+	return true
+}
+
+func (l *Limiter) release(rtt time.Duration, dropped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	inflight := l.inflight
+	l.inflight--
+	l.inflightGauge.Set(float64(l.inflight))
+
+	if dropped || rtt <= 0 {
+		return
+	}
+
+	l.update(rtt.Seconds(), inflight)
+}
+
+// update adapts the limit to the latency sample. Has to be called with the lock held.
+func (l *Limiter) update(rtt float64, inflight int) {
+	if l.longRTT == 0 {
+		l.longRTT = rtt
+	} else {
+		l.longRTT += (rtt - l.longRTT) * 2 / (longWindow + 1)
+	}
+
+	// recover faster from latency spikes that moved the long-term average
+	if l.longRTT/rtt > 2 {
+		l.longRTT *= 0.95
+	}
+
+	// the limit is not tested when the application does not use it
+	if float64(inflight) < l.limit/2 {
+		return
+	}
+
+	gradient := math.Max(0.5, math.Min(1, l.tolerance*l.longRTT/rtt))
+	newLimit := l.limit*gradient + math.Sqrt(l.limit)
+
+	l.limit = l.limit*(1-smoothing) + newLimit*smoothing
+	l.limit = math.Max(l.minLimit, math.Min(l.maxLimit, l.limit))
+	l.limitGauge.Set(l.limit)
+}
+
+// shed marks the limiter as shedding and reports whether it has just started. Has to be called with the lock held.
+func (l *Limiter) shed() (started bool) {
+	l.lastShed = l.now()
+
+	if l.shedding {
+		return false
+	}
+
+	l.shedding = true
+	time.AfterFunc(l.cooldown, l.checkShedding)
+
+	return true
+}
+
+func (l *Limiter) checkShedding() {
+	l.mu.Lock()
+
+	remaining := l.cooldown - l.now().Sub(l.lastShed)
+	if remaining > 0 {
+		time.AfterFunc(remaining, l.checkShedding)
+		l.mu.Unlock()
+
+		return
+	}
+
+	l.shedding = false
+	l.mu.Unlock()
+
+	l.reportShedding()
+}
+
+// reportShedding sets the status component according to the current shedding state. It is called without the lock
+// held, so that status watchers never run under it; the latest state is reported when transitions race.
+func (l *Limiter) reportShedding() {
+	l.statusMu.Lock()
+	defer l.statusMu.Unlock()
+
+	l.mu.Lock()
+	shedding, limit := l.shedding, int(l.limit)
+	l.mu.Unlock()
+
+	if shedding {
+		l.component.SetStatus(status.WARN, fmt.Sprintf("shedding load over concurrency limit %d", limit))
+	} else {
+		l.component.SetStatus(status.OK, "not shedding")
+	}
+}
@@ -0,0 +1,201 @@
+package concurrency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/metrics"
+	"github.com/moderntv/cadre/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func newTestLimiter(t *testing.T, opts ...Option) (*Limiter, *status.Status) {
+	t.Helper()
+
+	registry, err := metrics.NewRegistry("test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := status.NewStatus("test")
+
+	l, err := NewLimiter(registry, st, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return l, st
+}
+
+func TestLimiter_Acquire(t *testing.T) {
+	tests := []struct {
+		name     string
+		priority Priority
+		want     int
+	}{
+		{name: "low", priority: PriorityLow, want: 5},
+		{name: "normal", priority: PriorityNormal, want: 8},
+		{name: "high", priority: PriorityHigh, want: 9},
+		{name: "critical", priority: PriorityCritical, want: 10},
+		{name: "unknown", priority: Priority(42), want: 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, st := newTestLimiter(t, WithLimits(10, 1, 10))
+
+			var releases []func(bool)
+
+			for {
+				release, ok := l.Acquire(transportHTTP, tt.priority)
+				if !ok {
+					break
+				}
+
+				releases = append(releases, release)
+			}
+
+			if len(releases) != tt.want {
+				t.Errorf("acquired = %d, want %d", len(releases), tt.want)
+			}
+
+			if got := st.Report().Components[defaultComponentName].Status; got != status.WARN {
+				t.Errorf("status after rejection = %v, want %v", got, status.WARN)
+			}
+
+			for _, release := range releases {
+				release(true)
+			}
+
+			if _, ok := l.Acquire(transportHTTP, tt.priority); !ok {
+				t.Error("Acquire() after release rejected")
+			}
+		})
+	}
+}
+
+func TestLimiter_Adapts(t *testing.T) {
+	l, _ := newTestLimiter(t, WithLimits(20, 5, 100))
+
+	now := time.Unix(0, 0)
+	l.now = func() time.Time { return now }
+
+	// run batches of requests using the whole limit, each taking the latency
+	run := func(latency time.Duration) int {
+		for range 10 {
+			releases := []func(bool){}
+
+			for range l.Limit() {
+				release, ok := l.Acquire(transportGRPC, PriorityCritical)
+				if !ok {
+					t.Fatal("Acquire() within the limit rejected")
+				}
+
+				releases = append(releases, release)
+			}
+
+			now = now.Add(latency)
+
+			for _, release := range releases {
+				release(false)
+			}
+		}
+
+		return l.Limit()
+	}
+
+	grown := run(10 * time.Millisecond)
+	if grown <= 20 {
+		t.Errorf("limit with steady latency = %d, want more than 20", grown)
+	}
+
+	if shrunk := run(100 * time.Millisecond); shrunk >= grown {
+		t.Errorf("limit with growing latency = %d, want less than %d", shrunk, grown)
+	}
+}
+
+func TestLimiter_UnaryServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name     string
+		priority string
+		want     codes.Code
+	}{
+		{name: "default priority", want: codes.Unavailable},
+		{name: "critical", priority: "critical", want: codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, _ := newTestLimiter(t, WithLimits(2, 1, 2))
+
+			interceptor := l.UnaryServerInterceptor()
+			info := &grpc.UnaryServerInfo{FullMethod: "/example.Service/Method"}
+
+			ctx := t.Context()
+			if tt.priority != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(PriorityMetadataKey, tt.priority))
+			}
+
+			// normal priority of the outer call takes floor(2 * 0.8) = 1 slot
+			_, err := interceptor(t.Context(), nil, info, func(context.Context, any) (any, error) {
+				return interceptor(ctx, nil, info, func(context.Context, any) (any, error) {
+					return "ok", nil
+				})
+			})
+			if grpcstatus.Code(err) != tt.want {
+				t.Errorf("code = %v, want %v", grpcstatus.Code(err), tt.want)
+			}
+		})
+	}
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		priority       string
+		wantCode       int
+		wantRetryAfter string
+	}{
+		{name: "default priority", wantCode: http.StatusServiceUnavailable, wantRetryAfter: "1"},
+		{name: "critical", priority: "critical", wantCode: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, _ := newTestLimiter(t, WithLimits(2, 1, 2))
+
+			r := gin.New()
+			r.Use(l.Middleware())
+			r.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+			// normal priority of the outer request takes floor(2 * 0.8) = 1 slot
+			release, ok := l.Acquire(transportHTTP, PriorityNormal)
+			if !ok {
+				t.Fatal("Acquire() rejected")
+			}
+
+			defer release(true)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.priority != "" {
+				req.Header.Set(PriorityHeader, tt.priority)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode || w.Header().Get("Retry-After") != tt.wantRetryAfter {
+				t.Errorf("response = %d (Retry-After %q), want %d (Retry-After %q)",
+					w.Code, w.Header().Get("Retry-After"), tt.wantCode, tt.wantRetryAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package concurrency
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	transportGRPC = "grpc"
+
+	// PriorityMetadataKey is the gRPC metadata key carrying the request priority.
+	PriorityMetadataKey = "x-priority"
+)
+
+// UnaryServerInterceptor sheds unary calls over the limit with codes.Unavailable.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		_ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (resp any, err error) {
+		release, ok := l.Acquire(transportGRPC, grpcPriority(ctx))
+		if !ok {
+			return nil, status.Error(codes.Unavailable, "server overloaded")
+		}
+
+		defer func() {
+			release(status.Code(err) == codes.DeadlineExceeded || ctx.Err() != nil)
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor sheds streams over the limit with codes.Unavailable.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !l.Admit(transportGRPC, grpcPriority(ss.Context())) {
+			return status.Error(codes.Unavailable, "server overloaded")
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func grpcPriority(ctx context.Context) Priority {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return PriorityNormal
+	}
+
+	values := md.Get(PriorityMetadataKey)
+	if len(values) == 0 {
+		return PriorityNormal
+	}
+
+	return ParsePriority(values[0])
+}
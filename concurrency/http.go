@@ -0,0 +1,37 @@
+package concurrency
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/http/responses"
+)
+
+const (
+	transportHTTP = "http"
+
+	// PriorityHeader is the HTTP header carrying the request priority.
+	PriorityHeader = "X-Priority"
+)
+
+// Middleware returns gin middleware shedding requests over the limit with 503 Service Unavailable.
+func (l *Limiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		release, ok := l.Acquire(transportHTTP, ParsePriority(c.GetHeader(PriorityHeader)))
+		if !ok {
+			c.Header("Retry-After", "1")
+			responses.Unavailable(c, responses.Error{
+				Type:    "OVERLOADED",
+				Message: "Server is overloaded",
+			})
+
+			return
+		}
+
+		defer func() {
+			release(c.Writer.Status() == http.StatusRequestTimeout || c.Request.Context().Err() != nil)
+		}()
+
+		c.Next()
+	}
+}
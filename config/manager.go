@@ -1,16 +1,23 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"sync"
 
 	"github.com/moderntv/cadre/config/source"
 )
 
+// ReloadSourceName is the source name of changes published by Reload.
+const ReloadSourceName = "reload"
+
 type Manager struct {
 	sources []source.Source
 
+	watcherMu      sync.Mutex
 	watcher        *watcher
 	watchPublishCh chan source.ConfigChange
+	watchStopCh    chan struct{} // closed when the sources stop watching
 	watchSubCh     chan chan source.ConfigChange
 	watchUnsubCh   chan chan source.ConfigChange
 }
@@ -28,7 +35,8 @@ func NewManager(opts ...Option) (m *Manager, err error) {
 		sources: options.sources,
 
 		watchPublishCh: make(chan source.ConfigChange, 1),
-		watchSubCh:     make(chan chan source.ConfigChange, 1),
+		watchStopCh:    make(chan struct{}),
+		watchSubCh:     make(chan chan source.ConfigChange), // unbuffered - subscribed before Subscribe returns
 		watchUnsubCh:   make(chan chan source.ConfigChange, 1),
 	}
 
@@ -49,47 +57,129 @@ func (m *Manager) Load(dst any) (err error) {
 	return
 }
 
-// Subscribe returns a channel which will receive message on change.
+// Subscribe returns a channel which will receive message on change. The sources are watched once for all
+// subscribers; changes arriving while a subscriber is busy are coalesced into one pending message.
 func (m *Manager) Subscribe() (chan source.ConfigChange, error) {
-	if m.watcher == nil {
-		var err error
-
-		m.watcher, err = newWatcher(m.sources...)
-		if err != nil {
-			return nil, err
-		}
+	err := m.watch()
+	if err != nil {
+		return nil, err
 	}
 
-	msgCh := m.watcher.C()
+	msgCh := make(chan source.ConfigChange, 1)
 	m.watchSubCh <- msgCh
 
 	return msgCh, nil
 }
 
+// watch starts watching the sources and publishing their changes, unless already started.
+func (m *Manager) watch() (err error) {
+	m.watcherMu.Lock()
+	defer m.watcherMu.Unlock()
+
+	if m.watcher != nil {
+		return
+	}
+
+	m.watcher, err = newWatcher(m.sources...)
+	if err != nil {
+		m.watcher = nil
+		return
+	}
+
+	changes := m.watcher.C()
+
+	go func() {
+		for change := range changes {
+			m.watchPublishCh <- change
+		}
+
+		close(m.watchStopCh)
+	}()
+
+	return
+}
+
 // Unsubscribe removes a previously subscribed channel from change notifications.
 func (m *Manager) Unsubscribe(msgCh chan source.ConfigChange) {
 	m.watchUnsubCh <- msgCh
 }
 
+// Watch calls load and then calls it again whenever any of the manager's sources changes, until the context is done.
+// The error of the first load is returned; errors of reloads are passed to onError (if not nil), so that the caller
+// can keep its previous configuration.
+func Watch(ctx context.Context, m *Manager, load func() error, onError func(error)) (err error) {
+	err = load()
+	if err != nil {
+		return
+	}
+
+	changes, err := m.Subscribe()
+	if err != nil {
+		err = fmt.Errorf("cannot subscribe to config changes: %w", err)
+		return
+	}
+
+	go func() {
+		defer m.Unsubscribe(changes)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-changes:
+				if !ok {
+					return
+				}
+
+				err := load()
+				if err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+
+	return
+}
+
+// Reload notifies subscribers as if the sources changed, so that they load the configuration again.
+// Every subscriber receives a change after the call, coalesced with other changes pending for it.
+func (m *Manager) Reload() {
+	m.watchPublishCh <- source.ConfigChange{SourceName: ReloadSourceName}
+}
+
 func (m *Manager) manageSubscribers() {
 	subs := map[chan source.ConfigChange]struct{}{}
+	stopCh := m.watchStopCh
+	stopped := false
 
 	for {
 		select {
-		// case <-c.stopCh:
-		//     return
 		case msgCh := <-m.watchSubCh:
+			if stopped {
+				close(msgCh)
+				continue
+			}
+
 			subs[msgCh] = struct{}{}
 		case msgCh := <-m.watchUnsubCh:
 			delete(subs, msgCh)
 		case msg := <-m.watchPublishCh:
 			for msgCh := range subs {
-				// msgCh is buffered, use non-blocking send to protect the broker:
+				// msgCh is buffered, use non-blocking send to protect the broker - a pending message covers this one
 				select {
 				case msgCh <- msg:
 				default:
 				}
 			}
+		case <-stopCh:
+			for msgCh := range subs {
+				close(msgCh)
+			}
+
+			subs = map[chan source.ConfigChange]struct{}{}
+			stopped = true
+			stopCh = nil // never selected again
 		}
 	}
 }
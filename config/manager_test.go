@@ -0,0 +1,63 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moderntv/cadre/config"
+	"github.com/moderntv/cadre/config/encoder/yaml"
+	"github.com/moderntv/cadre/config/source/file"
+)
+
+func TestManager_Subscribe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	err := os.WriteFile(path, []byte("value: 0\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := file.NewSource(path, yaml.NewEncoder())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := config.NewManager(config.WithSource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscribers := make([]chan struct{}, 2)
+	for i := range subscribers {
+		changes, err := m.Subscribe()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		subscribers[i] = make(chan struct{}, 1)
+
+		go func() {
+			for range changes {
+				subscribers[i] <- struct{}{}
+			}
+		}()
+	}
+
+	// every write reaches every subscriber
+	for i := 1; i <= 4; i++ {
+		err = os.WriteFile(path, []byte("value: 1\n"), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for j, received := range subscribers {
+			select {
+			case <-received:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("write %d: subscriber %d did not receive the change", i, j)
+			}
+		}
+	}
+}
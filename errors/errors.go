@@ -8,6 +8,7 @@ type Type error
 
 var (
 	ErrInvalidInput         = Type(errors.New("invalid input"))
+	ErrUnauthenticated      = Type(errors.New("unauthenticated"))
 	ErrNotAllowed           = Type(errors.New("not allowed"))
 	ErrNotFound             = Type(errors.New("not found"))
 	ErrTemporaryUnavailable = Type(errors.New("temporary unavailable"))
@@ -0,0 +1,161 @@
+// Package binlog records gRPC calls of selected methods in the gRPC binary log format for debugging.
+//
+// The interceptors write headers, messages and trailers of the calls as grpc.binarylog.v1.GrpcLogEntry
+// entries to a Sink, typically a rotating FileSink. Each entry is prefixed by its big-endian uint32 length,
+// the same framing as used by grpc's binarylog package, so the files can be read by Reader or decoded
+// to JSON by cmd/binlog2json for offline analysis.
+//
+// Logging is opt-in: calls are recorded only for methods matching the configured patterns, which can
+// be replaced at runtime by SetMethods or by WatchConfig. Values of sensitive metadata (authorization,
+// cookies, admin tokens) are redacted; messages are recorded as they are, capped in size.
+package binlog
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// Redacted replaces values of redacted metadata.
+	Redacted = "[REDACTED]"
+
+	defaultMaxMessageSize = 64 * 1024
+)
+
+// metadata keys redacted by default.
+var defaultRedactedMetadata = []string{"authorization", "cookie", "set-cookie", "x-admin-token"}
+
+type Logger struct {
+	sink           Sink
+	maxMessageSize int
+	onError        func(error)
+
+	mu               sync.RWMutex
+	methods          []string
+	redactedMetadata map[string]bool
+
+	callID atomic.Uint64
+}
+
+type Option func(*Logger) error
+
+// New creates a new binary logger writing to the sink. It records no calls until methods are set.
+func New(sink Sink, opts ...Option) (l *Logger, err error) {
+	if sink == nil {
+		err = errors.New("sink cannot be nil")
+		return
+	}
+
+	l = &Logger{
+		sink:             sink,
+		maxMessageSize:   defaultMaxMessageSize,
+		redactedMetadata: map[string]bool{},
+	}
+
+	for _, key := range defaultRedactedMetadata {
+		l.redactedMetadata[key] = true
+	}
+
+	for _, opt := range opts {
+		err = opt(l)
+		if err != nil {
+			err = fmt.Errorf("cannot apply binary logger option: %w", err)
+			return
+		}
+	}
+
+	return
+}
+
+// WithMethods records calls of methods matching any of the path.Match patterns matched against
+// the full method (`/package.Service/Method`), e.g. `/example.GreeterService/*`.
+func WithMethods(patterns ...string) Option {
+	return func(l *Logger) error {
+		return l.SetMethods(patterns...)
+	}
+}
+
+// WithMaxMessageSize caps the size of a recorded message in bytes. Larger messages are truncated. Defaults to 64 KiB.
+func WithMaxMessageSize(size int) Option {
+	return func(l *Logger) error {
+		if size <= 0 {
+			return errors.New("max message size has to be positive")
+		}
+
+		l.maxMessageSize = size
+
+		return nil
+	}
+}
+
+// WithRedactedMetadata redacts also values of the metadata keys (case-insensitive).
+func WithRedactedMetadata(keys ...string) Option {
+	return func(l *Logger) error {
+		for _, key := range keys {
+			l.redactedMetadata[strings.ToLower(key)] = true
+		}
+
+		return nil
+	}
+}
+
+// WithErrorHandler passes errors of writing to the sink to the handler; they are dropped by default.
+func WithErrorHandler(onError func(error)) Option {
+	return func(l *Logger) error {
+		l.onError = onError
+
+		return nil
+	}
+}
+
+// SetMethods replaces the patterns of recorded methods at runtime. No patterns stop the recording.
+func (l *Logger) SetMethods(patterns ...string) error {
+	for _, pattern := range patterns {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return fmt.Errorf("invalid method pattern `%s`: %w", pattern, err)
+		}
+	}
+
+	l.mu.Lock()
+	l.methods = slices.Clone(patterns)
+	l.mu.Unlock()
+
+	return nil
+}
+
+// Methods returns the patterns of recorded methods.
+func (l *Logger) Methods() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return slices.Clone(l.methods)
+}
+
+// Close closes the sink.
+func (l *Logger) Close() error {
+	return l.sink.Close()
+}
+
+// enabled decides whether calls of the method are recorded.
+func (l *Logger) enabled(fullMethod string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, pattern := range l.methods {
+		if ok, _ := path.Match(pattern, fullMethod); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l *Logger) redacted(key string) bool {
+	return l.redactedMetadata[key]
+}
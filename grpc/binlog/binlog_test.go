@@ -0,0 +1,287 @@
+package binlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	binlogpb "google.golang.org/grpc/binarylog/grpc_binarylog_v1"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+type memorySink struct {
+	mu      sync.Mutex
+	entries []*binlogpb.GrpcLogEntry
+}
+
+func (s *memorySink) Write(e *binlogpb.GrpcLogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, e)
+
+	return nil
+}
+
+func (s *memorySink) Close() error { return nil }
+
+func (s *memorySink) types() []binlogpb.GrpcLogEntry_EventType {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var types []binlogpb.GrpcLogEntry_EventType
+	for _, e := range s.entries {
+		types = append(types, e.GetType())
+	}
+
+	return types
+}
+
+func newTestClient(t *testing.T, l *Logger) healthpb.HealthClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(l.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(l.StreamServerInterceptor()),
+	)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("users", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	go func() { _ = server.Serve(lis) }()
+
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestLogger_UnaryServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name      string
+		methods   []string
+		call      func(healthpb.HealthClient) error
+		wantTypes []binlogpb.GrpcLogEntry_EventType
+		wantCode  uint32
+	}{
+		{
+			name:    "selected",
+			methods: []string{"/grpc.health.v1.Health/Check"},
+			call: func(client healthpb.HealthClient) error {
+				_, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "users"})
+				return err
+			},
+			wantTypes: []binlogpb.GrpcLogEntry_EventType{
+				binlogpb.GrpcLogEntry_EVENT_TYPE_CLIENT_HEADER,
+				binlogpb.GrpcLogEntry_EVENT_TYPE_CLIENT_MESSAGE,
+				binlogpb.GrpcLogEntry_EVENT_TYPE_CLIENT_HALF_CLOSE,
+				binlogpb.GrpcLogEntry_EVENT_TYPE_SERVER_HEADER,
+				binlogpb.GrpcLogEntry_EVENT_TYPE_SERVER_MESSAGE,
+				binlogpb.GrpcLogEntry_EVENT_TYPE_SERVER_TRAILER,
+			},
+		},
+		{
+			name:    "error",
+			methods: []string{"/grpc.health.v1.Health/Check"},
+			call: func(client healthpb.HealthClient) error {
+				_, _ = client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "unknown"})
+				return nil
+			},
+			wantTypes: []binlogpb.GrpcLogEntry_EventType{
+				binlogpb.GrpcLogEntry_EVENT_TYPE_CLIENT_HEADER,
+				binlogpb.GrpcLogEntry_EVENT_TYPE_CLIENT_MESSAGE,
+				binlogpb.GrpcLogEntry_EVENT_TYPE_CLIENT_HALF_CLOSE,
+				binlogpb.GrpcLogEntry_EVENT_TYPE_SERVER_TRAILER,
+			},
+			wantCode: 5, // NotFound
+		},
+		{
+			name:    "not selected",
+			methods: []string{"/grpc.health.v1.Health/Check"},
+			call: func(client healthpb.HealthClient) error {
+				_, err := client.List(t.Context(), &healthpb.HealthListRequest{})
+				return err
+			},
+		},
+		{
+			name: "no methods",
+			call: func(client healthpb.HealthClient) error {
+				_, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "users"})
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &memorySink{}
+
+			l, err := New(sink, WithMethods(tt.methods...))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = tt.call(newTestClient(t, l))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := sink.types(); !reflect.DeepEqual(got, tt.wantTypes) {
+				t.Errorf("entries = %v, want %v", got, tt.wantTypes)
+			}
+
+			if len(sink.entries) == 0 {
+				return
+			}
+
+			if got := sink.entries[len(sink.entries)-1].GetTrailer().GetStatusCode(); got != tt.wantCode {
+				t.Errorf("status code = %d, want %d", got, tt.wantCode)
+			}
+
+			for i, e := range sink.entries {
+				if e.GetCallId() != sink.entries[0].GetCallId() || e.GetSequenceIdWithinCall() != uint64(i+1) {
+					t.Errorf("entry %d call id, sequence id = %d, %d, want %d, %d",
+						i, e.GetCallId(), e.GetSequenceIdWithinCall(), sink.entries[0].GetCallId(), i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestLogger_Entries(t *testing.T) {
+	sink := &memorySink{}
+
+	l, err := New(sink, WithMethods("/grpc.health.v1.Health/Check"), WithMaxMessageSize(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(t.Context(), "authorization", "Bearer secret", "x-client", "cli")
+
+	_, err = newTestClient(t, l).Check(ctx, &healthpb.HealthCheckRequest{Service: "users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := sink.entries[0].GetClientHeader()
+	if got := header.GetMethodName(); got != "/grpc.health.v1.Health/Check" {
+		t.Errorf("method = %q, want %q", got, "/grpc.health.v1.Health/Check")
+	}
+
+	values := map[string]string{}
+	for _, entry := range header.GetMetadata().GetEntry() {
+		values[entry.GetKey()] = string(entry.GetValue())
+	}
+
+	if values["authorization"] != Redacted || values["x-client"] != "cli" {
+		t.Errorf("metadata = %v, want redacted authorization", values)
+	}
+
+	request, err := proto.Marshal(&healthpb.HealthCheckRequest{Service: "users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := sink.entries[1]
+	if !message.GetPayloadTruncated() || message.GetMessage().GetLength() != uint32(len(request)) ||
+		!bytes.Equal(message.GetMessage().GetData(), request[:2]) {
+		t.Errorf("message = %v, want the first 2 bytes of %d", message, len(request))
+	}
+}
+
+func TestLogger_SetMethods(t *testing.T) {
+	tests := []struct {
+		methods []string
+		want    []string
+		wantErr bool
+	}{
+		{methods: []string{"/grpc.health.v1.Health/*"}, want: []string{"/grpc.health.v1.Health/*"}},
+		{methods: nil, want: nil},
+		{methods: []string{"[invalid"}, want: []string{"/users.v1.Users/Get"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.methods), func(t *testing.T) {
+			l, err := New(&memorySink{}, WithMethods("/users.v1.Users/Get"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = l.SetMethods(tt.methods...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SetMethods() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got := l.Methods(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Methods() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogger_StreamServerInterceptor(t *testing.T) {
+	sink := &memorySink{}
+
+	l, err := New(sink, WithMethods("/grpc.health.v1.Health/*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+
+	watch, err := newTestClient(t, l).Watch(ctx, &healthpb.HealthCheckRequest{Service: "users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = watch.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	want := []binlogpb.GrpcLogEntry_EventType{
+		binlogpb.GrpcLogEntry_EVENT_TYPE_CLIENT_HEADER,
+		binlogpb.GrpcLogEntry_EVENT_TYPE_CLIENT_MESSAGE,
+		binlogpb.GrpcLogEntry_EVENT_TYPE_SERVER_HEADER,
+		binlogpb.GrpcLogEntry_EVENT_TYPE_SERVER_MESSAGE,
+		binlogpb.GrpcLogEntry_EVENT_TYPE_CANCEL,
+	}
+
+	// the cancel is logged asynchronously
+	var got []binlogpb.GrpcLogEntry_EventType
+
+	for range 500 {
+		got = sink.types()
+		if reflect.DeepEqual(got, want) {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Errorf("entries = %v, want %v", got, want)
+}
@@ -0,0 +1,35 @@
+package binlog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/moderntv/cadre/config"
+)
+
+type Config struct {
+	// Methods are path.Match patterns of recorded methods (see WithMethods).
+	Methods []string `json:"methods" yaml:"methods"`
+}
+
+// WatchConfig applies the recorded methods from the configuration manager whenever it changes (see config.Watch).
+func (l *Logger) WatchConfig(ctx context.Context, m *config.Manager, onError func(error)) error {
+	return config.Watch(ctx, m, func() error { return l.loadConfig(m) }, onError)
+}
+
+func (l *Logger) loadConfig(m *config.Manager) (err error) {
+	cfg := &Config{}
+
+	err = m.Load(cfg)
+	if err != nil {
+		err = fmt.Errorf("cannot load binary log config: %w", err)
+		return
+	}
+
+	return l.Reload(cfg)
+}
+
+// Reload applies the configuration.
+func (l *Logger) Reload(cfg *Config) error {
+	return l.SetMethods(cfg.Methods...)
+}
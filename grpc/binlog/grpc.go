@@ -0,0 +1,383 @@
+package binlog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	binlogpb "google.golang.org/grpc/binarylog/grpc_binarylog_v1"
+	"google.golang.org/grpc/encoding"
+	encodingproto "google.golang.org/grpc/encoding/proto"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// UnaryServerInterceptor records unary calls of the selected methods.
+func (l *Logger) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !l.enabled(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		c := l.newCall(ctx, info.FullMethod)
+		c.message(binlogpb.GrpcLogEntry_EVENT_TYPE_CLIENT_MESSAGE, req)
+		c.log(binlogpb.GrpcLogEntry_EVENT_TYPE_CLIENT_HALF_CLOSE, nil, false)
+
+		// headers and trailers set by the handler
+		ts := &transportStream{ServerTransportStream: grpc.ServerTransportStreamFromContext(ctx), call: c}
+		if ts.ServerTransportStream != nil {
+			ctx = grpc.NewContextWithServerTransportStream(ctx, ts)
+		}
+
+		resp, err := handler(ctx, req)
+
+		// unary errors without explicitly sent headers are trailers-only responses
+		if err == nil {
+			ts.sendHeader()
+			c.message(binlogpb.GrpcLogEntry_EVENT_TYPE_SERVER_MESSAGE, resp)
+		}
+
+		c.end(ctx, err, ts.trailer())
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor records streams of the selected methods.
+func (l *Logger) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !l.enabled(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		c := l.newCall(ss.Context(), info.FullMethod)
+		s := &serverStream{ServerStream: ss, ts: &transportStream{call: c}}
+
+		err := handler(srv, s)
+		c.end(ss.Context(), err, s.ts.trailer())
+
+		return err
+	}
+}
+
+// call records the entries of one call.
+type call struct {
+	logger   *Logger
+	id       uint64
+	sequence atomic.Uint64
+}
+
+// newCall records the client header with the peer.
+func (l *Logger) newCall(ctx context.Context, fullMethod string) *call {
+	c := &call{logger: l, id: l.callID.Add(1)}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := &binlogpb.ClientHeader{
+		Metadata:   l.metadata(md),
+		MethodName: fullMethod,
+	}
+
+	if authority := md.Get(":authority"); len(authority) > 0 {
+		header.Authority = authority[0]
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		header.Timeout = durationpb.New(time.Until(deadline))
+	}
+
+	e := c.entry(binlogpb.GrpcLogEntry_EVENT_TYPE_CLIENT_HEADER)
+	e.Payload = &binlogpb.GrpcLogEntry_ClientHeader{ClientHeader: header}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		e.Peer = address(p.Addr)
+	}
+
+	l.write(e)
+
+	return c
+}
+
+func (c *call) entry(eventType binlogpb.GrpcLogEntry_EventType) *binlogpb.GrpcLogEntry {
+	return &binlogpb.GrpcLogEntry{
+		Timestamp:            timestamppb.Now(),
+		CallId:               c.id,
+		SequenceIdWithinCall: c.sequence.Add(1),
+		Type:                 eventType,
+		Logger:               binlogpb.GrpcLogEntry_LOGGER_SERVER,
+	}
+}
+
+func (c *call) log(eventType binlogpb.GrpcLogEntry_EventType, payload any, truncated bool) {
+	e := c.entry(eventType)
+	e.PayloadTruncated = truncated
+
+	switch payload := payload.(type) {
+	case *binlogpb.ServerHeader:
+		e.Payload = &binlogpb.GrpcLogEntry_ServerHeader{ServerHeader: payload}
+	case *binlogpb.Message:
+		e.Payload = &binlogpb.GrpcLogEntry_Message{Message: payload}
+	case *binlogpb.Trailer:
+		e.Payload = &binlogpb.GrpcLogEntry_Trailer{Trailer: payload}
+	}
+
+	c.logger.write(e)
+}
+
+// message records the serialized message capped in size.
+func (c *call) message(eventType binlogpb.GrpcLogEntry_EventType, m any) {
+	data, err := marshal(m)
+	if err != nil {
+		c.logger.error(err)
+		return
+	}
+
+	truncated := len(data) > c.logger.maxMessageSize
+	msg := &binlogpb.Message{Length: uint32(len(data))} //nolint: gosec
+
+	if truncated {
+		msg.Data = data[:c.logger.maxMessageSize]
+	} else {
+		msg.Data = data
+	}
+
+	c.log(eventType, msg, truncated)
+}
+
+// end records the status and trailer of the call, or its cancellation by the client.
+func (c *call) end(ctx context.Context, err error, trailer metadata.MD) {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		c.log(binlogpb.GrpcLogEntry_EVENT_TYPE_CANCEL, nil, false)
+		return
+	}
+
+	st := status.Convert(err)
+	t := &binlogpb.Trailer{
+		Metadata:      c.logger.metadata(trailer),
+		StatusCode:    uint32(st.Code()),
+		StatusMessage: st.Message(),
+	}
+
+	if len(st.Details()) > 0 {
+		details, err := proto.Marshal(st.Proto())
+		if err == nil {
+			t.StatusDetails = details
+		}
+	}
+
+	c.log(binlogpb.GrpcLogEntry_EVENT_TYPE_SERVER_TRAILER, t, false)
+}
+
+func (l *Logger) write(e *binlogpb.GrpcLogEntry) {
+	err := l.sink.Write(e)
+	if err != nil {
+		l.error(err)
+	}
+}
+
+func (l *Logger) error(err error) {
+	if l.onError != nil {
+		l.onError(err)
+	}
+}
+
+// metadata converts the metadata without reserved (`grpc-` prefixed or pseudo) headers, redacting sensitive values.
+func (l *Logger) metadata(md metadata.MD) *binlogpb.Metadata {
+	m := &binlogpb.Metadata{}
+
+	for key, values := range md {
+		if strings.HasPrefix(key, ":") || (strings.HasPrefix(key, "grpc-") && key != "grpc-trace-bin") {
+			continue
+		}
+
+		for _, value := range values {
+			if l.redacted(key) {
+				value = Redacted
+			}
+
+			m.Entry = append(m.Entry, &binlogpb.MetadataEntry{Key: key, Value: []byte(value)})
+		}
+	}
+
+	return m
+}
+
+func marshal(m any) ([]byte, error) {
+	if m, ok := m.(proto.Message); ok {
+		return proto.Marshal(m)
+	}
+
+	data, err := encoding.GetCodecV2(encodingproto.Name).Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	defer data.Free()
+
+	return data.Materialize(), nil
+}
+
+func address(addr net.Addr) *binlogpb.Address {
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		if addr.IP.To4() != nil {
+			return &binlogpb.Address{
+				Type:    binlogpb.Address_TYPE_IPV4,
+				Address: addr.IP.String(),
+				IpPort:  uint32(addr.Port), //nolint: gosec
+			}
+		}
+
+		return &binlogpb.Address{
+			Type:    binlogpb.Address_TYPE_IPV6,
+			Address: addr.IP.String(),
+			IpPort:  uint32(addr.Port), //nolint: gosec
+		}
+
+	case *net.UnixAddr:
+		return &binlogpb.Address{Type: binlogpb.Address_TYPE_UNIX, Address: addr.String()}
+
+	default:
+		return &binlogpb.Address{Type: binlogpb.Address_TYPE_UNKNOWN, Address: addr.String()}
+	}
+}
+
+// transportStream accumulates headers and trailers set by the handler and records the header once it is sent.
+type transportStream struct {
+	grpc.ServerTransportStream
+
+	call *call
+
+	mu         sync.Mutex
+	header     metadata.MD
+	headerSent bool
+	trailers   metadata.MD
+}
+
+func (ts *transportStream) SetHeader(md metadata.MD) error {
+	err := ts.ServerTransportStream.SetHeader(md)
+	if err == nil {
+		ts.setHeader(md)
+	}
+
+	return err
+}
+
+func (ts *transportStream) SendHeader(md metadata.MD) error {
+	err := ts.ServerTransportStream.SendHeader(md)
+	if err == nil {
+		ts.setHeader(md)
+		ts.sendHeader()
+	}
+
+	return err
+}
+
+func (ts *transportStream) SetTrailer(md metadata.MD) error {
+	err := ts.ServerTransportStream.SetTrailer(md)
+	if err == nil {
+		ts.setTrailer(md)
+	}
+
+	return err
+}
+
+func (ts *transportStream) setHeader(md metadata.MD) {
+	ts.mu.Lock()
+	ts.header = metadata.Join(ts.header, md)
+	ts.mu.Unlock()
+}
+
+func (ts *transportStream) setTrailer(md metadata.MD) {
+	ts.mu.Lock()
+	ts.trailers = metadata.Join(ts.trailers, md)
+	ts.mu.Unlock()
+}
+
+// sendHeader records the header unless it has been recorded already.
+func (ts *transportStream) sendHeader() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.headerSent {
+		return
+	}
+
+	ts.headerSent = true
+	ts.call.log(
+		binlogpb.GrpcLogEntry_EVENT_TYPE_SERVER_HEADER,
+		&binlogpb.ServerHeader{Metadata: ts.call.logger.metadata(ts.header)},
+		false,
+	)
+}
+
+func (ts *transportStream) trailer() metadata.MD {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.trailers
+}
+
+// serverStream records messages of the stream; headers are recorded through the embedded transportStream.
+type serverStream struct {
+	grpc.ServerStream
+
+	ts *transportStream
+}
+
+func (s *serverStream) SetHeader(md metadata.MD) error {
+	err := s.ServerStream.SetHeader(md)
+	if err == nil {
+		s.ts.setHeader(md)
+	}
+
+	return err
+}
+
+func (s *serverStream) SendHeader(md metadata.MD) error {
+	err := s.ServerStream.SendHeader(md)
+	if err == nil {
+		s.ts.setHeader(md)
+		s.ts.sendHeader()
+	}
+
+	return err
+}
+
+func (s *serverStream) SetTrailer(md metadata.MD) {
+	s.ServerStream.SetTrailer(md)
+	s.ts.setTrailer(md)
+}
+
+func (s *serverStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		// the header is sent with the first message
+		s.ts.sendHeader()
+		s.ts.call.message(binlogpb.GrpcLogEntry_EVENT_TYPE_SERVER_MESSAGE, m)
+	}
+
+	return err
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+
+	switch {
+	case err == nil:
+		s.ts.call.message(binlogpb.GrpcLogEntry_EVENT_TYPE_CLIENT_MESSAGE, m)
+	case errors.Is(err, io.EOF):
+		s.ts.call.log(binlogpb.GrpcLogEntry_EVENT_TYPE_CLIENT_HALF_CLOSE, nil, false)
+	}
+
+	return err
+}
@@ -0,0 +1,93 @@
+package binlog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	binlogpb "google.golang.org/grpc/binarylog/grpc_binarylog_v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxEntrySize guards against reading garbage as a huge entry.
+const maxEntrySize = 64 * 1024 * 1024
+
+// Reader reads length-prefixed entries written by FileSink (or grpc's binarylog sinks).
+type Reader struct {
+	r *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next entry or io.EOF at the end of the log. A truncated entry results in io.ErrUnexpectedEOF.
+func (r *Reader) Next() (e *binlogpb.GrpcLogEntry, err error) {
+	header := make([]byte, 4)
+
+	_, err = io.ReadFull(r.r, header)
+	if err != nil {
+		return
+	}
+
+	size := binary.BigEndian.Uint32(header)
+	if size > maxEntrySize {
+		err = fmt.Errorf("invalid binary log entry size %d", size)
+		return
+	}
+
+	b := make([]byte, size)
+
+	_, err = io.ReadFull(r.r, b)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return
+	}
+
+	e = &binlogpb.GrpcLogEntry{}
+
+	err = proto.Unmarshal(b, e)
+	if err != nil {
+		err = fmt.Errorf("cannot unmarshal binary log entry: %w", err)
+		return
+	}
+
+	return
+}
+
+// WriteJSON decodes the log from r and writes its entries to w as JSON, one entry per line.
+func WriteJSON(w io.Writer, r io.Reader) (err error) {
+	reader := NewReader(r)
+
+	for {
+		var e *binlogpb.GrpcLogEntry
+
+		e, err = reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return
+		}
+
+		var b []byte
+
+		b, err = protojson.Marshal(e)
+		if err != nil {
+			err = fmt.Errorf("cannot marshal binary log entry to json: %w", err)
+			return
+		}
+
+		_, err = w.Write(append(b, '\n'))
+		if err != nil {
+			return
+		}
+	}
+}
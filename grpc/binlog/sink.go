@@ -0,0 +1,257 @@
+package binlog
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	binlogpb "google.golang.org/grpc/binarylog/grpc_binarylog_v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Sink receives the log entries.
+type Sink interface {
+	Write(*binlogpb.GrpcLogEntry) error
+	Close() error
+}
+
+// backupTimeFormat suffixes rotated files, it sorts chronologically.
+const backupTimeFormat = "20060102T150405.000000000"
+
+// FileSink writes length-prefixed entries to a file. The file is rotated when it would exceed the size
+// limit or when it is older than the age limit; rotated files are kept next to it with a timestamp suffix
+// (e.g. `grpc.binlog.20260102T150405.000000000`) and removed once older than the age limit or beyond
+// the number of backups. A file which cannot be rotated is truncated instead.
+type FileSink struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	now func() time.Time
+}
+
+type FileSinkOption func(*FileSink) error
+
+// NewFileSink opens (or creates) the file at the path and appends entries to it.
+func NewFileSink(path string, opts ...FileSinkOption) (s *FileSink, err error) {
+	s = &FileSink{
+		path:       path,
+		maxSize:    100 * 1024 * 1024,
+		maxAge:     7 * 24 * time.Hour,
+		maxBackups: 5,
+		now:        time.Now,
+	}
+
+	for _, opt := range opts {
+		err = opt(s)
+		if err != nil {
+			err = fmt.Errorf("cannot apply file sink option: %w", err)
+			return
+		}
+	}
+
+	err = s.open(os.O_APPEND)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// WithMaxSize sets the size in bytes the file is rotated at - default 100 MiB.
+func WithMaxSize(size int64) FileSinkOption {
+	return func(s *FileSink) error {
+		if size <= 0 {
+			return errors.New("max size has to be positive")
+		}
+
+		s.maxSize = size
+
+		return nil
+	}
+}
+
+// WithMaxAge sets the age the file is rotated at and rotated files are removed at - default 7 days.
+// Zero disables the age limit.
+func WithMaxAge(age time.Duration) FileSinkOption {
+	return func(s *FileSink) error {
+		if age < 0 {
+			return errors.New("max age cannot be negative")
+		}
+
+		s.maxAge = age
+
+		return nil
+	}
+}
+
+// WithMaxBackups sets the number of kept rotated files - default 5. Zero keeps all of them.
+func WithMaxBackups(n int) FileSinkOption {
+	return func(s *FileSink) error {
+		if n < 0 {
+			return errors.New("max backups cannot be negative")
+		}
+
+		s.maxBackups = n
+
+		return nil
+	}
+}
+
+// open opens the file either appending to it (os.O_APPEND) or truncating it (os.O_TRUNC).
+func (s *FileSink) open(flag int) (err error) {
+	s.file, err = os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|flag, 0o600)
+	if err != nil {
+		err = fmt.Errorf("cannot open binary log file: %w", err)
+		return
+	}
+
+	info, err := s.file.Stat()
+	if err != nil {
+		err = fmt.Errorf("cannot stat binary log file: %w", err)
+		return
+	}
+
+	s.size = info.Size()
+	s.openedAt = s.now()
+
+	return
+}
+
+// Write appends the length-prefixed entry, rotating the file if needed.
+func (s *FileSink) Write(e *binlogpb.GrpcLogEntry) (err error) {
+	b, err := proto.Marshal(e)
+	if err != nil {
+		err = fmt.Errorf("cannot marshal binary log entry: %w", err)
+		return
+	}
+
+	entry := make([]byte, 4, 4+len(b))
+	binary.BigEndian.PutUint32(entry, uint32(len(b))) //nolint: gosec
+	entry = append(entry, b...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		err = os.ErrClosed
+		return
+	}
+
+	tooBig := s.size > 0 && s.size+int64(len(entry)) > s.maxSize
+	tooOld := s.maxAge > 0 && s.now().Sub(s.openedAt) > s.maxAge
+
+	var rotateErr error
+	if tooBig || tooOld {
+		rotateErr = s.rotate()
+		if s.file == nil {
+			return rotateErr
+		}
+	}
+
+	n, err := s.file.Write(entry)
+	s.size += int64(n)
+
+	if err != nil {
+		err = fmt.Errorf("cannot write binary log entry: %w", err)
+		return
+	}
+
+	// the entry is written also when the rotation failed
+	return rotateErr
+}
+
+func (s *FileSink) rotate() (err error) {
+	err = s.file.Close()
+	if err != nil {
+		err = fmt.Errorf("cannot close binary log file: %w", err)
+		return
+	}
+
+	s.file = nil
+
+	// the file is truncated when it cannot be renamed, so that the sink keeps working within the limits
+	// and the rotation is not retried by every write
+	renameErr := os.Rename(s.path, s.path+"."+s.now().Format(backupTimeFormat))
+	if renameErr != nil {
+		err = s.open(os.O_TRUNC)
+		if err != nil {
+			return
+		}
+
+		return fmt.Errorf("cannot rotate binary log file, truncated it: %w", renameErr)
+	}
+
+	err = s.open(os.O_APPEND)
+	if err != nil {
+		return
+	}
+
+	return s.removeBackups()
+}
+
+// removeBackups removes rotated files beyond the limits.
+func (s *FileSink) removeBackups() (err error) {
+	backups, err := filepath.Glob(s.path + ".*")
+	if err != nil {
+		return
+	}
+
+	// the newest first
+	slices.Sort(backups)
+	slices.Reverse(backups)
+
+	kept := 0
+
+	for _, backup := range backups {
+		_, err := time.Parse(backupTimeFormat, backup[len(s.path)+1:])
+		if err != nil {
+			continue // not a rotated file
+		}
+
+		remove := s.maxBackups > 0 && kept >= s.maxBackups
+
+		if s.maxAge > 0 && !remove {
+			info, err := os.Stat(backup)
+			remove = err == nil && s.now().Sub(info.ModTime()) > s.maxAge
+		}
+
+		if !remove {
+			kept++
+			continue
+		}
+
+		err = os.Remove(backup)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("cannot remove rotated binary log file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Close closes the file.
+func (s *FileSink) Close() (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return
+	}
+
+	err = s.file.Close()
+	s.file = nil
+
+	return
+}
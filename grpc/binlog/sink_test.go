@@ -0,0 +1,224 @@
+package binlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	binlogpb "google.golang.org/grpc/binarylog/grpc_binarylog_v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func mustMarshal(t *testing.T, e *binlogpb.GrpcLogEntry) []byte {
+	t.Helper()
+
+	b, err := proto.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func testEntry(callID uint64) *binlogpb.GrpcLogEntry {
+	return &binlogpb.GrpcLogEntry{
+		CallId: callID,
+		Type:   binlogpb.GrpcLogEntry_EVENT_TYPE_CLIENT_MESSAGE,
+		Logger: binlogpb.GrpcLogEntry_LOGGER_SERVER,
+		Payload: &binlogpb.GrpcLogEntry_Message{
+			Message: &binlogpb.Message{Length: 16, Data: bytes.Repeat([]byte{'x'}, 16)},
+		},
+	}
+}
+
+func readEntries(t *testing.T, path string) []uint64 {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	var ids []uint64
+
+	r := NewReader(f)
+
+	for {
+		e, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return ids
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids = append(ids, e.GetCallId())
+	}
+}
+
+// write writes entries with the call ids to the sink.
+func write(t *testing.T, s *FileSink, ids ...uint64) {
+	t.Helper()
+
+	for _, id := range ids {
+		err := s.Write(testEntry(id))
+		if err != nil {
+			t.Fatalf("Write(%d) error = %v", id, err)
+		}
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grpc.binlog")
+
+	// every file fits two entries
+	size := int64(len(mustMarshal(t, testEntry(1)))+4) * 2
+
+	s, err := NewFileSink(path, WithMaxSize(size), WithMaxBackups(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write(t, s, 1, 2, 3, 4, 5, 6, 7)
+
+	err = s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Write(testEntry(8))
+	if !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Write() after Close() error = %v, want %v", err, os.ErrClosed)
+	}
+
+	// appends to the existing file
+	s, err = NewFileSink(path, WithMaxSize(size))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write(t, s, 8)
+
+	err = s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := [][]uint64{}
+	for _, file := range append(backups, path) {
+		got = append(got, readEntries(t, file))
+	}
+
+	// the oldest backup is removed
+	if want := [][]uint64{{3, 4}, {5, 6}, {7, 8}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("backups and file entries = %v, want %v", got, want)
+	}
+}
+
+func TestFileSink_RenameFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grpc.binlog")
+	size := int64(len(mustMarshal(t, testEntry(1)))+4) * 2
+
+	s, err := NewFileSink(path, WithMaxSize(size))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Close()
+
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	// the rotated file cannot replace a non-empty directory
+	err = os.MkdirAll(filepath.Join(path+"."+now.Format(backupTimeFormat), "blocked"), 0o700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write(t, s, 1, 2)
+
+	err = s.Write(testEntry(3))
+	if err == nil {
+		t.Fatal("Write() with failed rotation succeeded")
+	}
+
+	// truncated, the entry is written and the rotation is not retried until the file is full again
+	write(t, s, 4)
+
+	if got, want := readEntries(t, path), []uint64{3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var log bytes.Buffer
+
+	for i := range 2 {
+		b := mustMarshal(t, testEntry(uint64(i+1)))
+		log.Write([]byte{0, 0, 0, byte(len(b))})
+		log.Write(b)
+	}
+
+	tests := []struct {
+		name    string
+		log     []byte
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "entries",
+			log:  log.Bytes(),
+			want: []string{"1 EVENT_TYPE_CLIENT_MESSAGE", "2 EVENT_TYPE_CLIENT_MESSAGE"},
+		},
+		{name: "truncated entry", log: log.Bytes()[:log.Len()-1], wantErr: io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			err := WriteJSON(&out, bytes.NewReader(tt.log))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WriteJSON() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			var got []string
+
+			d := json.NewDecoder(&out)
+			for d.More() {
+				var entry struct {
+					CallID string `json:"callId"`
+					Type   string `json:"type"`
+				}
+
+				err = d.Decode(&entry)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got = append(got, entry.CallID+" "+entry.Type)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WriteJSON() entries = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
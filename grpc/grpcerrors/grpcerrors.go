@@ -0,0 +1,123 @@
+// Package grpcerrors translates between cadre typed errors and gRPC statuses.
+//
+// Server interceptors convert errors returned by handlers - ErrInvalidInput, ErrUnauthenticated, ErrNotAllowed,
+// ErrNotFound, ErrTemporaryUnavailable and ErrInternalError - to statuses with matching codes and errdetails.ErrorInfo.
+// Client interceptors (or FromStatus) convert statuses back so HTTP handlers calling gRPC backends
+// can pass the errors straight to responses.FromError.
+package grpcerrors
+
+import (
+	"errors"
+
+	cerr "github.com/moderntv/cadre/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Domain of the errdetails.ErrorInfo attached to converted statuses.
+const Domain = "cadre"
+
+type mapping struct {
+	typ    cerr.Type
+	code   codes.Code
+	reason string
+}
+
+var mappings = []mapping{
+	{typ: cerr.ErrInvalidInput, code: codes.InvalidArgument, reason: "INVALID_INPUT"},
+	{typ: cerr.ErrUnauthenticated, code: codes.Unauthenticated, reason: "UNAUTHENTICATED"},
+	{typ: cerr.ErrNotAllowed, code: codes.PermissionDenied, reason: "NOT_ALLOWED"},
+	{typ: cerr.ErrNotFound, code: codes.NotFound, reason: "NOT_FOUND"},
+	{typ: cerr.ErrTemporaryUnavailable, code: codes.Unavailable, reason: "TEMPORARY_UNAVAILABLE"},
+	{typ: cerr.ErrInternalError, code: codes.Internal, reason: "INTERNAL_ERROR"},
+}
+
+// ToStatus converts the error to a gRPC status. Errors already carrying a status are kept,
+// context errors are mapped to codes.Canceled and codes.DeadlineExceeded, other untyped errors to codes.Unknown.
+func ToStatus(err error) *status.Status {
+	if err == nil {
+		return nil
+	}
+
+	if s, ok := status.FromError(err); ok {
+		return s
+	}
+
+	for _, m := range mappings {
+		if !errors.Is(err, m.typ) {
+			continue
+		}
+
+		s := status.New(m.code, err.Error())
+
+		sd, detailsErr := s.WithDetails(&errdetails.ErrorInfo{
+			Reason: m.reason,
+			Domain: Domain,
+		})
+		if detailsErr != nil {
+			return s
+		}
+
+		return sd
+	}
+
+	return status.FromContextError(err)
+}
+
+// Error is a typed error converted from a gRPC status. It matches the cadre error type with errors.Is
+// and still carries the original status for status.FromError and status.Code.
+type Error struct {
+	cerr.TypedError
+
+	status *status.Status
+}
+
+func (e Error) GRPCStatus() *status.Status {
+	return e.status
+}
+
+// FromStatus converts an error returned by a gRPC call to a cadre typed error. Errors without a status
+// and statuses with codes.OK are returned unchanged.
+func FromStatus(err error) error {
+	s, ok := status.FromError(err)
+	if !ok || s.Code() == codes.OK {
+		return err
+	}
+
+	return Error{
+		TypedError: cerr.NewTyped(typeOf(s), errors.New(s.Message())),
+		status:     s,
+	}
+}
+
+func typeOf(s *status.Status) cerr.Type {
+	// prefer the exact type of statuses converted by ToStatus
+	for _, d := range s.Details() {
+		info, ok := d.(*errdetails.ErrorInfo)
+		if !ok || info.GetDomain() != Domain {
+			continue
+		}
+
+		for _, m := range mappings {
+			if m.reason == info.GetReason() {
+				return m.typ
+			}
+		}
+	}
+
+	switch s.Code() {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange, codes.AlreadyExists:
+		return cerr.ErrInvalidInput
+	case codes.Unauthenticated:
+		return cerr.ErrUnauthenticated
+	case codes.PermissionDenied:
+		return cerr.ErrNotAllowed
+	case codes.NotFound:
+		return cerr.ErrNotFound
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded, codes.Canceled:
+		return cerr.ErrTemporaryUnavailable
+	default:
+		return cerr.ErrInternalError
+	}
+}
@@ -0,0 +1,181 @@
+package grpcerrors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	cerr "github.com/moderntv/cadre/errors"
+	"github.com/moderntv/cadre/http/responses"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		code       codes.Code
+		wantReason string
+	}{
+		{
+			name:       "invalid input",
+			err:        cerr.NewTyped(cerr.ErrInvalidInput, errors.New("bad id")),
+			code:       codes.InvalidArgument,
+			wantReason: "INVALID_INPUT",
+		},
+		{
+			name:       "unauthenticated",
+			err:        cerr.ErrUnauthenticated,
+			code:       codes.Unauthenticated,
+			wantReason: "UNAUTHENTICATED",
+		},
+		{name: "not allowed", err: cerr.ErrNotAllowed, code: codes.PermissionDenied, wantReason: "NOT_ALLOWED"},
+		{
+			name:       "not found",
+			err:        fmt.Errorf("user: %w", cerr.NewTyped(cerr.ErrNotFound, errors.New("no user"))),
+			code:       codes.NotFound,
+			wantReason: "NOT_FOUND",
+		},
+		{
+			name:       "unavailable",
+			err:        cerr.NewTyped(cerr.ErrTemporaryUnavailable, errors.New("db down")),
+			code:       codes.Unavailable,
+			wantReason: "TEMPORARY_UNAVAILABLE",
+		},
+		{
+			name:       "internal",
+			err:        cerr.NewTyped(cerr.ErrInternalError, errors.New("oops")),
+			code:       codes.Internal,
+			wantReason: "INTERNAL_ERROR",
+		},
+		{name: "status", err: status.Error(codes.AlreadyExists, "exists"), code: codes.AlreadyExists},
+		{name: "canceled", err: context.Canceled, code: codes.Canceled},
+		{name: "untyped", err: errors.New("untyped"), code: codes.Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := ToStatus(tt.err)
+			if s.Code() != tt.code || !strings.Contains(tt.err.Error(), s.Message()) {
+				t.Errorf("ToStatus() = %v %q, want %v", s.Code(), s.Message(), tt.code)
+			}
+
+			var reason string
+
+			for _, detail := range s.Details() {
+				if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetDomain() == Domain {
+					reason = info.GetReason()
+				}
+			}
+
+			if reason != tt.wantReason {
+				t.Errorf("ToStatus() reason = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+
+	if s := ToStatus(nil); s != nil {
+		t.Errorf("ToStatus(nil) = %v, want nil", s)
+	}
+}
+
+func TestFromStatus(t *testing.T) {
+	plain := errors.New("plain")
+
+	tests := []struct {
+		name    string
+		err     error
+		want    error
+		wantMsg string
+	}{
+		{
+			name:    "typed",
+			err:     ToStatus(cerr.NewTyped(cerr.ErrNotFound, errors.New("cause"))).Err(),
+			want:    cerr.ErrNotFound,
+			wantMsg: "cause",
+		},
+		{name: "unauthenticated", err: status.Error(codes.Unauthenticated, "no token"), want: cerr.ErrUnauthenticated},
+		{name: "permission denied", err: status.Error(codes.PermissionDenied, "denied"), want: cerr.ErrNotAllowed},
+		{name: "unmapped code", err: status.Error(codes.Unimplemented, "unknown"), want: cerr.ErrInternalError},
+		{name: "plain", err: plain, want: plain},
+		{name: "nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := FromStatus(tt.err)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("FromStatus() = %v, want %v", err, tt.want)
+			}
+
+			if tt.wantMsg != "" && err.Error() != tt.wantMsg {
+				t.Errorf("FromStatus() message = %q, want %q", err.Error(), tt.wantMsg)
+			}
+
+			if tt.err != nil && status.Code(err) != status.Code(tt.err) {
+				t.Errorf("FromStatus() code = %v, want %v", status.Code(err), status.Code(tt.err))
+			}
+		})
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	_, err := UnaryServerInterceptor()(
+		t.Context(),
+		nil,
+		&grpc.UnaryServerInfo{FullMethod: "/example.Service/Method"},
+		func(context.Context, any) (any, error) {
+			return nil, cerr.NewTyped(cerr.ErrNotFound, errors.New("no user"))
+		},
+	)
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("server code = %v, want %v", status.Code(err), codes.NotFound)
+	}
+
+	err = UnaryClientInterceptor()(
+		t.Context(),
+		"/example.Service/Method",
+		nil,
+		nil,
+		nil,
+		func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+			return err
+		},
+	)
+	if !errors.Is(err, cerr.ErrNotFound) {
+		t.Errorf("client error = %v, want %v", err, cerr.ErrNotFound)
+	}
+}
+
+func TestFromStatus_HTTPStatus(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+
+	tests := []struct {
+		code       codes.Code
+		wantStatus int
+	}{
+		{code: codes.InvalidArgument, wantStatus: http.StatusBadRequest},
+		{code: codes.Unauthenticated, wantStatus: http.StatusUnauthorized},
+		{code: codes.PermissionDenied, wantStatus: http.StatusForbidden},
+		{code: codes.NotFound, wantStatus: http.StatusNotFound},
+		{code: codes.Unavailable, wantStatus: http.StatusServiceUnavailable},
+		{code: codes.Unimplemented, wantStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			responses.FromError(c, FromStatus(status.Error(tt.code, "")))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
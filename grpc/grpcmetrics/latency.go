@@ -0,0 +1,96 @@
+// Package grpcmetrics provides gRPC server and client metrics complementing go-grpc-prometheus counters.
+package grpcmetrics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moderntv/cadre/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Latency observes handling time of gRPC calls in a histogram labeled like go-grpc-prometheus metrics
+// (grpc_type, grpc_service, grpc_method, grpc_code). Observations of traced calls carry trace ID exemplars.
+type Latency struct {
+	handlingSeconds *prometheus.HistogramVec
+}
+
+// NewLatency creates the latency histogram with the configured buckets and registers it to the metrics registry.
+func NewLatency(metricsRegistry *metrics.Registry, config metrics.HistogramConfig) (l *Latency, err error) {
+	opts := prometheus.HistogramOpts{
+		Subsystem: "grpc_server",
+		Name:      "handling_seconds",
+		Help:      "Response latency of gRPC calls handled by the server",
+	}
+	config.Apply(&opts)
+
+	l = &Latency{}
+
+	l.handlingSeconds, err = metricsRegistry.RegisterNewHistogramVec(
+		"grpc_server_handling_seconds",
+		opts,
+		[]string{"grpc_type", "grpc_service", "grpc_method", "grpc_code"},
+	)
+	if err != nil {
+		err = fmt.Errorf("cannot register grpc latency histogram: %w", err)
+		return
+	}
+
+	return
+}
+
+// UnaryServerInterceptor observes the latency of unary calls.
+func (l *Latency) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		l.observe(ctx, "unary", info.FullMethod, err, time.Since(start))
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor observes the duration of streams.
+func (l *Latency) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		l.observe(ss.Context(), streamType(info), info.FullMethod, err, time.Since(start))
+
+		return err
+	}
+}
+
+func (l *Latency) observe(ctx context.Context, grpcType, fullMethod string, err error, d time.Duration) {
+	service, method := splitMethod(fullMethod)
+
+	metrics.ObserveWithTraceExemplar(
+		ctx,
+		l.handlingSeconds.WithLabelValues(grpcType, service, method, status.Code(err).String()),
+		d.Seconds(),
+	)
+}
+
+func streamType(info *grpc.StreamServerInfo) string {
+	switch {
+	case info.IsClientStream && info.IsServerStream:
+		return "bidi_stream"
+	case info.IsClientStream:
+		return "client_stream"
+	default:
+		return "server_stream"
+	}
+}
+
+func splitMethod(fullMethod string) (service, method string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "unknown", "unknown"
+	}
+
+	return service, method
+}
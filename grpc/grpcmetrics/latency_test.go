@@ -0,0 +1,122 @@
+package grpcmetrics
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"github.com/moderntv/cadre/metrics"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func gatherHistogram(t *testing.T, registry *metrics.Registry) *dto.Metric {
+	t.Helper()
+
+	families, err := registry.GetPrometheusRegistry().Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, family := range families {
+		if family.GetName() == "test_grpc_server_handling_seconds" && len(family.GetMetric()) == 1 {
+			return family.GetMetric()[0]
+		}
+	}
+
+	t.Fatal("histogram not found")
+
+	return nil
+}
+
+func TestLatency_UnaryServerInterceptor(t *testing.T) {
+	traceID := trace.TraceID{1, 2, 3}
+	traced := trace.ContextWithSpanContext(t.Context(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		err          error
+		wantCode     string
+		wantExemplar string
+	}{
+		{name: "ok", ctx: t.Context(), wantCode: "OK"},
+		{
+			name:         "traced error",
+			ctx:          traced,
+			err:          status.Error(codes.NotFound, ""),
+			wantCode:     "NotFound",
+			wantExemplar: traceID.String(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, err := metrics.NewRegistry("test", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			histogram := metrics.HistogramConfig{Buckets: []float64{0.1, 1}, NativeBucketFactor: 1.1}
+
+			latency, err := NewLatency(registry, histogram)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, _ = latency.UnaryServerInterceptor()(
+				tt.ctx,
+				nil,
+				&grpc.UnaryServerInfo{FullMethod: "/example.Service/Method"},
+				func(context.Context, any) (any, error) { return nil, tt.err },
+			)
+
+			m := gatherHistogram(t, registry)
+
+			labels := map[string]string{}
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+
+			wantLabels := map[string]string{
+				"grpc_type":    "unary",
+				"grpc_service": "example.Service",
+				"grpc_method":  "Method",
+				"grpc_code":    tt.wantCode,
+			}
+			if !maps.Equal(labels, wantLabels) {
+				t.Errorf("labels = %v, want %v", labels, wantLabels)
+			}
+
+			h := m.GetHistogram()
+			if h.GetSampleCount() != 1 || len(h.GetBucket()) != 2 || h.GetSchema() != 3 {
+				t.Errorf("histogram = %d samples, %d buckets, schema %d, want 1 sample, 2 buckets, schema 3",
+					h.GetSampleCount(), len(h.GetBucket()), h.GetSchema())
+			}
+
+			var exemplars []*dto.Exemplar
+
+			for _, bucket := range h.GetBucket() {
+				exemplars = append(exemplars, bucket.GetExemplar())
+			}
+
+			var exemplar string
+
+			for _, e := range append(exemplars, h.GetExemplars()...) {
+				for _, label := range e.GetLabel() {
+					exemplar = label.GetValue()
+				}
+			}
+
+			if exemplar != tt.wantExemplar {
+				t.Errorf("exemplar trace id = %q, want %q", exemplar, tt.wantExemplar)
+			}
+		})
+	}
+}
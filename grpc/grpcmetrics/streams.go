@@ -0,0 +1,209 @@
+package grpcmetrics
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/moderntv/cadre/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+// default buckets of the stream duration in seconds - streams are often long-lived.
+var defaultStreamDurationBuckets = []float64{0.01, 0.1, 1, 10, 60, 300, 900, 1800, 3600}
+
+// Streams is a grpc stats.Handler exporting metrics of RPCs (every RPC is a stream, unary ones with a single
+// message each way) labeled by grpc_type, grpc_service and grpc_method: active streams, messages sent
+// and received, payload sizes and stream duration (labeled also by grpc_code). Server and client metrics
+// are prefixed with grpc_server and grpc_client respectively.
+type Streams struct {
+	active          *prometheus.GaugeVec
+	received        *prometheus.CounterVec
+	sent            *prometheus.CounterVec
+	receivedPayload *prometheus.HistogramVec
+	sentPayload     *prometheus.HistogramVec
+	duration        *prometheus.HistogramVec
+}
+
+// NewServerStreams creates the stats handler of a grpc server (grpc.StatsHandler) and registers its metrics
+// to the metrics registry. Zero duration config uses buckets from 10ms to 1h.
+func NewServerStreams(metricsRegistry *metrics.Registry, duration metrics.HistogramConfig) (*Streams, error) {
+	return newStreams(metricsRegistry, "grpc_server", duration)
+}
+
+// NewClientStreams creates the stats handler of grpc client connections (grpc.WithStatsHandler). Connections
+// reporting into the same registry share the metrics.
+func NewClientStreams(metricsRegistry *metrics.Registry, duration metrics.HistogramConfig) (*Streams, error) {
+	return newStreams(metricsRegistry, "grpc_client", duration)
+}
+
+func newStreams(
+	metricsRegistry *metrics.Registry,
+	subsystem string,
+	duration metrics.HistogramConfig,
+) (s *Streams, err error) {
+	labels := []string{"grpc_type", "grpc_service", "grpc_method"}
+	s = &Streams{}
+
+	s.active, err = metricsRegistry.RegisterOrGetNewGaugeVec(
+		subsystem+"_active_streams",
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "active_streams",
+			Help:      "Number of gRPC streams in progress",
+		},
+		labels,
+	)
+	if err != nil {
+		err = fmt.Errorf("cannot register grpc stream metrics: %w", err)
+		return
+	}
+
+	s.received, err = metricsRegistry.RegisterOrGetNewCounterVec(
+		subsystem+"_stream_messages_received_total",
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "stream_messages_received_total",
+			Help:      "Total number of gRPC stream messages received",
+		},
+		labels,
+	)
+	if err != nil {
+		err = fmt.Errorf("cannot register grpc stream metrics: %w", err)
+		return
+	}
+
+	s.sent, err = metricsRegistry.RegisterOrGetNewCounterVec(
+		subsystem+"_stream_messages_sent_total",
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "stream_messages_sent_total",
+			Help:      "Total number of gRPC stream messages sent",
+		},
+		labels,
+	)
+	if err != nil {
+		err = fmt.Errorf("cannot register grpc stream metrics: %w", err)
+		return
+	}
+
+	// 64B - 16MiB
+	payloadBuckets := prometheus.ExponentialBuckets(64, 4, 10)
+
+	s.receivedPayload, err = metricsRegistry.RegisterOrGetNewHistogramVec(
+		subsystem+"_stream_received_payload_bytes",
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "stream_received_payload_bytes",
+			Help:      "Size of received gRPC messages (uncompressed)",
+			Buckets:   payloadBuckets,
+		},
+		labels,
+	)
+	if err != nil {
+		err = fmt.Errorf("cannot register grpc stream metrics: %w", err)
+		return
+	}
+
+	s.sentPayload, err = metricsRegistry.RegisterOrGetNewHistogramVec(
+		subsystem+"_stream_sent_payload_bytes",
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "stream_sent_payload_bytes",
+			Help:      "Size of sent gRPC messages (uncompressed)",
+			Buckets:   payloadBuckets,
+		},
+		labels,
+	)
+	if err != nil {
+		err = fmt.Errorf("cannot register grpc stream metrics: %w", err)
+		return
+	}
+
+	if len(duration.Buckets) == 0 {
+		duration.Buckets = defaultStreamDurationBuckets
+	}
+
+	durationOpts := prometheus.HistogramOpts{
+		Subsystem: subsystem,
+		Name:      "stream_duration_seconds",
+		Help:      "Duration of gRPC streams",
+	}
+	duration.Apply(&durationOpts)
+
+	s.duration, err = metricsRegistry.RegisterOrGetNewHistogramVec(
+		subsystem+"_stream_duration_seconds",
+		durationOpts,
+		append(labels, "grpc_code"),
+	)
+	if err != nil {
+		err = fmt.Errorf("cannot register grpc stream metrics: %w", err)
+		return
+	}
+
+	return
+}
+
+type streamKey struct{}
+
+// streamLabels are set on stats.Begin, which precedes all other events of the stream.
+type streamLabels struct {
+	service string
+	method  string
+	typ     string
+}
+
+func (s *Streams) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	service, method := splitMethod(info.FullMethodName)
+
+	return context.WithValue(ctx, streamKey{}, &streamLabels{service: service, method: method})
+}
+
+func (s *Streams) HandleRPC(ctx context.Context, rs stats.RPCStats) {
+	l, ok := ctx.Value(streamKey{}).(*streamLabels)
+	if !ok {
+		return
+	}
+
+	switch rs := rs.(type) {
+	case *stats.Begin:
+		l.typ = rpcType(rs.IsClientStream, rs.IsServerStream)
+		s.active.WithLabelValues(l.typ, l.service, l.method).Inc()
+
+	case *stats.InPayload:
+		s.received.WithLabelValues(l.typ, l.service, l.method).Inc()
+		s.receivedPayload.WithLabelValues(l.typ, l.service, l.method).Observe(float64(rs.Length))
+
+	case *stats.OutPayload:
+		s.sent.WithLabelValues(l.typ, l.service, l.method).Inc()
+		s.sentPayload.WithLabelValues(l.typ, l.service, l.method).Observe(float64(rs.Length))
+
+	case *stats.End:
+		metrics.ObserveWithTraceExemplar(
+			ctx,
+			s.duration.WithLabelValues(l.typ, l.service, l.method, status.Code(rs.Error).String()),
+			rs.EndTime.Sub(rs.BeginTime).Seconds(),
+		)
+		s.active.WithLabelValues(l.typ, l.service, l.method).Dec()
+	}
+}
+
+func (s *Streams) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (s *Streams) HandleConn(context.Context, stats.ConnStats) {}
+
+func rpcType(clientStream, serverStream bool) string {
+	switch {
+	case clientStream && serverStream:
+		return "bidi_stream"
+	case clientStream:
+		return "client_stream"
+	case serverStream:
+		return "server_stream"
+	default:
+		return "unary"
+	}
+}
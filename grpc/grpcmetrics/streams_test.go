@@ -0,0 +1,191 @@
+package grpcmetrics
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/moderntv/cadre/metrics"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// gatherMetrics returns metrics of the family labeled by grpc_type and grpc_method.
+func gatherMetrics(t *testing.T, registry *metrics.Registry, name string) map[string]*dto.Metric {
+	t.Helper()
+
+	families, err := registry.GetPrometheusRegistry().Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ms := map[string]*dto.Metric{}
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		for _, m := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+
+			ms[labels["grpc_type"]+" "+labels["grpc_method"]] = m
+		}
+	}
+
+	return ms
+}
+
+// value returns the value of the gauge or counter, or the sample count of the histogram.
+func value(m *dto.Metric) float64 {
+	if h := m.GetHistogram(); h != nil {
+		return float64(h.GetSampleCount())
+	}
+
+	if c := m.GetCounter(); c != nil {
+		return c.GetValue()
+	}
+
+	return m.GetGauge().GetValue()
+}
+
+// waitFor waits until the metric has the value.
+func waitFor(t *testing.T, registry *metrics.Registry, name, key string, want float64) {
+	t.Helper()
+
+	var got float64
+
+	for range 500 {
+		got = value(gatherMetrics(t, registry, name)[key])
+		if got == want {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("%s{%s} = %v, want %v", name, key, got, want)
+}
+
+func TestStreams(t *testing.T) {
+	serverRegistry, err := metrics.NewRegistry("test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientRegistry, err := metrics.NewRegistry("test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverStreams, err := NewServerStreams(serverRegistry, metrics.HistogramConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientStreams, err := NewClientStreams(clientRegistry, metrics.HistogramConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.StatsHandler(serverStreams))
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	go func() { _ = server.Serve(lis) }()
+
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(clientStreams),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	client := healthpb.NewHealthClient(conn)
+
+	_, err = client.Check(t.Context(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+
+	watch, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the initial status and the update
+	_, err = watch.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	healthServer.SetServingStatus("users", healthpb.HealthCheckResponse_SERVING)
+
+	_, err = watch.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the watch is in progress
+	waitFor(t, serverRegistry, "test_grpc_server_active_streams", "server_stream Watch", 1)
+	waitFor(t, clientRegistry, "test_grpc_client_active_streams", "server_stream Watch", 1)
+
+	cancel()
+
+	waitFor(t, serverRegistry, "test_grpc_server_active_streams", "server_stream Watch", 0)
+
+	const watchKey = "server_stream Watch"
+
+	tests := []struct {
+		registry *metrics.Registry
+		name     string
+		key      string
+		want     float64
+	}{
+		{registry: serverRegistry, name: "server_stream_messages_sent_total", key: watchKey, want: 2},
+		{registry: serverRegistry, name: "server_stream_messages_sent_total", key: "unary Check", want: 1},
+		{registry: serverRegistry, name: "server_stream_received_payload_bytes", key: watchKey, want: 1},
+		{registry: serverRegistry, name: "server_stream_duration_seconds", key: watchKey, want: 1},
+		{registry: serverRegistry, name: "server_stream_duration_seconds", key: "unary Check", want: 1},
+		{registry: clientRegistry, name: "client_stream_messages_received_total", key: watchKey, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.key, func(t *testing.T) {
+			if got := value(gatherMetrics(t, tt.registry, "test_grpc_"+tt.name)[tt.key]); got != tt.want {
+				t.Errorf("%s{%s} = %v, want %v", tt.name, tt.key, got, tt.want)
+			}
+		})
+	}
+
+	// the payload size of the request
+	payload := gatherMetrics(t, serverRegistry, "test_grpc_server_stream_received_payload_bytes")[watchKey]
+	if got, want := payload.GetHistogram().GetSampleSum(), float64(len("users")+2); got != want {
+		t.Errorf("received payload bytes = %v, want %v", got, want)
+	}
+
+	// client metrics are shared by connections reporting into the same registry
+	_, err = NewClientStreams(clientRegistry, metrics.HistogramConfig{})
+	if err != nil {
+		t.Errorf("NewClientStreams() with the same registry error = %v", err)
+	}
+}
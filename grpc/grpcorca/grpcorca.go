@@ -0,0 +1,281 @@
+// Package grpcorca reports backend load metrics of gRPC servers to clients via ORCA (Open Request Cost Aggregation).
+//
+// Reporter periodically samples the CPU utilization of the process, the rate of handled calls and errors and
+// application-defined utilizations read from gauges in the metrics.Registry. The metrics are reported per request
+// in trailers (see ServerOptions) and out of band by the ORCA service (see Register). Clients balancing calls with
+// the weighted round robin policy (package lb/wrr) use the reports to send load proportional to capacity.
+package grpcorca
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/moderntv/cadre/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/orca"
+	"google.golang.org/grpc/status"
+)
+
+// cpuSecondsMetric is exported by the process collector of metrics.Registry.
+const cpuSecondsMetric = "process_cpu_seconds_total"
+
+// errorCodes are status codes counted as errors (EPS); other codes are outcomes of the particular call.
+var errorCodes = map[codes.Code]bool{
+	codes.Unknown:           true,
+	codes.DeadlineExceeded:  true,
+	codes.ResourceExhausted: true,
+	codes.Internal:          true,
+	codes.Unavailable:       true,
+	codes.DataLoss:          true,
+}
+
+type Reporter struct {
+	registry *metrics.Registry
+	recorder orca.ServerMetricsRecorder
+
+	interval             time.Duration
+	minReportingInterval time.Duration
+	appUtilization       string            // gauge name
+	utilizations         map[string]string // utilization name -> gauge name
+
+	requests atomic.Uint64
+	errors   atomic.Uint64
+
+	// previous sample
+	sampledAt  time.Time
+	cpuSeconds float64
+	requested  uint64
+	errored    uint64
+}
+
+type Option func(*Reporter) error
+
+// New creates a reporter sampling the metrics of the registry. Run has to be called to keep the metrics up to date.
+func New(registry *metrics.Registry, opts ...Option) (r *Reporter, err error) {
+	if registry == nil {
+		err = errors.New("metrics registry cannot be nil")
+		return
+	}
+
+	r = &Reporter{
+		registry:     registry,
+		recorder:     orca.NewServerMetricsRecorder(),
+		interval:     5 * time.Second,
+		utilizations: map[string]string{},
+	}
+
+	for _, opt := range opts {
+		err = opt(r)
+		if err != nil {
+			err = fmt.Errorf("cannot apply orca reporter option: %w", err)
+			return
+		}
+	}
+
+	r.sample(time.Now())
+
+	return
+}
+
+// WithInterval sets how often the metrics are sampled - default 5s.
+func WithInterval(interval time.Duration) Option {
+	return func(r *Reporter) error {
+		if interval <= 0 {
+			return errors.New("interval has to be positive")
+		}
+
+		r.interval = interval
+
+		return nil
+	}
+}
+
+// WithMinReportingInterval sets the minimal interval of out-of-band reports requested by clients.
+// Intervals shorter than the grpc default (30s) are ignored.
+func WithMinReportingInterval(interval time.Duration) Option {
+	return func(r *Reporter) error {
+		r.minReportingInterval = interval
+
+		return nil
+	}
+}
+
+// WithApplicationUtilization reports the gauge (its name as exposed, including the namespace) as the application
+// utilization, which takes precedence over the CPU utilization in the weighted round robin policy. Values of
+// labeled gauges are summed.
+func WithApplicationUtilization(gauge string) Option {
+	return func(r *Reporter) error {
+		if gauge == "" {
+			return errors.New("gauge name cannot be empty")
+		}
+
+		r.appUtilization = gauge
+
+		return nil
+	}
+}
+
+// WithUtilization reports the gauge (its name as exposed, including the namespace) as the named utilization.
+// Values of labeled gauges are summed.
+func WithUtilization(name, gauge string) Option {
+	return func(r *Reporter) error {
+		if name == "" || gauge == "" {
+			return errors.New("utilization and gauge name cannot be empty")
+		}
+
+		r.utilizations[name] = gauge
+
+		return nil
+	}
+}
+
+// ServerMetrics returns the last sampled metrics; it implements orca.ServerMetricsProvider.
+func (r *Reporter) ServerMetrics() *orca.ServerMetrics {
+	return r.recorder.ServerMetrics()
+}
+
+// Run samples the metrics until the context is done.
+func (r *Reporter) Run(ctx context.Context) {
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case now := <-t.C:
+			r.sample(now)
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sample updates the metrics; rates are computed since the previous sample.
+func (r *Reporter) sample(now time.Time) {
+	gauges, cpuSeconds, err := r.gather()
+	if err != nil {
+		return
+	}
+
+	requested, errored := r.requests.Load(), r.errors.Load()
+
+	if !r.sampledAt.IsZero() {
+		elapsed := now.Sub(r.sampledAt).Seconds()
+		if elapsed > 0 {
+			r.recorder.SetQPS(float64(requested-r.requested) / elapsed)
+			r.recorder.SetEPS(float64(errored-r.errored) / elapsed)
+
+			cpu, ok := cpuSeconds[cpuSecondsMetric]
+			if ok {
+				r.recorder.SetCPUUtilization((cpu - r.cpuSeconds) / elapsed / float64(runtime.GOMAXPROCS(0)))
+			}
+		}
+	}
+
+	r.sampledAt = now
+	r.cpuSeconds = cpuSeconds[cpuSecondsMetric]
+	r.requested, r.errored = requested, errored
+
+	if r.appUtilization != "" {
+		value, ok := gauges[r.appUtilization]
+		if ok {
+			r.recorder.SetApplicationUtilization(value)
+		} else {
+			r.recorder.DeleteApplicationUtilization()
+		}
+	}
+
+	for name, gauge := range r.utilizations {
+		value, ok := gauges[gauge]
+		if ok {
+			r.recorder.SetNamedUtilization(name, value)
+		} else {
+			r.recorder.DeleteNamedUtilization(name)
+		}
+	}
+}
+
+// gather returns the summed values of the configured gauges and of the process CPU counter.
+func (r *Reporter) gather() (gauges, counters map[string]float64, err error) {
+	families, err := r.registry.GetPrometheusRegistry().Gather()
+	if err != nil {
+		return
+	}
+
+	gauges = map[string]float64{}
+	counters = map[string]float64{}
+
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			switch {
+			case m.GetGauge() != nil:
+				gauges[family.GetName()] += m.GetGauge().GetValue()
+
+			case m.GetCounter() != nil && family.GetName() == cpuSecondsMetric:
+				counters[family.GetName()] += m.GetCounter().GetValue()
+			}
+		}
+	}
+
+	return
+}
+
+// ServerOptions enables per-request reports of the metrics in trailers of every call and counts the calls.
+// The options have to be passed to grpc.NewServer after the server chain interceptors.
+func (r *Reporter) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		orca.CallMetricsServerOption(r),
+		grpc.ChainUnaryInterceptor(r.unaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(r.streamServerInterceptor()),
+	}
+}
+
+// the interceptors run inside the orca interceptors, requesting the per-call recorder makes them send the report.
+func (r *Reporter) unaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		orca.CallMetricsRecorderFromContext(ctx)
+
+		resp, err := handler(ctx, req)
+		r.count(err)
+
+		return resp, err
+	}
+}
+
+func (r *Reporter) streamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		orca.CallMetricsRecorderFromContext(ss.Context())
+
+		err := handler(srv, ss)
+		r.count(err)
+
+		return err
+	}
+}
+
+func (r *Reporter) count(err error) {
+	r.requests.Add(1)
+
+	if errorCodes[status.Code(err)] {
+		r.errors.Add(1)
+	}
+}
+
+// Register registers the ORCA service reporting the metrics out of band to the registrar (e.g. grpc server).
+func (r *Reporter) Register(registrar grpc.ServiceRegistrar) (err error) {
+	err = orca.Register(registrar, orca.ServiceOptions{
+		ServerMetricsProvider: r,
+		MinReportingInterval:  r.minReportingInterval,
+	})
+	if err != nil {
+		err = fmt.Errorf("cannot register orca service: %w", err)
+		return
+	}
+
+	return
+}
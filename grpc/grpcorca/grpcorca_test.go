@@ -0,0 +1,148 @@
+package grpcorca
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/moderntv/cadre/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestReporter(t *testing.T) (*Reporter, *prometheus.GaugeVec) {
+	t.Helper()
+
+	registry, err := metrics.NewRegistry("test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	busy, err := registry.RegisterNewGaugeVec(
+		"workers_busy",
+		prometheus.GaugeOpts{Name: "workers_busy"},
+		[]string{"pool"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New(registry,
+		WithApplicationUtilization("test_workers_busy"),
+		WithUtilization("workers", "test_workers_busy"),
+		WithUtilization("missing", "test_missing"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r, busy
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(nil)
+	if err == nil {
+		t.Error("New() without registry succeeded")
+	}
+}
+
+func TestReporter_Sample(t *testing.T) {
+	r, busy := newTestReporter(t)
+
+	busy.WithLabelValues("a").Set(0.25)
+	busy.WithLabelValues("b").Set(0.5)
+
+	for range 20 {
+		r.count(nil)
+	}
+
+	r.count(status.Error(codes.Unavailable, "overloaded"))
+	r.count(status.Error(codes.NotFound, "not found"))
+
+	r.sample(r.sampledAt.Add(2 * time.Second))
+
+	sm := r.ServerMetrics()
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{name: "qps", got: sm.QPS, want: 11},
+		{name: "eps", got: sm.EPS, want: 0.5},
+		{name: "application utilization", got: sm.AppUtilization, want: 0.75},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if want := map[string]float64{"workers": 0.75}; !reflect.DeepEqual(sm.Utilization, want) {
+		t.Errorf("utilization = %v, want %v", sm.Utilization, want)
+	}
+
+	if sm.CPUUtilization < 0 {
+		t.Errorf("cpu utilization = %v, want >= 0", sm.CPUUtilization)
+	}
+}
+
+func TestReporter_ServerOptions(t *testing.T) {
+	r, busy := newTestReporter(t)
+	busy.WithLabelValues("a").Set(0.5)
+	r.sample(time.Now())
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(r.ServerOptions()...)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+
+	err := r.Register(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() { _ = server.Serve(lis) }()
+
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	var trailer metadata.MD
+
+	_, err = healthpb.NewHealthClient(conn).Check(t.Context(), &healthpb.HealthCheckRequest{}, grpc.Trailer(&trailer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(trailer.Get("endpoint-load-metrics-bin")) == 0 {
+		t.Errorf("trailer = %v, want the load report", trailer)
+	}
+
+	if got := r.requests.Load(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+
+	if _, ok := server.GetServiceInfo()["xds.service.orca.v3.OpenRcaService"]; !ok {
+		t.Error("orca service not registered")
+	}
+}
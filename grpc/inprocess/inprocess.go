@@ -0,0 +1,323 @@
+// Package inprocess provides a gRPC channel calling services registered in the same process directly.
+//
+// Calls pass through the configured client and server interceptor chains, but there is no network and no
+// transport: requests and responses are copied instead of serialized, outgoing metadata becomes incoming
+// metadata of the handler and headers and trailers set by the handler are returned via grpc.Header and
+// grpc.Trailer call options. Services have to be registered through Registrar to be callable.
+package inprocess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	grpc_middleware "github.com/rkollar/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+type service struct {
+	impl    any
+	methods map[string]*grpc.MethodDesc
+	streams map[string]*grpc.StreamDesc
+}
+
+// Channel is a grpc.ClientConnInterface calling the registered services in-process.
+type Channel struct {
+	mu       sync.RWMutex
+	services map[string]*service
+
+	unaryServer  grpc.UnaryServerInterceptor
+	streamServer grpc.StreamServerInterceptor
+	unaryClient  grpc.UnaryClientInterceptor
+	streamClient grpc.StreamClientInterceptor
+}
+
+type Option func(*Channel) error
+
+// New creates a new channel without any services.
+func New(opts ...Option) (ch *Channel, err error) {
+	ch = &Channel{
+		services: map[string]*service{},
+	}
+
+	for _, opt := range opts {
+		err = opt(ch)
+		if err != nil {
+			err = fmt.Errorf("cannot apply in-process channel option: %w", err)
+			return
+		}
+	}
+
+	return
+}
+
+// WithServerInterceptors sets the server interceptor chains the calls pass through before reaching the handlers.
+// They should be the same as the chains of the grpc server serving the services.
+func WithServerInterceptors(unary []grpc.UnaryServerInterceptor, stream []grpc.StreamServerInterceptor) Option {
+	return func(ch *Channel) error {
+		ch.unaryServer = grpc_middleware.ChainUnaryServer(unary...)
+		ch.streamServer = grpc_middleware.ChainStreamServer(stream...)
+
+		return nil
+	}
+}
+
+// WithClientInterceptors sets the client interceptor chains the calls pass through first. Interceptors get
+// nil *grpc.ClientConn.
+func WithClientInterceptors(unary []grpc.UnaryClientInterceptor, stream []grpc.StreamClientInterceptor) Option {
+	return func(ch *Channel) error {
+		ch.unaryClient = grpc_middleware.ChainUnaryClient(unary...)
+		ch.streamClient = grpc_middleware.ChainStreamClient(stream...)
+
+		return nil
+	}
+}
+
+// RegisterService makes the service callable through the channel.
+func (ch *Channel) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	s := &service{
+		impl:    impl,
+		methods: map[string]*grpc.MethodDesc{},
+		streams: map[string]*grpc.StreamDesc{},
+	}
+
+	for i := range desc.Methods {
+		s.methods[desc.Methods[i].MethodName] = &desc.Methods[i]
+	}
+
+	for i := range desc.Streams {
+		s.streams[desc.Streams[i].StreamName] = &desc.Streams[i]
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.services[desc.ServiceName] = s
+}
+
+type registrar struct {
+	ch   *Channel
+	next grpc.ServiceRegistrar
+}
+
+func (r *registrar) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	r.next.RegisterService(desc, impl)
+	r.ch.RegisterService(desc, impl)
+}
+
+// Registrar returns a registrar registering services both to the next registrar (usually the grpc server)
+// and to the channel.
+func (ch *Channel) Registrar(next grpc.ServiceRegistrar) grpc.ServiceRegistrar {
+	return &registrar{ch: ch, next: next}
+}
+
+// Invoke calls the unary method and copies the response into reply.
+func (ch *Channel) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	if ch.unaryClient != nil {
+		return ch.unaryClient(ctx, method, args, reply, nil, ch.invoke, opts...)
+	}
+
+	return ch.invoke(ctx, method, args, reply, nil, opts...)
+}
+
+func (ch *Channel) invoke(
+	ctx context.Context,
+	method string,
+	args, reply any,
+	_ *grpc.ClientConn,
+	opts ...grpc.CallOption,
+) error {
+	s, name, err := ch.lookup(method)
+	if err != nil {
+		return err
+	}
+
+	desc, ok := s.methods[name]
+	if !ok {
+		return unknownMethod(method)
+	}
+
+	ts := newTransportStream(method)
+
+	sctx, cancel := serverContext(ctx, ts)
+	defer cancel()
+
+	dec := func(in any) error {
+		return copyMessage(in, args)
+	}
+
+	resp, err := desc.Handler(s.impl, sctx, dec, ch.unaryServer)
+
+	ts.deliver(opts)
+
+	if err != nil {
+		return toStatusError(err)
+	}
+
+	return copyMessage(reply, resp)
+}
+
+// NewStream starts the stream handler in a new goroutine. The stream has to be finished by reading it until
+// an error or by canceling the context.
+func (ch *Channel) NewStream(
+	ctx context.Context,
+	desc *grpc.StreamDesc,
+	method string,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	if ch.streamClient != nil {
+		return ch.streamClient(ctx, desc, nil, method, ch.newStream, opts...)
+	}
+
+	return ch.newStream(ctx, desc, nil, method, opts...)
+}
+
+func (ch *Channel) newStream(
+	ctx context.Context,
+	_ *grpc.StreamDesc,
+	_ *grpc.ClientConn,
+	method string,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	s, name, err := ch.lookup(method)
+	if err != nil {
+		return nil, err
+	}
+
+	desc, ok := s.streams[name]
+	if !ok {
+		return nil, unknownMethod(method)
+	}
+
+	st := newStream(ctx, method, opts)
+
+	go func() {
+		ss := &serverStream{st}
+
+		var err error
+		if ch.streamServer != nil {
+			err = ch.streamServer(s.impl, ss, &grpc.StreamServerInfo{
+				FullMethod:     method,
+				IsClientStream: desc.ClientStreams,
+				IsServerStream: desc.ServerStreams,
+			}, desc.Handler)
+		} else {
+			err = desc.Handler(s.impl, ss)
+		}
+
+		st.finish(err)
+	}()
+
+	return &clientStream{st}, nil
+}
+
+func (ch *Channel) lookup(method string) (s *service, name string, err error) {
+	serviceName, name, ok := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	if !ok {
+		err = status.Errorf(codes.Unimplemented, "malformed method name %q", method)
+		return
+	}
+
+	ch.mu.RLock()
+	defer ch.mu.RUnlock()
+
+	s, ok = ch.services[serviceName]
+	if !ok {
+		err = status.Errorf(codes.Unimplemented, "unknown service %s", serviceName)
+		return
+	}
+
+	return
+}
+
+func unknownMethod(method string) error {
+	return status.Errorf(codes.Unimplemented, "unknown method %s", method)
+}
+
+type addr struct{}
+
+func (addr) Network() string { return "inprocess" }
+func (addr) String() string  { return "inprocess" }
+
+var inProcessPeer = &peer.Peer{Addr: addr{}, LocalAddr: addr{}}
+
+type peerKey struct{}
+
+// NewPeerContext makes the handlers of calls made with the context see remote as the peer address instead
+// of the in-process one, e.g. the address of the HTTP client the calls are made on behalf of.
+func NewPeerContext(ctx context.Context, remote net.Addr) context.Context {
+	return context.WithValue(ctx, peerKey{}, &peer.Peer{Addr: remote, LocalAddr: addr{}})
+}
+
+// serverContext turns the client context into the handler context - outgoing metadata becomes incoming.
+func serverContext(ctx context.Context, ts *transportStream) (context.Context, context.CancelFunc) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+
+	ctx = metadata.NewIncomingContext(ctx, md.Copy())
+	ctx = metadata.NewOutgoingContext(ctx, nil)
+	p, ok := ctx.Value(peerKey{}).(*peer.Peer)
+	if !ok {
+		p = inProcessPeer
+	}
+
+	ctx = peer.NewContext(ctx, p)
+	ctx = grpc.NewContextWithServerTransportStream(ctx, ts)
+
+	return context.WithCancel(ctx)
+}
+
+// message returns the message as proto.Message; legacy (APIv1, e.g. gogo) messages are wrapped.
+func message(m any) (proto.Message, error) {
+	switch m := m.(type) {
+	case proto.Message:
+		return m, nil
+	case protoadapt.MessageV1:
+		return protoadapt.MessageV2Of(m), nil
+	}
+
+	return nil, status.Errorf(codes.Internal, "in-process message %T is not a proto message", m)
+}
+
+// copyMessage deep-copies src into dst; both have to be proto messages of the same type.
+func copyMessage(dst, src any) error {
+	d, err := message(dst)
+	if err != nil {
+		return err
+	}
+
+	s, err := message(src)
+	if err != nil {
+		return err
+	}
+
+	if d.ProtoReflect().Descriptor() != s.ProtoReflect().Descriptor() {
+		return status.Errorf(codes.Internal, "in-process message %T cannot be copied into %T", src, dst)
+	}
+
+	proto.Reset(d)
+	proto.Merge(d, s)
+
+	return nil
+}
+
+// toStatusError converts handler errors the same way the grpc server does.
+func toStatusError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	return status.Error(codes.Unknown, err.Error())
+}
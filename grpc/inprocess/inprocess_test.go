@@ -0,0 +1,315 @@
+package inprocess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// echo is a bidi stream service echoing received strings; "fail" ends the stream with an error.
+var echoDesc = grpc.ServiceDesc{
+	ServiceName: "test.Echo",
+	HandlerType: (*any)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Echo",
+		ServerStreams: true,
+		ClientStreams: true,
+		Handler: func(_ any, stream grpc.ServerStream) error {
+			for {
+				in := &wrapperspb.StringValue{}
+
+				err := stream.RecvMsg(in)
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+
+				if err != nil {
+					return err
+				}
+
+				if in.GetValue() == "fail" {
+					stream.SetTrailer(metadata.Pairs("reason", "fail"))
+					return errors.New("failed")
+				}
+
+				err = stream.SendMsg(in)
+				if err != nil {
+					return err
+				}
+			}
+		},
+	}},
+}
+
+func newTestChannel(t *testing.T, opts ...Option) (*Channel, *health.Server, *grpc.Server) {
+	t.Helper()
+
+	ch, err := New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+
+	r := ch.Registrar(server)
+	healthpb.RegisterHealthServer(r, healthServer)
+	r.RegisterService(&echoDesc, struct{}{})
+
+	return ch, healthServer, server
+}
+
+func TestChannel_Invoke(t *testing.T) {
+	ch, healthServer, server := newTestChannel(t)
+	healthServer.SetServingStatus("users", healthpb.HealthCheckResponse_SERVING)
+
+	// services are registered also to the server
+	if _, ok := server.GetServiceInfo()["grpc.health.v1.Health"]; !ok {
+		t.Error("health service not registered to the server")
+	}
+
+	tests := []struct {
+		method     string
+		service    string
+		wantCode   codes.Code
+		wantStatus healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{method: "/grpc.health.v1.Health/Check", service: "users", wantStatus: healthpb.HealthCheckResponse_SERVING},
+		{method: "/grpc.health.v1.Health/Check", service: "unknown", wantCode: codes.NotFound},
+		{method: "/grpc.health.v1.Health/Unknown", service: "users", wantCode: codes.Unimplemented},
+		{method: "/unknown.Service/Method", service: "users", wantCode: codes.Unimplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.service, func(t *testing.T) {
+			resp := &healthpb.HealthCheckResponse{}
+
+			err := ch.Invoke(t.Context(), tt.method, &healthpb.HealthCheckRequest{Service: tt.service}, resp)
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("Invoke() error = %v, want %v", err, tt.wantCode)
+			}
+
+			if resp.GetStatus() != tt.wantStatus {
+				t.Errorf("status = %v, want %v", resp.GetStatus(), tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestChannel_Interceptors(t *testing.T) {
+	var calls []string
+
+	unary := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			method, _ := grpc.Method(ctx)
+			p, _ := peer.FromContext(ctx)
+
+			call := fmt.Sprintf("%s %s %s %v %s", name, info.FullMethod, method, md.Get("x-request-id"), p.Addr)
+			calls = append(calls, call)
+
+			_ = grpc.SetHeader(ctx, metadata.Pairs("server", name))
+
+			return handler(ctx, req)
+		}
+	}
+
+	client := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		calls = append(calls, "client "+method)
+
+		return invoker(metadata.AppendToOutgoingContext(ctx, "x-request-id", "42"), method, req, reply, cc, opts...)
+	}
+
+	ch, _, _ := newTestChannel(t,
+		WithServerInterceptors([]grpc.UnaryServerInterceptor{unary("first"), unary("second")}, nil),
+		WithClientInterceptors([]grpc.UnaryClientInterceptor{client}, nil),
+	)
+
+	var header metadata.MD
+
+	_, err := healthpb.NewHealthClient(ch).Check(t.Context(), &healthpb.HealthCheckRequest{}, grpc.Header(&header))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantCalls := []string{
+		"client /grpc.health.v1.Health/Check",
+		"first /grpc.health.v1.Health/Check /grpc.health.v1.Health/Check [42] inprocess",
+		"second /grpc.health.v1.Health/Check /grpc.health.v1.Health/Check [42] inprocess",
+	}
+	if !reflect.DeepEqual(calls, wantCalls) {
+		t.Errorf("calls = %q, want %q", calls, wantCalls)
+	}
+
+	if got, want := header.Get("server"), []string{"first", "second"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("header = %q, want %q", got, want)
+	}
+}
+
+func TestChannel_ServerStream(t *testing.T) {
+	var streams []string
+
+	ch, healthServer, _ := newTestChannel(t, WithServerInterceptors(nil, []grpc.StreamServerInterceptor{
+		func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			streams = append(streams, info.FullMethod)
+			return handler(srv, ss)
+		},
+	}))
+
+	healthServer.SetServingStatus("users", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	watch, err := healthpb.NewHealthClient(ch).Watch(ctx, &healthpb.HealthCheckRequest{Service: "users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []healthpb.HealthCheckResponse_ServingStatus{
+		healthpb.HealthCheckResponse_NOT_SERVING,
+		healthpb.HealthCheckResponse_SERVING,
+	} {
+		healthServer.SetServingStatus("users", want)
+
+		resp, err := watch.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.GetStatus() != want {
+			t.Errorf("Recv() status = %v, want %v", resp.GetStatus(), want)
+		}
+	}
+
+	cancel()
+
+	_, err = watch.Recv()
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("Recv() after cancel error = %v, want %v", err, codes.Canceled)
+	}
+
+	if want := []string{"/grpc.health.v1.Health/Watch"}; !reflect.DeepEqual(streams, want) {
+		t.Errorf("streams = %q, want %q", streams, want)
+	}
+}
+
+func TestChannel_BidiStream(t *testing.T) {
+	ch, _, _ := newTestChannel(t)
+
+	tests := []struct {
+		name        string
+		in          []string
+		wantCode    codes.Code
+		wantTrailer []string
+	}{
+		{name: "echo", in: []string{"a", "b"}},
+		{name: "handler error", in: []string{"a", "fail"}, wantCode: codes.Unknown, wantTrailer: []string{"fail"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var trailer metadata.MD
+
+			stream, err := ch.NewStream(t.Context(), &echoDesc.Streams[0], "/test.Echo/Echo", grpc.Trailer(&trailer))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, s := range tt.in {
+				in := wrapperspb.String(s)
+
+				err = stream.SendMsg(in)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// messages are copied
+				in.Value = "changed"
+
+				out := &wrapperspb.StringValue{}
+
+				err = stream.RecvMsg(out)
+				if err != nil {
+					break
+				}
+
+				if out.GetValue() != s {
+					t.Errorf("RecvMsg() = %q, want %q", out.GetValue(), s)
+				}
+			}
+
+			if err == nil {
+				_ = stream.CloseSend()
+				err = stream.RecvMsg(&wrapperspb.StringValue{})
+			}
+
+			if tt.wantCode == codes.OK && !errors.Is(err, io.EOF) {
+				t.Errorf("RecvMsg() error = %v, want %v", err, io.EOF)
+			}
+
+			if tt.wantCode != codes.OK && status.Code(err) != tt.wantCode {
+				t.Errorf("RecvMsg() error = %v, want %v", err, tt.wantCode)
+			}
+
+			if got := trailer.Get("reason"); !reflect.DeepEqual(got, tt.wantTrailer) {
+				t.Errorf("trailer = %q, want %q", got, tt.wantTrailer)
+			}
+		})
+	}
+}
+
+func TestNewPeerContext(t *testing.T) {
+	client := &net.TCPAddr{IP: net.ParseIP("192.0.2.1")}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{name: "in-process", ctx: t.Context(), want: "inprocess"},
+		{name: "client address", ctx: NewPeerContext(t.Context(), client), want: "192.0.2.1:0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+
+			unary := func(
+				ctx context.Context,
+				req any,
+				_ *grpc.UnaryServerInfo,
+				handler grpc.UnaryHandler,
+			) (any, error) {
+				if p, ok := peer.FromContext(ctx); ok {
+					got = p.Addr.String()
+				}
+
+				return handler(ctx, req)
+			}
+
+			ch, _, _ := newTestChannel(t, WithServerInterceptors([]grpc.UnaryServerInterceptor{unary}, nil))
+
+			_, err := healthpb.NewHealthClient(ch).Check(tt.ctx, &healthpb.HealthCheckRequest{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("peer = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
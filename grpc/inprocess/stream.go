@@ -0,0 +1,279 @@
+package inprocess
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+var errHeaderSent = status.Error(codes.Internal, "header already sent")
+
+// transportStream collects headers and trailers set by the handler (grpc.SetHeader, grpc.SetTrailer, ...).
+type transportStream struct {
+	method string
+
+	mu          sync.Mutex
+	header      metadata.MD
+	trailer     metadata.MD
+	headerSent  bool
+	headerReady chan struct{} // closed once the header is sent
+}
+
+func newTransportStream(method string) *transportStream {
+	return &transportStream{
+		method:      method,
+		headerReady: make(chan struct{}),
+	}
+}
+
+func (ts *transportStream) Method() string {
+	return ts.method
+}
+
+func (ts *transportStream) SetHeader(md metadata.MD) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.headerSent {
+		return errHeaderSent
+	}
+
+	ts.header = metadata.Join(ts.header, md)
+
+	return nil
+}
+
+func (ts *transportStream) SendHeader(md metadata.MD) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.headerSent {
+		return errHeaderSent
+	}
+
+	ts.header = metadata.Join(ts.header, md)
+	ts.headerSent = true
+	close(ts.headerReady)
+
+	return nil
+}
+
+func (ts *transportStream) SetTrailer(md metadata.MD) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.trailer = metadata.Join(ts.trailer, md)
+
+	return nil
+}
+
+// sendHeader sends the header implicitly (with the first message or at the end of the call).
+func (ts *transportStream) sendHeader() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if !ts.headerSent {
+		ts.headerSent = true
+		close(ts.headerReady)
+	}
+}
+
+func (ts *transportStream) getHeader() metadata.MD {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.header.Copy()
+}
+
+func (ts *transportStream) getTrailer() metadata.MD {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.trailer.Copy()
+}
+
+// deliver fills header, trailer and peer call options.
+func (ts *transportStream) deliver(opts []grpc.CallOption) {
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case grpc.HeaderCallOption:
+			*o.HeaderAddr = ts.getHeader()
+		case grpc.TrailerCallOption:
+			*o.TrailerAddr = ts.getTrailer()
+		case grpc.PeerCallOption:
+			*o.PeerAddr = *inProcessPeer
+		}
+	}
+}
+
+// stream connects a client stream with the handler. Messages are passed through unbuffered channels, so
+// a sent message has always been received once the handler returns.
+type stream struct {
+	*transportStream
+
+	ctx     context.Context // client
+	sctx    context.Context // handler
+	cancel  context.CancelFunc
+	options []grpc.CallOption // filled at the end of the stream
+
+	toServer chan proto.Message
+	toClient chan proto.Message
+	closed   atomic.Bool
+	done     chan struct{}
+	err      error // set before done is closed
+}
+
+func newStream(ctx context.Context, method string, opts []grpc.CallOption) *stream {
+	s := &stream{
+		transportStream: newTransportStream(method),
+		ctx:             ctx,
+		options:         opts,
+		toServer:        make(chan proto.Message),
+		toClient:        make(chan proto.Message),
+		done:            make(chan struct{}),
+	}
+
+	s.sctx, s.cancel = serverContext(ctx, s.transportStream)
+
+	return s
+}
+
+func (s *stream) finish(err error) {
+	if err != nil {
+		s.err = toStatusError(err)
+	}
+
+	s.sendHeader()
+	s.deliver(s.options)
+	close(s.done)
+	s.cancel()
+}
+
+func contextError(ctx context.Context) error {
+	return status.FromContextError(ctx.Err()).Err()
+}
+
+type clientStream struct {
+	*stream
+}
+
+func (cs *clientStream) Header() (metadata.MD, error) {
+	select {
+	case <-cs.headerReady:
+		return cs.getHeader(), nil
+	case <-cs.ctx.Done():
+		return nil, contextError(cs.ctx)
+	}
+}
+
+func (cs *clientStream) Trailer() metadata.MD {
+	select {
+	case <-cs.done:
+		return cs.getTrailer()
+	default:
+		return nil
+	}
+}
+
+func (cs *clientStream) CloseSend() error {
+	if cs.closed.CompareAndSwap(false, true) {
+		close(cs.toServer)
+	}
+
+	return nil
+}
+
+func (cs *clientStream) Context() context.Context {
+	return cs.ctx
+}
+
+func (cs *clientStream) SendMsg(m any) error {
+	if cs.closed.Load() {
+		return status.Error(codes.Internal, "SendMsg called after CloseSend")
+	}
+
+	msg, err := message(m)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case cs.toServer <- proto.Clone(msg):
+		return nil
+	case <-cs.done:
+		// the status is returned by RecvMsg
+		return io.EOF
+	case <-cs.ctx.Done():
+		return contextError(cs.ctx)
+	}
+}
+
+func (cs *clientStream) RecvMsg(m any) error {
+	select {
+	case msg := <-cs.toClient:
+		return copyMessage(m, msg)
+	case <-cs.done:
+		if cs.err != nil {
+			return cs.err
+		}
+
+		return io.EOF
+	case <-cs.ctx.Done():
+		return contextError(cs.ctx)
+	}
+}
+
+type serverStream struct {
+	*stream
+}
+
+func (ss *serverStream) SetTrailer(md metadata.MD) {
+	_ = ss.transportStream.SetTrailer(md)
+}
+
+func (ss *serverStream) Context() context.Context {
+	return ss.sctx
+}
+
+func (ss *serverStream) SendMsg(m any) error {
+	msg, err := message(m)
+	if err != nil {
+		return err
+	}
+
+	ss.sendHeader()
+
+	select {
+	case ss.toClient <- proto.Clone(msg):
+		return nil
+	case <-ss.sctx.Done():
+		return contextError(ss.sctx)
+	}
+}
+
+func (ss *serverStream) RecvMsg(m any) error {
+	select {
+	case msg, ok := <-ss.toServer:
+		if !ok {
+			return io.EOF
+		}
+
+		return copyMessage(m, msg)
+	case <-ss.sctx.Done():
+		return contextError(ss.sctx)
+	}
+}
+
+var (
+	_ grpc.ClientStream          = (*clientStream)(nil)
+	_ grpc.ServerStream          = (*serverStream)(nil)
+	_ grpc.ServerTransportStream = (*transportStream)(nil)
+	_ grpc.ClientConnInterface   = (*Channel)(nil)
+)
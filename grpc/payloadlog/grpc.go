@@ -0,0 +1,84 @@
+package payloadlog
+
+import (
+	"context"
+	"time"
+
+	"github.com/moderntv/cadre/requestid"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor logs the request and response of sampled unary calls in one entry.
+func (l *Logger) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !l.sampled(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		e := l.event(ctx, info.FullMethod).
+			Str("grpc.code", status.Code(err).String()).
+			Dur("grpc.time_ms", time.Since(start))
+		e = l.payload(e, "request", req)
+
+		if err == nil {
+			e = l.payload(e, "response", resp)
+		}
+
+		e.Msg("grpc payload")
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor logs every message received and sent by sampled streams.
+func (l *Logger) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !l.sampled(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, logger: l, method: info.FullMethod})
+	}
+}
+
+func (l *Logger) event(ctx context.Context, fullMethod string) *zerolog.Event {
+	e := l.logger.Info().Str("grpc.method", fullMethod)
+
+	if id, ok := requestid.FromContext(ctx); ok {
+		e = e.Str(requestid.LogField, id)
+	}
+
+	return e
+}
+
+type serverStream struct {
+	grpc.ServerStream
+
+	logger *Logger
+	method string
+}
+
+func (s *serverStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.logger.payload(s.logger.event(s.Context(), s.method).Str("direction", "send"), "message", m).
+			Msg("grpc stream payload")
+	}
+
+	return err
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.logger.payload(s.logger.event(s.Context(), s.method).Str("direction", "recv"), "message", m).
+			Msg("grpc stream payload")
+	}
+
+	return err
+}
@@ -0,0 +1,324 @@
+// Package payloadlog logs request and response payloads of gRPC calls for debugging.
+//
+// Logging is opt-in: the interceptors log nothing until the logger is enabled, which can be done
+// at runtime. Calls are sampled per method and payloads are capped in size. Fields marked with
+// the `debug_redact` field option, a configured redaction extension or listed by their full name
+// (`package.Message.field`) are redacted before logging.
+package payloadlog
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"path"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// Redacted replaces redacted string fields.
+	Redacted = "[REDACTED]"
+
+	defaultMaxSize = 4096
+
+	anyFullName protoreflect.FullName = "google.protobuf.Any"
+)
+
+// SamplingRule samples calls of methods matching the pattern.
+type SamplingRule struct {
+	// Method is a path.Match pattern matched against the full method (`/package.Service/Method`).
+	Method string
+	// Rate is the share of logged calls between 0 and 1.
+	Rate float64
+}
+
+type Logger struct {
+	logger  zerolog.Logger
+	enabled atomic.Bool
+	maxSize int
+
+	mu           sync.RWMutex
+	rules        []SamplingRule
+	defaultRate  float64
+	redactFields map[protoreflect.FullName]bool
+	redactExt    protoreflect.ExtensionType
+}
+
+type Option func(*Logger) error
+
+// New creates a new disabled payload logger.
+func New(logger zerolog.Logger, opts ...Option) (l *Logger, err error) {
+	l = &Logger{
+		logger:       logger,
+		maxSize:      defaultMaxSize,
+		defaultRate:  1,
+		redactFields: map[protoreflect.FullName]bool{},
+	}
+
+	for _, opt := range opts {
+		err = opt(l)
+		if err != nil {
+			err = fmt.Errorf("cannot apply payload logger option: %w", err)
+			return
+		}
+	}
+
+	return
+}
+
+// WithEnabled enables logging right away.
+func WithEnabled() Option {
+	return func(l *Logger) error {
+		l.enabled.Store(true)
+
+		return nil
+	}
+}
+
+// WithSampling configures sampling rules. The first rule matching the method applies,
+// methods matched by no rule use the default rate. Defaults to logging every call.
+func WithSampling(defaultRate float64, rules ...SamplingRule) Option {
+	return func(l *Logger) error {
+		return l.SetSampling(defaultRate, rules...)
+	}
+}
+
+// WithMaxSize caps the size of a logged payload in bytes. Larger payloads are truncated. Defaults to 4 KiB.
+func WithMaxSize(size int) Option {
+	return func(l *Logger) error {
+		if size <= 0 {
+			return errors.New("max payload size has to be positive")
+		}
+
+		l.maxSize = size
+
+		return nil
+	}
+}
+
+// WithRedactedFields redacts fields by their full names, e.g. `example.LoginRequest.password`.
+func WithRedactedFields(fields ...string) Option {
+	return func(l *Logger) error {
+		for _, field := range fields {
+			name := protoreflect.FullName(field)
+			if !name.IsValid() {
+				return fmt.Errorf("invalid field name `%s`", field)
+			}
+
+			l.redactFields[name] = true
+		}
+
+		return nil
+	}
+}
+
+// WithRedactExtension redacts fields having the boolean field option extension set to true,
+// e.g. a custom `(mycompany.sensitive) = true`. The standard `debug_redact` option is always honored.
+func WithRedactExtension(xt protoreflect.ExtensionType) Option {
+	return func(l *Logger) error {
+		if xt == nil || xt.TypeDescriptor().Kind() != protoreflect.BoolKind {
+			return errors.New("redact extension has to be a bool field option")
+		}
+
+		l.redactExt = xt
+
+		return nil
+	}
+}
+
+// Enable starts logging payloads.
+func (l *Logger) Enable() {
+	l.enabled.Store(true)
+}
+
+// Disable stops logging payloads.
+func (l *Logger) Disable() {
+	l.enabled.Store(false)
+}
+
+// Enabled reports whether payloads are logged.
+func (l *Logger) Enabled() bool {
+	return l.enabled.Load()
+}
+
+// SetSampling replaces the sampling rules at runtime.
+func (l *Logger) SetSampling(defaultRate float64, rules ...SamplingRule) error {
+	if defaultRate < 0 || defaultRate > 1 {
+		return fmt.Errorf("invalid default sampling rate %v", defaultRate)
+	}
+
+	for _, rule := range rules {
+		_, err := path.Match(rule.Method, "")
+		if err != nil {
+			return fmt.Errorf("invalid sampling method pattern `%s`: %w", rule.Method, err)
+		}
+
+		if rule.Rate < 0 || rule.Rate > 1 {
+			return fmt.Errorf("invalid sampling rate %v of `%s`", rule.Rate, rule.Method)
+		}
+	}
+
+	l.mu.Lock()
+	l.defaultRate = defaultRate
+	l.rules = append([]SamplingRule(nil), rules...)
+	l.mu.Unlock()
+
+	return nil
+}
+
+// sampled decides whether the call of the method is logged.
+func (l *Logger) sampled(fullMethod string) bool {
+	if !l.Enabled() {
+		return false
+	}
+
+	l.mu.RLock()
+	rate := l.defaultRate
+
+	for _, rule := range l.rules {
+		if ok, _ := path.Match(rule.Method, fullMethod); ok {
+			rate = rule.Rate
+			break
+		}
+	}
+	l.mu.RUnlock()
+
+	return rate >= 1 || (rate > 0 && rand.Float64() < rate) //nolint: gosec
+}
+
+// payload adds the redacted and capped message to the event under the key.
+func (l *Logger) payload(e *zerolog.Event, key string, m any) *zerolog.Event {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return e.Str(key, fmt.Sprintf("<%T>", m))
+	}
+
+	msg = proto.Clone(msg)
+	l.redact(msg.ProtoReflect())
+
+	d, err := protojson.Marshal(msg)
+	if err != nil {
+		return e.Str(key, "<cannot marshal: "+err.Error()+">")
+	}
+
+	if len(d) > l.maxSize {
+		// do not split a multi-byte character
+		size := l.maxSize
+		for size > 0 && !utf8.RuneStart(d[size]) {
+			size--
+		}
+
+		return e.Str(key, string(d[:size])).Bool(key+"_truncated", true).Int(key+"_size", len(d))
+	}
+
+	return e.RawJSON(key, d)
+}
+
+// redact redacts sensitive fields of the message in place.
+func (l *Logger) redact(m protoreflect.Message) {
+	if m.Descriptor().FullName() == anyFullName {
+		l.redactAny(m)
+		return
+	}
+
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if l.sensitive(fd) {
+			if fd.Kind() == protoreflect.StringKind && !fd.IsList() && !fd.IsMap() {
+				m.Set(fd, protoreflect.ValueOfString(Redacted))
+			} else {
+				m.Clear(fd)
+			}
+
+			return true
+		}
+
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := range list.Len() {
+				l.redact(list.Get(i).Message())
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				l.redact(mv.Message())
+				return true
+			})
+		case fd.Message() != nil && !fd.IsList() && !fd.IsMap():
+			l.redact(v.Message())
+		}
+
+		return true
+	})
+}
+
+// redactAny redacts the message packed in google.protobuf.Any. Messages which cannot be unpacked are redacted whole.
+func (l *Logger) redactAny(m protoreflect.Message) {
+	fields := m.Descriptor().Fields()
+	typeURL := fields.ByNumber(1)
+	value := fields.ByNumber(2)
+
+	if !m.Has(typeURL) {
+		return
+	}
+
+	mt, err := protoregistry.GlobalTypes.FindMessageByURL(m.Get(typeURL).String())
+	if err != nil {
+		m.Clear(typeURL)
+		m.Clear(value)
+
+		return
+	}
+
+	packed := mt.New()
+
+	err = proto.Unmarshal(m.Get(value).Bytes(), packed.Interface())
+	if err != nil {
+		m.Clear(typeURL)
+		m.Clear(value)
+
+		return
+	}
+
+	l.redact(packed)
+
+	d, err := proto.Marshal(packed.Interface())
+	if err != nil {
+		m.Clear(typeURL)
+		m.Clear(value)
+
+		return
+	}
+
+	m.Set(value, protoreflect.ValueOfBytes(d))
+}
+
+func (l *Logger) sensitive(fd protoreflect.FieldDescriptor) bool {
+	if l.redactFields[fd.FullName()] {
+		return true
+	}
+
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	if !ok || opts == nil {
+		return false
+	}
+
+	if opts.GetDebugRedact() {
+		return true
+	}
+
+	if l.redactExt != nil && proto.HasExtension(opts, l.redactExt) {
+		redact, _ := proto.GetExtension(opts, l.redactExt).(bool)
+
+		return redact
+	}
+
+	return false
+}
@@ -0,0 +1,230 @@
+package payloadlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func newLoginRequest(t *testing.T) proto.Message {
+	t.Helper()
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("login_test.proto"),
+		Package: proto.String("example"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("LoginRequest"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{
+					Name:     proto.String("username"),
+					JsonName: proto.String("username"),
+					Number:   proto.Int32(1),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				},
+				{
+					Name:     proto.String("password"),
+					JsonName: proto.String("password"),
+					Number:   proto.Int32(2),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					Options:  &descriptorpb.FieldOptions{DebugRedact: proto.Bool(true)},
+				},
+			},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desc := fd.Messages().ByName("LoginRequest")
+
+	msg := dynamicpb.NewMessage(desc)
+	msg.Set(desc.Fields().ByName("username"), protoreflect.ValueOfString("john"))
+	msg.Set(desc.Fields().ByName("password"), protoreflect.ValueOfString("secret"))
+
+	return msg
+}
+
+func entries(t *testing.T, logs *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var result []map[string]any
+
+	for line := range strings.Lines(logs.String()) {
+		var entry map[string]any
+
+		err := json.Unmarshal([]byte(line), &entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result = append(result, entry)
+	}
+
+	return result
+}
+
+func TestLogger_UnaryServerInterceptor(t *testing.T) {
+	loginRule := SamplingRule{Method: "/example.AuthService/*", Rate: 1}
+
+	tests := []struct {
+		name         string
+		opts         []Option
+		method       string
+		want         []string
+		wantRequest  any
+		wantResponse any
+	}{
+		{name: "disabled by default", method: "/example.AuthService/Login"},
+		{
+			name:         "redacted",
+			opts:         []Option{WithEnabled(), WithRedactedFields("google.protobuf.DescriptorProto.name")},
+			method:       "/example.AuthService/Login",
+			wantRequest:  map[string]any{"username": "john", "password": Redacted},
+			wantResponse: map[string]any{"name": "file.proto", "messageType": []any{map[string]any{"name": Redacted}}},
+		},
+		{
+			name:   "not sampled",
+			opts:   []Option{WithEnabled(), WithSampling(0, loginRule)},
+			method: "/example.Other/Method",
+		},
+		{
+			name:         "sampled and truncated",
+			opts:         []Option{WithEnabled(), WithSampling(0, loginRule), WithMaxSize(10)},
+			method:       "/example.AuthService/Login",
+			wantRequest:  `{"username`,
+			wantResponse: `{"name":"f`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+
+			l, err := New(zerolog.New(&logs), tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp := &descriptorpb.FileDescriptorProto{
+				Name:        proto.String("file.proto"),
+				MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Hidden")}},
+			}
+
+			_, err = l.UnaryServerInterceptor()(
+				t.Context(),
+				newLoginRequest(t),
+				&grpc.UnaryServerInfo{FullMethod: tt.method},
+				func(context.Context, any) (any, error) { return resp, nil },
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the original response is not modified
+			if resp.GetMessageType()[0].GetName() != "Hidden" {
+				t.Error("response was modified")
+			}
+
+			logged := entries(t, &logs)
+			if tt.wantRequest == nil {
+				if len(logged) > 0 {
+					t.Errorf("logged %v, want nothing", logged)
+				}
+
+				return
+			}
+
+			if len(logged) != 1 || logged[0]["grpc.method"] != tt.method {
+				t.Fatalf("logged %v, want one entry of %s", logged, tt.method)
+			}
+
+			if got := logged[0]["request"]; !reflect.DeepEqual(got, tt.wantRequest) {
+				t.Errorf("request = %v, want %v", got, tt.wantRequest)
+			}
+
+			if got := logged[0]["response"]; !reflect.DeepEqual(got, tt.wantResponse) {
+				t.Errorf("response = %v, want %v", got, tt.wantResponse)
+			}
+		})
+	}
+}
+
+func TestLogger_payload(t *testing.T) {
+	hidden, err := anypb.New(&descriptorpb.DescriptorProto{Name: proto.String("Hidden")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		maxSize int
+		msg     proto.Message
+		want    string
+	}{
+		{
+			name:    "packed message is redacted",
+			maxSize: 1024,
+			msg:     hidden,
+			want:    `{"@type":"type.googleapis.com/google.protobuf.DescriptorProto","name":"` + Redacted + `"}`,
+		},
+		{
+			name:    "unknown packed message is redacted whole",
+			maxSize: 1024,
+			msg:     &anypb.Any{TypeUrl: "type.googleapis.com/example.Unknown", Value: []byte("secret")},
+			want:    `{}`,
+		},
+		{
+			name:    "truncated on character boundary",
+			maxSize: 15,
+			msg:     &descriptorpb.FileDescriptorProto{Package: proto.String("žž")},
+			want:    `{"package":"ž`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+
+			l, err := New(zerolog.New(&logs), WithMaxSize(tt.maxSize),
+				WithRedactedFields("google.protobuf.DescriptorProto.name"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l.payload(l.logger.Info(), "payload", tt.msg).Send()
+
+			var entry struct {
+				Payload json.RawMessage `json:"payload"`
+			}
+
+			err = json.Unmarshal(logs.Bytes(), &entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := string(entry.Payload)
+
+			var s string
+			if json.Unmarshal(entry.Payload, &s) == nil {
+				got = s
+			}
+
+			if strings.ReplaceAll(got, " ", "") != tt.want {
+				t.Errorf("payload = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+// Package gateway serves grpc-gateway (REST to gRPC) handlers from cadre's gin HTTP servers.
+//
+// Requests not matched by any gin route are handed to a runtime.ServeMux whose handlers call the gRPC services
+// over an in-process connection. They pass through the server's global middleware (metrics, logging, request ID,
+// tracing, recovery), which see the pattern of the gateway handler as the route when RouteMiddleware precedes
+// them. The gRPC interceptors see the HTTP client as the peer. Failed calls are rendered the same way as errors
+// of the responses package.
+package gateway
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/moderntv/cadre/grpc/inprocess"
+	"github.com/moderntv/cadre/http/middleware"
+	"github.com/moderntv/cadre/http/responses"
+	"github.com/moderntv/cadre/http/route"
+	"github.com/moderntv/cadre/validation"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Registrator registers gateway handlers of a service into the mux, calling the service through conn.
+// It usually wraps the Register<Service>HandlerClient function generated by protoc-gen-grpc-gateway:
+//
+//	func(ctx context.Context, mux *runtime.ServeMux, conn grpc.ClientConnInterface) error {
+//		return pb.RegisterUsersHandlerClient(ctx, mux, pb.NewUsersClient(conn))
+//	}
+type Registrator func(ctx context.Context, mux *runtime.ServeMux, conn grpc.ClientConnInterface) error
+
+type (
+	ginContextKey struct{}
+	probeKey      struct{}
+)
+
+// NewServeMux creates a grpc-gateway mux rendering errors using the responses package.
+// Given options are applied afterwards and may override the error handlers.
+func NewServeMux(opts ...runtime.ServeMuxOption) *runtime.ServeMux {
+	opts = append([]runtime.ServeMuxOption{
+		runtime.WithErrorHandler(ErrorHandler),
+		runtime.WithRoutingErrorHandler(RoutingErrorHandler),
+		runtime.WithMiddlewares(probe),
+	}, opts...)
+
+	return runtime.NewServeMux(opts...)
+}
+
+// probe reports the pattern of the matched handler instead of calling it when the request is probed by match.
+func probe(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		pattern, ok := r.Context().Value(probeKey{}).(*string)
+		if !ok {
+			next(w, r, pathParams)
+			return
+		}
+
+		if p, ok := runtime.HTTPPattern(r.Context()); ok {
+			*pattern = p.String()
+		}
+	}
+}
+
+// match returns the path template (e.g. `/v1/users/{id=*}`) of the handler the mux serves the request by.
+// The mux has to be created by NewServeMux.
+func match(mux *runtime.ServeMux, r *http.Request) (pattern string, ok bool) {
+	probed := r.Clone(context.WithValue(r.Context(), probeKey{}, &pattern))
+	probed.Body = http.NoBody
+
+	mux.ServeHTTP(discardWriter{header: http.Header{}}, probed)
+
+	return pattern, pattern != ""
+}
+
+// RouteMiddleware returns gin middleware setting the route (see package http/route) and the metrics endpoint
+// of requests not matched by any gin route to the pattern of the gateway handler serving them. It has to precede
+// the middleware matching policies by routes.
+func RouteMiddleware(mux *runtime.ServeMux) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.FullPath() == "" {
+			if pattern, ok := match(mux, c.Request); ok {
+				route.Set(c, pattern)
+				c.Set(middleware.MetricsEndpointKey, pattern)
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// Handler returns gin handler serving requests by the mux. It is meant to be used as the NoRoute handler.
+// Calls made over an in-process connection carry the client address as their peer.
+func Handler(mux *runtime.ServeMux) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), ginContextKey{}, c)
+
+		if ip := net.ParseIP(c.ClientIP()); ip != nil {
+			ctx = inprocess.NewPeerContext(ctx, &net.TCPAddr{IP: ip})
+		}
+
+		// gin presets 404 for unmatched requests; gateway handlers write successful responses without explicit status
+		c.Status(http.StatusOK)
+
+		mux.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+	}
+}
+
+// ErrorHandler renders failed gRPC calls as responses.ErrorResponse with HTTP status matching the gRPC code.
+// Outside of Handler it falls back to runtime.DefaultHTTPErrorHandler.
+func ErrorHandler(
+	ctx context.Context,
+	mux *runtime.ServeMux,
+	marshaler runtime.Marshaler,
+	w http.ResponseWriter,
+	r *http.Request,
+	err error,
+) {
+	c, ok := ctx.Value(ginContextKey{}).(*gin.Context)
+	if !ok {
+		runtime.DefaultHTTPErrorHandler(ctx, mux, marshaler, w, r, err)
+		return
+	}
+
+	st := status.Convert(err)
+	errs := Errors(st)
+
+	switch st.Code() {
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		responses.BadRequest(c, errs...)
+	case codes.Unauthenticated:
+		responses.Unauthorized(c, errs...)
+	case codes.PermissionDenied:
+		responses.Forbidden(c, errs...)
+	case codes.NotFound:
+		responses.NotFound(c, errs...)
+	case codes.AlreadyExists, codes.Aborted:
+		responses.Conflict(c, errs...)
+	case codes.ResourceExhausted:
+		responses.TooManyRequests(c, errs...)
+	case codes.DeadlineExceeded:
+		responses.Timeout(c)
+	case codes.Unavailable:
+		responses.Unavailable(c, errs...)
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		responses.InternalError(c, errs...)
+	default:
+		c.AbortWithStatusJSON(runtime.HTTPStatusFromCode(st.Code()), responses.ErrorResponse{
+			Message: st.Message(),
+			Errors:  errs,
+		})
+	}
+}
+
+// RoutingErrorHandler renders requests not matched by any gateway handler the same way as the HTTP server
+// renders requests not matched by any route.
+func RoutingErrorHandler(
+	ctx context.Context,
+	mux *runtime.ServeMux,
+	marshaler runtime.Marshaler,
+	w http.ResponseWriter,
+	r *http.Request,
+	httpStatus int,
+) {
+	c, ok := ctx.Value(ginContextKey{}).(*gin.Context)
+	if !ok {
+		runtime.DefaultRoutingErrorHandler(ctx, mux, marshaler, w, r, httpStatus)
+		return
+	}
+
+	switch httpStatus {
+	case http.StatusNotFound:
+		responses.NotFound(c, responses.Error{
+			Type:    "NO_ROUTE",
+			Message: "No such route",
+			Data:    c.Request.RequestURI,
+		})
+	case http.StatusMethodNotAllowed:
+		c.AbortWithStatusJSON(http.StatusMethodNotAllowed, responses.ErrorResponse{
+			Message: "Method not allowed",
+		})
+	default:
+		responses.BadRequest(c, responses.Error{
+			Type:    "BAD_REQUEST",
+			Message: http.StatusText(httpStatus),
+		})
+	}
+}
+
+// Errors converts the status to response errors. Field violations of errdetails.BadRequest are converted
+// to INVALID_FIELD errors as validation.BadRequest does, other statuses to a single error typed
+// by errdetails.ErrorInfo reason if present or by the code (e.g. NOT_FOUND).
+func Errors(st *status.Status) []responses.Error {
+	errType := codeType(st.Code())
+
+	var errs []responses.Error
+
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			errType = d.GetReason()
+		case *errdetails.BadRequest:
+			for _, violation := range d.GetFieldViolations() {
+				errs = append(errs, responses.Error{
+					Type:    "INVALID_FIELD",
+					Message: violation.GetDescription(),
+					Data: validation.FieldViolation{
+						Field:       violation.GetField(),
+						Description: violation.GetDescription(),
+						Reason:      violation.GetReason(),
+					},
+				})
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return []responses.Error{{
+		Type:    errType,
+		Message: st.Message(),
+	}}
+}
+
+// codeType converts the code name to upper snake case (e.g. InvalidArgument to INVALID_ARGUMENT).
+func codeType(code codes.Code) string {
+	var b strings.Builder
+
+	for i, r := range code.String() {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte('_')
+		}
+
+		b.WriteRune(unicode.ToUpper(r))
+	}
+
+	return b.String()
+}
+
+// discardWriter discards responses of probed requests.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w discardWriter) Header() http.Header       { return w.header }
+func (discardWriter) Write(d []byte) (int, error) { return len(d), nil }
+func (discardWriter) WriteHeader(int)             {}
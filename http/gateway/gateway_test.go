@@ -0,0 +1,253 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/moderntv/cadre/grpc/inprocess"
+	"github.com/moderntv/cadre/http/middleware"
+	"github.com/moderntv/cadre/http/responses"
+	"github.com/moderntv/cadre/http/route"
+	"github.com/moderntv/cadre/validation"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// registerHealth mimics a generated Register<Service>HandlerClient function for the health service.
+func registerHealth(_ context.Context, mux *runtime.ServeMux, conn grpc.ClientConnInterface) error {
+	client := healthpb.NewHealthClient(conn)
+
+	return mux.HandlePath("GET", "/v1/health/{service}", func(
+		w http.ResponseWriter,
+		r *http.Request,
+		p map[string]string,
+	) {
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+
+		resp, err := client.Check(r.Context(), &healthpb.HealthCheckRequest{Service: p["service"]})
+		if err != nil {
+			runtime.HTTPError(r.Context(), mux, outbound, w, r, err)
+			return
+		}
+
+		runtime.ForwardResponseMessage(r.Context(), mux, outbound, w, r, resp)
+	})
+}
+
+func newRouter(t *testing.T, interceptors ...grpc.UnaryServerInterceptor) *gin.Engine {
+	t.Helper()
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("users", healthpb.HealthCheckResponse_SERVING)
+
+	ch, err := inprocess.New(inprocess.WithServerInterceptors(interceptors, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	healthpb.RegisterHealthServer(ch, healthServer)
+
+	mux := NewServeMux()
+	err = registerHealth(t.Context(), mux, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(RouteMiddleware(mux), func(c *gin.Context) {
+		c.Header("X-Route", route.Method(c))
+		c.Header("X-Metrics-Endpoint", c.GetString(middleware.MetricsEndpointKey))
+	})
+	router.GET("/native", func(c *gin.Context) { responses.Ok(c, "native") })
+	router.NoRoute(Handler(mux))
+
+	return router
+}
+
+func serve(router *gin.Engine, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	router := newRouter(t)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+		wantBody string
+		wantErr  string
+	}{
+		{
+			name:     "grpc",
+			method:   "GET",
+			path:     "/v1/health/users",
+			wantCode: http.StatusOK,
+			wantBody: `{"status":"SERVING"}`,
+		},
+		{name: "native", method: "GET", path: "/native", wantCode: http.StatusOK, wantBody: `{"data":"native"}`},
+		{
+			name:     "grpc error",
+			method:   "GET",
+			path:     "/v1/health/orders",
+			wantCode: http.StatusNotFound,
+			wantErr:  "NOT_FOUND",
+		},
+		{name: "no route", method: "GET", path: "/v1/nothing", wantCode: http.StatusNotFound, wantErr: "NO_ROUTE"},
+		{name: "method not allowed", method: "POST", path: "/v1/health/users", wantCode: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(router, tt.method, tt.path)
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+
+			if tt.wantBody != "" {
+				if got := w.Body.String(); got != tt.wantBody {
+					t.Errorf("body = %s, want %s", got, tt.wantBody)
+				}
+
+				return
+			}
+
+			var resp responses.ErrorResponse
+
+			err := json.Unmarshal(w.Body.Bytes(), &resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, e := range resp.Errors {
+				got = append(got, e.Type)
+			}
+
+			var want []string
+			if tt.wantErr != "" {
+				want = []string{tt.wantErr}
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("error types = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestRouteMiddleware(t *testing.T) {
+	var peerAddr string
+
+	recordPeer := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+		if p, ok := peer.FromContext(ctx); ok {
+			peerAddr = p.Addr.String()
+		}
+
+		return h(ctx, req)
+	}
+
+	router := newRouter(t, recordPeer)
+
+	tests := []struct {
+		method       string
+		path         string
+		wantRoute    string
+		wantEndpoint string
+	}{
+		{
+			method:       "GET",
+			path:         "/v1/health/users",
+			wantRoute:    "GET /v1/health/{service=*}",
+			wantEndpoint: "/v1/health/{service=*}",
+		},
+		{method: "GET", path: "/native", wantRoute: "GET /native"},
+		{method: "GET", path: "/v1/nothing", wantRoute: "GET "},
+		{method: "POST", path: "/v1/health/users", wantRoute: "POST "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := serve(router, tt.method, tt.path)
+
+			if got := w.Header().Get("X-Route"); got != tt.wantRoute {
+				t.Errorf("route = %q, want %q", got, tt.wantRoute)
+			}
+
+			if got := w.Header().Get("X-Metrics-Endpoint"); got != tt.wantEndpoint {
+				t.Errorf("metrics endpoint = %q, want %q", got, tt.wantEndpoint)
+			}
+		})
+	}
+
+	// the grpc service sees the http client
+	if peerAddr != "192.0.2.1:0" {
+		t.Errorf("peer = %q, want the client address", peerAddr)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	withDetails := func(details ...protoadapt.MessageV1) *status.Status {
+		st, err := status.New(codes.InvalidArgument, "invalid").WithDetails(details...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return st
+	}
+
+	tests := []struct {
+		name string
+		st   *status.Status
+		want []responses.Error
+	}{
+		{
+			name: "code",
+			st:   status.New(codes.FailedPrecondition, "not ready"),
+			want: []responses.Error{{Type: "FAILED_PRECONDITION", Message: "not ready"}},
+		},
+		{
+			name: "error info",
+			st:   withDetails(&errdetails.ErrorInfo{Reason: "INVALID_INPUT"}),
+			want: []responses.Error{{Type: "INVALID_INPUT", Message: "invalid"}},
+		},
+		{
+			name: "field violation",
+			st: withDetails(&errdetails.BadRequest{
+				FieldViolations: []*errdetails.BadRequest_FieldViolation{
+					{Field: "name", Description: "name is required", Reason: "required"},
+				},
+			}),
+			want: []responses.Error{{
+				Type:    "INVALID_FIELD",
+				Message: "name is required",
+				Data:    validation.FieldViolation{Field: "name", Description: "name is required", Reason: "required"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Errors(tt.st); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Errors() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
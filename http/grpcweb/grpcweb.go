@@ -0,0 +1,270 @@
+// Package grpcweb serves gRPC-Web requests from cadre's gin HTTP servers so that browsers can call
+// gRPC services directly.
+//
+// The middleware detects gRPC-Web (application/grpc-web) and gRPC-Web-text (application/grpc-web-text)
+// requests and their CORS preflights and translates them to the gRPC server, other requests continue to
+// the REST routes of the same listener. Translated requests are labelled `grpc-web` (or `grpc-web-text`)
+// in the `proto` label of the HTTP metrics.
+//
+// Cross-origin requests are accepted only from the allowed origins (WithAllowedOrigins), same-origin
+// requests always.
+package grpcweb
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/http/middleware"
+	"google.golang.org/grpc"
+)
+
+const (
+	// ProtoGRPCWeb is the `proto` metrics label of gRPC-Web requests.
+	ProtoGRPCWeb = "grpc-web"
+	// ProtoGRPCWebText is the `proto` metrics label of gRPC-Web-text requests.
+	ProtoGRPCWebText = "grpc-web-text"
+
+	// UnknownEndpoint is the `endpoint` metrics label of gRPC-Web requests of methods not registered on the gRPC server.
+	UnknownEndpoint = "grpc-web-unknown"
+
+	contentTypeGRPC        = "application/grpc"
+	contentTypeGRPCWeb     = "application/grpc-web"
+	contentTypeGRPCWebText = "application/grpc-web-text"
+)
+
+// headers sent by gRPC-Web clients, allowed in cross-origin requests even if not listed in WithAllowedHeaders
+var requiredHeaders = []string{"content-type", "x-grpc-web", "x-user-agent", "grpc-timeout"}
+
+// response headers readable by cross-origin clients in addition to the ones sent by the gRPC server
+var exposedHeaders = []string{"grpc-status", "grpc-message", "grpc-status-details-bin"}
+
+type GRPCWeb struct {
+	allowAllOrigins bool
+	allowedOrigins  map[string]bool
+	allowAllHeaders bool
+	allowedHeaders  map[string]bool
+
+	server *grpc.Server
+
+	// registered methods - services are registered after the translation is created
+	methodsOnce sync.Once
+	methods     map[string]bool
+}
+
+type Option func(*GRPCWeb) error
+
+// New creates gRPC-Web translation of requests to the gRPC server.
+func New(server *grpc.Server, opts ...Option) (g *GRPCWeb, err error) {
+	if server == nil {
+		err = errors.New("grpc-web requires grpc server")
+		return
+	}
+
+	g = &GRPCWeb{
+		server:          server,
+		allowedOrigins:  map[string]bool{},
+		allowAllHeaders: true,
+	}
+
+	for _, opt := range opts {
+		err = opt(g)
+		if err != nil {
+			err = fmt.Errorf("cannot apply grpc-web option: %w", err)
+			return
+		}
+	}
+
+	return
+}
+
+// WithAllowedOrigins allows cross-origin requests from the origins (e.g. `https://player.example.com`).
+// Origin `*` allows all origins.
+func WithAllowedOrigins(origins ...string) Option {
+	return func(g *GRPCWeb) error {
+		for _, origin := range origins {
+			if origin == "*" {
+				g.allowAllOrigins = true
+				continue
+			}
+
+			g.allowedOrigins[strings.ToLower(origin)] = true
+		}
+
+		return nil
+	}
+}
+
+// WithAllowedHeaders restricts request headers allowed in cross-origin requests. All headers are allowed by default,
+// the headers of the gRPC-Web protocol always.
+func WithAllowedHeaders(headers ...string) Option {
+	return func(g *GRPCWeb) error {
+		g.allowAllHeaders = false
+		g.allowedHeaders = map[string]bool{}
+
+		for _, header := range append(headers, requiredHeaders...) {
+			if header == "*" {
+				g.allowAllHeaders = true
+				continue
+			}
+
+			g.allowedHeaders[strings.ToLower(header)] = true
+		}
+
+		return nil
+	}
+}
+
+// Middleware returns gin middleware serving gRPC-Web requests and their CORS preflights by the gRPC server
+// and aborting the chain. Other requests are passed on.
+func (g *GRPCWeb) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		proto, ok := g.proto(c.Request)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		c.Set(middleware.MetricsProtoKey, proto)
+		c.Set(middleware.MetricsEndpointKey, g.endpoint(c.Request.URL.Path))
+
+		if c.Request.Method == http.MethodOptions {
+			g.preflight(c)
+			return
+		}
+
+		if !g.isAllowedOrigin(c.Request) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		if c.Request.Header.Get("Origin") != "" {
+			c.Header("Access-Control-Allow-Origin", c.Request.Header.Get("Origin"))
+			c.Header("Vary", "Origin")
+		}
+
+		// gin presets 404 for requests not matched by any route
+		c.Status(http.StatusOK)
+		g.serve(c.Writer, c.Request, proto == ProtoGRPCWebText)
+		c.Abort()
+	}
+}
+
+// proto returns the metrics label of the gRPC-Web request or false if r is neither a gRPC-Web request
+// nor its CORS preflight.
+func (g *GRPCWeb) proto(r *http.Request) (string, bool) {
+	contentType := r.Header.Get("Content-Type")
+
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(contentType, contentTypeGRPCWebText):
+		return ProtoGRPCWebText, true
+	case r.Method == http.MethodPost && strings.HasPrefix(contentType, contentTypeGRPCWeb):
+		return ProtoGRPCWeb, true
+	case r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") == http.MethodPost &&
+		headerContains(r.Header.Get("Access-Control-Request-Headers"), "x-grpc-web"):
+		return ProtoGRPCWeb, true
+	default:
+		return "", false
+	}
+}
+
+// preflight answers the CORS preflight of a gRPC-Web request.
+func (g *GRPCWeb) preflight(c *gin.Context) {
+	requested := c.Request.Header.Get("Access-Control-Request-Headers")
+	if !g.isAllowedOrigin(c.Request) || !g.isAllowedHeaders(requested) {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+
+	c.Header("Access-Control-Allow-Origin", c.Request.Header.Get("Origin"))
+	c.Header("Access-Control-Allow-Methods", http.MethodPost)
+	c.Header("Access-Control-Allow-Headers", requested)
+	c.Header("Access-Control-Max-Age", "600")
+	c.Header("Vary", "Origin")
+	c.AbortWithStatus(http.StatusNoContent)
+}
+
+// serve translates the gRPC-Web request to a gRPC one, serves it by the gRPC server and translates the response
+// back to gRPC-Web.
+func (g *GRPCWeb) serve(w http.ResponseWriter, r *http.Request, text bool) {
+	req := r.Clone(r.Context())
+	req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2"
+	req.ContentLength = -1
+	req.Header.Del("Content-Length")
+
+	contentType := r.Header.Get("Content-Type")
+	if text {
+		req.Header.Set("Content-Type", contentTypeGRPC+strings.TrimPrefix(contentType, contentTypeGRPCWebText))
+		req.Body = io.NopCloser(base64.NewDecoder(base64.StdEncoding, r.Body))
+	} else {
+		req.Header.Set("Content-Type", contentTypeGRPC+strings.TrimPrefix(contentType, contentTypeGRPCWeb))
+	}
+
+	rw := newResponseWriter(w, text, r.Header.Get("Origin") != "")
+	g.server.ServeHTTP(rw, req)
+	rw.finish()
+}
+
+// endpoint returns the metrics label of the request path, which is the full method if it is registered.
+// Other paths share one label to keep the cardinality of the metrics bounded.
+func (g *GRPCWeb) endpoint(path string) string {
+	g.methodsOnce.Do(func() {
+		g.methods = map[string]bool{}
+
+		for service, info := range g.server.GetServiceInfo() {
+			for _, method := range info.Methods {
+				g.methods["/"+service+"/"+method.Name] = true
+			}
+		}
+	})
+
+	if g.methods[path] {
+		return path
+	}
+
+	return UnknownEndpoint
+}
+
+// isAllowedOrigin reports whether the request is same-origin or comes from an allowed origin.
+func (g *GRPCWeb) isAllowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || g.allowAllOrigins || g.allowedOrigins[strings.ToLower(origin)] {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// isAllowedHeaders reports whether all headers of the comma separated list are allowed.
+func (g *GRPCWeb) isAllowedHeaders(headers string) bool {
+	if g.allowAllHeaders {
+		return true
+	}
+
+	for header := range strings.SplitSeq(headers, ",") {
+		header = strings.ToLower(strings.TrimSpace(header))
+		if header != "" && !g.allowedHeaders[header] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func headerContains(headers, header string) bool {
+	for h := range strings.SplitSeq(headers, ",") {
+		if strings.EqualFold(strings.TrimSpace(h), header) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,362 @@
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/http/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const checkMethod = "/grpc.health.v1.Health/Check"
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newRouter returns a router serving gRPC-Web requests by the health service; labels collects the metrics labels
+// of the requests.
+func newRouter(t *testing.T, labels *[]string, opts ...Option) *gin.Engine {
+	t.Helper()
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("users", healthpb.HealthCheckResponse_SERVING)
+
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	g, err := New(grpcServer, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Next()
+
+		label := c.GetString(middleware.MetricsProtoKey) + " " + c.GetString(middleware.MetricsEndpointKey)
+		*labels = append(*labels, label)
+	})
+	router.Use(g.Middleware())
+	router.GET("/native", func(c *gin.Context) { c.String(http.StatusOK, "native") })
+
+	return router
+}
+
+func request(t *testing.T, service string) []byte {
+	t.Helper()
+
+	payload, err := proto.Marshal(&healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := make([]byte, 5, 5+len(payload))
+	binary.BigEndian.PutUint32(b[1:], uint32(len(payload))) //nolint: gosec
+
+	return append(b, payload...)
+}
+
+// frames splits the gRPC-Web response body into the message and trailer frames.
+func frames(t *testing.T, body []byte) (messages [][]byte, trailer string) {
+	t.Helper()
+
+	for len(body) > 0 {
+		if len(body) < 5 {
+			t.Fatalf("truncated frame header %q", body)
+		}
+
+		size := int(binary.BigEndian.Uint32(body[1:5]))
+		if len(body) < 5+size {
+			t.Fatalf("truncated frame %q", body)
+		}
+
+		if body[0] == trailerFrame {
+			trailer = string(body[5 : 5+size])
+		} else {
+			messages = append(messages, body[5:5+size])
+		}
+
+		body = body[5+size:]
+	}
+
+	return
+}
+
+// decodeText decodes the gRPC-Web-text body, which consists of separately padded base64 chunks.
+func decodeText(t *testing.T, body string) []byte {
+	t.Helper()
+
+	var decoded []byte
+
+	for len(body) > 0 {
+		end := 0
+		for end < len(body) {
+			end += 4
+			if strings.HasSuffix(body[:min(end, len(body))], "=") {
+				break
+			}
+		}
+
+		b, err := base64.StdEncoding.DecodeString(body[:min(end, len(body))])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded = append(decoded, b...)
+		body = body[min(end, len(body)):]
+	}
+
+	return decoded
+}
+
+func TestGRPCWeb_Middleware(t *testing.T) {
+	tests := []struct {
+		name            string
+		contentType     string
+		service         string
+		wantContentType string
+		wantStatus      healthpb.HealthCheckResponse_ServingStatus
+		wantTrailer     string
+		wantLabel       string
+	}{
+		{
+			name:            "grpc-web",
+			contentType:     "application/grpc-web+proto",
+			service:         "users",
+			wantContentType: "application/grpc-web+proto",
+			wantStatus:      healthpb.HealthCheckResponse_SERVING,
+			wantTrailer:     "grpc-status: 0\r\n",
+			wantLabel:       ProtoGRPCWeb + " " + checkMethod,
+		},
+		{
+			name:            "grpc-web-text",
+			contentType:     "application/grpc-web-text",
+			service:         "users",
+			wantContentType: "application/grpc-web-text",
+			wantStatus:      healthpb.HealthCheckResponse_SERVING,
+			wantTrailer:     "grpc-status: 0\r\n",
+			wantLabel:       ProtoGRPCWebText + " " + checkMethod,
+		},
+		{
+			name:            "error",
+			contentType:     "application/grpc-web+proto",
+			service:         "orders",
+			wantContentType: "application/grpc-web+proto",
+			wantTrailer:     "grpc-status: 5\r\n",
+			wantLabel:       ProtoGRPCWeb + " " + checkMethod,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var labels []string
+
+			body := request(t, tt.service)
+			if strings.HasPrefix(tt.contentType, contentTypeGRPCWebText) {
+				body = []byte(base64.StdEncoding.EncodeToString(body))
+			}
+
+			req := httptest.NewRequest(http.MethodPost, checkMethod, bytes.NewReader(body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			w := httptest.NewRecorder()
+			newRouter(t, &labels).ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("content type = %q, want %q", got, tt.wantContentType)
+			}
+
+			body = w.Body.Bytes()
+			if strings.HasPrefix(tt.contentType, contentTypeGRPCWebText) {
+				body = decodeText(t, w.Body.String())
+			}
+
+			messages, trailer := frames(t, body)
+			if !strings.Contains(trailer, tt.wantTrailer) {
+				t.Errorf("trailer = %q, want %q", trailer, tt.wantTrailer)
+			}
+
+			var status healthpb.HealthCheckResponse_ServingStatus
+
+			if len(messages) > 0 {
+				resp := &healthpb.HealthCheckResponse{}
+
+				err := proto.Unmarshal(messages[0], resp)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				status = resp.GetStatus()
+			}
+
+			if status != tt.wantStatus {
+				t.Errorf("serving status = %v, want %v", status, tt.wantStatus)
+			}
+
+			if len(labels) != 1 || labels[0] != tt.wantLabel {
+				t.Errorf("labels = %q, want [%q]", labels, tt.wantLabel)
+			}
+		})
+	}
+}
+
+func TestGRPCWeb_MiddlewareNative(t *testing.T) {
+	var labels []string
+
+	w := httptest.NewRecorder()
+	newRouter(t, &labels).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/native", nil))
+
+	if w.Code != http.StatusOK || w.Body.String() != "native" {
+		t.Errorf("response = %d %q, want %d %q", w.Code, w.Body.String(), http.StatusOK, "native")
+	}
+
+	if len(labels) != 1 || labels[0] != " " {
+		t.Errorf("labels = %q, want no gRPC-Web labels", labels)
+	}
+}
+
+func TestGRPCWeb_MiddlewareEndpoint(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: checkMethod, want: checkMethod},
+		{path: "/grpc.health.v1.Health/Unknown", want: UnknownEndpoint},
+		{path: "/random/path/1234", want: UnknownEndpoint},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var labels []string
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(request(t, "")))
+			req.Header.Set("Content-Type", "application/grpc-web+proto")
+
+			newRouter(t, &labels).ServeHTTP(httptest.NewRecorder(), req)
+
+			want := ProtoGRPCWeb + " " + tt.want
+			if len(labels) != 1 || labels[0] != want {
+				t.Errorf("labels = %q, want [%q]", labels, want)
+			}
+		})
+	}
+}
+
+func TestGRPCWeb_MiddlewareCORS(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		method  string
+		origin  string
+		headers string
+		want    int
+	}{
+		{
+			name:   "denied by default",
+			method: http.MethodOptions,
+			origin: "https://player.example.com",
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "allowed origin",
+			opts:   []Option{WithAllowedOrigins("https://player.example.com")},
+			method: http.MethodOptions,
+			origin: "https://player.example.com",
+			want:   http.StatusNoContent,
+		},
+		{
+			name:   "other origin",
+			opts:   []Option{WithAllowedOrigins("https://player.example.com")},
+			method: http.MethodOptions,
+			origin: "https://evil.example.com",
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "all origins",
+			opts:   []Option{WithAllowedOrigins("*")},
+			method: http.MethodOptions,
+			origin: "https://any.example.com",
+			want:   http.StatusNoContent,
+		},
+		{name: "same origin", method: http.MethodOptions, origin: "http://example.com", want: http.StatusNoContent},
+		{
+			name:    "denied header",
+			opts:    []Option{WithAllowedOrigins("*"), WithAllowedHeaders("authorization")},
+			method:  http.MethodOptions,
+			origin:  "https://any.example.com",
+			headers: "x-tenant",
+			want:    http.StatusForbidden,
+		},
+		{
+			name:    "allowed header",
+			opts:    []Option{WithAllowedOrigins("*"), WithAllowedHeaders("authorization")},
+			method:  http.MethodOptions,
+			origin:  "https://any.example.com",
+			headers: "authorization",
+			want:    http.StatusNoContent,
+		},
+		{
+			name:   "request denied",
+			method: http.MethodPost,
+			origin: "https://evil.example.com",
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "request allowed",
+			opts:   []Option{WithAllowedOrigins("https://player.example.com")},
+			method: http.MethodPost,
+			origin: "https://player.example.com",
+			want:   http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var labels []string
+
+			req := httptest.NewRequest(tt.method, checkMethod, bytes.NewReader(request(t, "users")))
+			req.Header.Set("Origin", tt.origin)
+
+			if tt.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+				req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web,"+tt.headers)
+			} else {
+				req.Header.Set("Content-Type", "application/grpc-web+proto")
+			}
+
+			w := httptest.NewRecorder()
+			newRouter(t, &labels, tt.opts...).ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+
+			wantOrigin := ""
+			if tt.want != http.StatusForbidden {
+				wantOrigin = tt.origin
+			}
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != wantOrigin {
+				t.Errorf("allowed origin = %q, want %q", got, wantOrigin)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(nil)
+	if err == nil {
+		t.Error("New() without server succeeded")
+	}
+}
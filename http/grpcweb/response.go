@@ -0,0 +1,138 @@
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/http2"
+)
+
+// trailerFrame flags the gRPC-Web frame carrying the trailers in the response body.
+const trailerFrame byte = 0x80
+
+// responseWriter translates the response of the gRPC server to gRPC-Web. Headers are sent when the body is first
+// written or flushed and trailers, sent by the gRPC server as HTTP/2 trailers, are written as the last frame
+// of the body by finish.
+type responseWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+	text   bool
+	cors   bool
+
+	wroteHeader bool
+}
+
+func newResponseWriter(w http.ResponseWriter, text, cors bool) *responseWriter {
+	return &responseWriter{
+		w:      w,
+		header: http.Header{},
+		text:   text,
+		cors:   cors,
+	}
+}
+
+func (rw *responseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.wroteHeader {
+		return
+	}
+
+	rw.wroteHeader = true
+
+	header := rw.w.Header()
+	exposed := slices.Clone(exposedHeaders)
+
+	for k, v := range rw.header {
+		if k == "Trailer" || strings.HasPrefix(k, http2.TrailerPrefix) {
+			continue
+		}
+
+		if k == "Content-Type" {
+			v = []string{rw.contentType(v[0])}
+		}
+
+		header[k] = v
+		exposed = append(exposed, strings.ToLower(k))
+	}
+
+	if rw.cors {
+		header.Set("Access-Control-Expose-Headers", strings.Join(exposed, ", "))
+	}
+
+	rw.w.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.WriteHeader(http.StatusOK)
+
+	if !rw.text {
+		return rw.w.Write(b)
+	}
+
+	// every write is encoded separately, gRPC-Web clients decode padded chunks
+	_, err := rw.w.Write([]byte(base64.StdEncoding.EncodeToString(b)))
+	if err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+func (rw *responseWriter) Flush() {
+	rw.WriteHeader(http.StatusOK)
+
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish writes the trailers set by the gRPC server as the trailer frame.
+func (rw *responseWriter) finish() {
+	trailer := http.Header{}
+
+	for k, v := range rw.header {
+		if name, ok := strings.CutPrefix(k, http2.TrailerPrefix); ok {
+			trailer[http.CanonicalHeaderKey(name)] = v
+		}
+	}
+
+	for _, declared := range rw.header.Values("Trailer") {
+		for k := range strings.SplitSeq(declared, ",") {
+			k = http.CanonicalHeaderKey(strings.TrimSpace(k))
+			if v, ok := rw.header[k]; ok {
+				trailer[k] = v
+			}
+		}
+	}
+
+	var body bytes.Buffer
+
+	for k, v := range trailer {
+		for _, value := range v {
+			body.WriteString(strings.ToLower(k) + ": " + value + "\r\n")
+		}
+	}
+
+	frame := make([]byte, 5, 5+body.Len())
+	frame[0] = trailerFrame
+	binary.BigEndian.PutUint32(frame[1:], uint32(body.Len())) //nolint: gosec
+
+	_, _ = rw.Write(append(frame, body.Bytes()...))
+	rw.Flush()
+}
+
+// contentType returns the gRPC-Web content type of the gRPC content type.
+func (rw *responseWriter) contentType(contentType string) string {
+	if rw.text {
+		return contentTypeGRPCWebText + strings.TrimPrefix(contentType, contentTypeGRPC)
+	}
+
+	return contentTypeGRPCWeb + strings.TrimPrefix(contentType, contentTypeGRPC)
+}
@@ -6,7 +6,10 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/requestid"
+	"github.com/moderntv/cadre/tracing"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func NewLogger(baseLogger zerolog.Logger, ignorePatterns []*regexp.Regexp) func(*gin.Context) {
@@ -25,9 +28,25 @@ func NewLogger(baseLogger zerolog.Logger, ignorePatterns []*regexp.Regexp) func(
 		}
 
 		latency := time.Since(start)
-		dumplogger := logger.With().
+		logCtx := logger.With()
+
+		requestID, ok := requestid.FromContext(c.Request.Context())
+		if ok {
+			logCtx = logCtx.Str(requestid.LogField, requestID)
+		}
+
+		// correlate with traces - span context is set by the tracing middleware
+		sc := trace.SpanContextFromContext(c.Request.Context())
+		if sc.IsValid() {
+			logCtx = logCtx.
+				Str(tracing.TraceIDField, sc.TraceID().String()).
+				Str(tracing.SpanIDField, sc.SpanID().String())
+		}
+
+		dumplogger := logCtx.
 			Str("method", c.Request.Method).
 			Str("path", path).
+			Str("proto", c.Request.Proto).
 			Dur("latency", latency).
 			// keep this for log aggregation, where number is better than user-readable string
 			Str("latency_str", latency.String()). // user-readable latency
@@ -6,15 +6,58 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/http/route"
 	"github.com/moderntv/cadre/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+const (
+	// MetricsProtoKey is the gin context key overriding the `proto` label of the request metrics (see WithProtoLabel),
+	// e.g. to distinguish requests tunneling other protocols such as gRPC-Web.
+	MetricsProtoKey = "cadre.metrics.proto"
+	// MetricsEndpointKey is the gin context key overriding the aggregated `endpoint` label
+	// of requests not matched by any route.
+	MetricsEndpointKey = "cadre.metrics.endpoint"
+)
+
+type metricsOptions struct {
+	latencyHistogram metrics.HistogramConfig
+	protoLabel       bool
+}
+
+type MetricsOption func(*metricsOptions)
+
+// WithLatencyHistogram configures buckets of the request duration histogram.
+func WithLatencyHistogram(config metrics.HistogramConfig) MetricsOption {
+	return func(o *metricsOptions) {
+		o.latencyHistogram = config
+	}
+}
+
+// WithProtoLabel adds the `proto` label with the protocol of the request (e.g. HTTP/1.1, HTTP/3.0) to the request metrics.
+// It is opt-in as it changes the label sets of the existing series.
+func WithProtoLabel() MetricsOption {
+	return func(o *metricsOptions) {
+		o.protoLabel = true
+	}
+}
+
 func NewMetrics(
 	r *metrics.Registry,
 	subsystem string,
 	metricsAggregation bool,
+	opts ...MetricsOption,
 ) (handler func(*gin.Context), err error) {
+	options := &metricsOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	labels := []string{"endpoint", "status"}
+	if options.protoLabel {
+		labels = append(labels, "proto")
+	}
+
 	requestsDuration, err := r.RegisterNewSummaryVec(
 		fmt.Sprintf("http_%v_request_duration_us", subsystem),
 		prometheus.SummaryOpts{
@@ -24,7 +67,24 @@ func NewMetrics(
 			Help:       "The response time of requests",
 			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
 		},
-		[]string{"endpoint", "status"},
+		labels,
+	)
+	if err != nil {
+		return
+	}
+
+	histogramOpts := prometheus.HistogramOpts{
+		Subsystem: subsystem,
+
+		Name: "request_duration_seconds",
+		Help: "The response time of requests",
+	}
+	options.latencyHistogram.Apply(&histogramOpts)
+
+	requestsDurationHistogram, err := r.RegisterNewHistogramVec(
+		fmt.Sprintf("http_%v_request_duration_seconds", subsystem),
+		histogramOpts,
+		labels,
 	)
 	if err != nil {
 		return
@@ -37,7 +97,7 @@ func NewMetrics(
 			Name: "requests_total",
 			Help: "HTTP requests count",
 		},
-		[]string{"endpoint", "status"},
+		labels,
 	)
 	if err != nil {
 		return
@@ -52,12 +112,29 @@ func NewMetrics(
 
 		path := c.Request.URL.Path
 		if metricsAggregation {
-			path = c.FullPath()
+			path = route.FromContext(c)
+			if path == "" {
+				path = c.GetString(MetricsEndpointKey)
+			}
+		}
+
+		values := []string{path, strconv.Itoa(c.Writer.Status())}
+		if options.protoLabel {
+			proto := c.GetString(MetricsProtoKey)
+			if proto == "" {
+				proto = c.Request.Proto
+			}
+
+			values = append(values, proto)
 		}
 
-		status := strconv.Itoa(c.Writer.Status())
-		requestsCount.WithLabelValues(path, status).Inc()
-		requestsDuration.WithLabelValues(path, status).Observe(float64(d.Microseconds()))
+		requestsCount.WithLabelValues(values...).Inc()
+		requestsDuration.WithLabelValues(values...).Observe(float64(d.Microseconds()))
+		metrics.ObserveWithTraceExemplar(
+			c.Request.Context(),
+			requestsDurationHistogram.WithLabelValues(values...),
+			d.Seconds(),
+		)
 	}
 
 	return
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/metrics"
+)
+
+func gatherLabels(t *testing.T, registry *metrics.Registry, name string) map[string]string {
+	t.Helper()
+
+	families, err := registry.GetPrometheusRegistry().Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, family := range families {
+		if family.GetName() == name {
+			if len(family.GetMetric()) != 1 {
+				t.Fatalf("%s has %d metrics, want 1", name, len(family.GetMetric()))
+			}
+
+			labels := map[string]string{}
+			for _, label := range family.GetMetric()[0].GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+
+			return labels
+		}
+	}
+
+	t.Fatalf("metric %s not found", name)
+
+	return nil
+}
+
+func TestNewMetrics(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []MetricsOption
+		proto  string
+		labels map[string]string
+	}{
+		{
+			name:   "default labels",
+			labels: map[string]string{"endpoint": "/users/:id", "status": "200"},
+		},
+		{
+			name:   "proto label",
+			opts:   []MetricsOption{WithProtoLabel()},
+			labels: map[string]string{"endpoint": "/users/:id", "status": "200", "proto": "HTTP/1.1"},
+		},
+		{
+			name:   "overridden proto label",
+			opts:   []MetricsOption{WithProtoLabel()},
+			proto:  "grpc-web",
+			labels: map[string]string{"endpoint": "/users/:id", "status": "200", "proto": "grpc-web"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, err := metrics.NewRegistry("test", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			handler, err := NewMetrics(registry, "api", true, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if tt.proto != "" {
+					c.Set(MetricsProtoKey, tt.proto)
+				}
+			}, handler)
+			router.GET("/users/:id", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+
+			for _, name := range []string{
+				"test_api_requests_total",
+				"test_api_request_duration_us",
+				"test_api_request_duration_seconds",
+			} {
+				if got := gatherLabels(t, registry, name); !maps.Equal(got, tt.labels) {
+					t.Errorf("%s labels = %v, want %v", name, got, tt.labels)
+				}
+			}
+		})
+	}
+}
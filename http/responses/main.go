@@ -45,6 +45,11 @@ func FromError(c *gin.Context, err error) {
 		return
 	}
 
+	if errors.Is(err, cerr.ErrUnauthenticated) {
+		Unauthorized(c, NewError(err))
+		return
+	}
+
 	if errors.Is(err, cerr.ErrNotAllowed) {
 		Forbidden(c, NewError(err))
 		return
@@ -152,6 +157,14 @@ func Conflict(c *gin.Context, errors ...Error) {
 	})
 }
 
+// TooManyRequests sets the HTTP response status to 429.
+func TooManyRequests(c *gin.Context, errors ...Error) {
+	c.AbortWithStatusJSON(429, ErrorResponse{
+		Message: "Too many requests. Slow down",
+		Errors:  errors,
+	})
+}
+
 // InternalError sets the HTTP response status to 500.
 func InternalError(c *gin.Context, errors ...Error) {
 	c.AbortWithStatusJSON(500, ErrorResponse{
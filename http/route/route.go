@@ -0,0 +1,31 @@
+// Package route resolves the route pattern HTTP middleware match their policies against.
+//
+// The route is the registered gin route (e.g. `/users/:id`). Requests served outside of gin routes, such as
+// grpc-gateway handlers, carry the pattern of the handler serving them set by Set.
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Key is the gin context key of the route of requests not matched by any gin route.
+const Key = "cadre.route"
+
+// Set sets the route of the request not matched by any gin route.
+func Set(c *gin.Context, pattern string) {
+	c.Set(Key, pattern)
+}
+
+// FromContext returns the route of the request or an empty string if the request matched no route.
+func FromContext(c *gin.Context) string {
+	if path := c.FullPath(); path != "" {
+		return path
+	}
+
+	return c.GetString(Key)
+}
+
+// Method returns the method and the route of the request as `METHOD /route`.
+func Method(c *gin.Context) string {
+	return c.Request.Method + " " + FromContext(c)
+}
@@ -59,6 +59,16 @@ func (server *HttpServer) RegisterGlobalMiddleware(handlers ...gin.HandlerFunc)
 	return nil
 }
 
+// SetNoRoute replaces handlers of requests not matched by any route. Global middleware still applies.
+func (server *HttpServer) SetNoRoute(handlers ...gin.HandlerFunc) {
+	server.router.NoRoute(handlers...)
+}
+
+// SetTrustedProxies configures networks of proxies whose forwarding headers are used to resolve the client IP.
+func (server *HttpServer) SetTrustedProxies(trustedProxies []string) error {
+	return server.router.SetTrustedProxies(trustedProxies)
+}
+
 func (server *HttpServer) RegisterRoute(path, method string, handlers ...gin.HandlerFunc) error {
 	server.router.Handle(method, path, handlers...)
 
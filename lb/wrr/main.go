@@ -0,0 +1,86 @@
+// Package wrr registers the `wrr` load balancing policy weighting instances by their ORCA load reports.
+//
+// The policy wraps grpc's weighted_round_robin and accepts the same configuration, but enables out-of-band load
+// reports by default, as cadre servers report them when built with WithORCA (see package grpc/grpcorca).
+// The weight of an instance is its QPS divided by its application (or CPU) utilization, so instances with more
+// capacity receive proportionally more calls. Instances without reports are weighted by the mean weight.
+//
+// The package has to be imported to register the policy:
+//
+//	import _ "github.com/moderntv/cadre/lb/wrr"
+//
+//	conn, err := client.NewConn("users", client.WithLoadBalancing(client.WeightedRoundRobin))
+package wrr
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/weightedroundrobin"
+	"google.golang.org/grpc/serviceconfig"
+)
+
+const Name = "wrr"
+
+func init() {
+	b, err := NewBuilder()
+	if err != nil {
+		panic(err)
+	}
+
+	balancer.Register(b)
+}
+
+// builder builds grpc's weighted_round_robin balancers under another name.
+type builder struct {
+	balancer.Builder
+
+	parser balancer.ConfigParser
+	name   string
+}
+
+func NewBuilder() (balancer.Builder, error) {
+	return NewNamedBuilder(Name)
+}
+
+func NewNamedBuilder(name string) (balancer.Builder, error) {
+	wrr := balancer.Get(weightedroundrobin.Name)
+
+	parser, ok := wrr.(balancer.ConfigParser)
+	if !ok {
+		return nil, fmt.Errorf("balancer `%s` is not registered", weightedroundrobin.Name)
+	}
+
+	return &builder{
+		Builder: wrr,
+		parser:  parser,
+		name:    name,
+	}, nil
+}
+
+func (b *builder) Name() string {
+	return b.name
+}
+
+// ParseConfig enables out-of-band load reports unless the config disables them.
+func (b *builder) ParseConfig(js json.RawMessage) (serviceconfig.LoadBalancingConfig, error) {
+	config := map[string]any{}
+	if len(js) > 0 {
+		err := json.Unmarshal(js, &config)
+		if err != nil {
+			return nil, fmt.Errorf("wrr: cannot unmarshal config `%s`: %w", string(js), err)
+		}
+	}
+
+	if _, ok := config["enableOobLoadReport"]; !ok {
+		config["enableOobLoadReport"] = true
+	}
+
+	js, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("wrr: cannot marshal config: %w", err)
+	}
+
+	return b.parser.ParseConfig(js)
+}
@@ -0,0 +1,141 @@
+package wrr
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/moderntv/cadre/grpc/grpcorca"
+	"github.com/moderntv/cadre/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+func TestParseConfig(t *testing.T) {
+	parser, ok := balancer.Get(Name).(balancer.ConfigParser)
+	if !ok {
+		t.Fatal("wrr balancer does not parse configs")
+	}
+
+	tests := []struct {
+		name    string
+		config  string
+		wantOOB bool
+		wantErr bool
+	}{
+		{name: "defaults", config: `{}`, wantOOB: true},
+		{name: "empty", config: ``, wantOOB: true},
+		{name: "disabled", config: `{"enableOobLoadReport":false}`, wantOOB: false},
+		{name: "invalid", config: `{"errorUtilizationPenalty":-1}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := parser.ParseConfig(json.RawMessage(tt.config))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			b, err := json.Marshal(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var parsed struct {
+				EnableOOBLoadReport bool `json:"enableOobLoadReport"`
+			}
+
+			err = json.Unmarshal(b, &parsed)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if parsed.EnableOOBLoadReport != tt.wantOOB {
+				t.Errorf("enableOobLoadReport = %v, want %v", parsed.EnableOOBLoadReport, tt.wantOOB)
+			}
+		})
+	}
+}
+
+func startServer(t *testing.T) string {
+	t.Helper()
+
+	registry, err := metrics.NewRegistry("test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reporter, err := grpcorca.New(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer(reporter.ServerOptions()...)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+
+	err = reporter.Register(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() { _ = server.Serve(lis) }()
+
+	t.Cleanup(server.Stop)
+
+	return lis.Addr().String()
+}
+
+func TestBalancing(t *testing.T) {
+	addrs := []string{startServer(t), startServer(t)}
+
+	r := manual.NewBuilderWithScheme("wrr-test")
+	r.InitialState(resolver.State{Endpoints: []resolver.Endpoint{
+		{Addresses: []resolver.Address{{Addr: addrs[0]}}},
+		{Addresses: []resolver.Address{{Addr: addrs[1]}}},
+	}})
+
+	conn, err := grpc.NewClient(
+		r.Scheme()+":///users",
+		grpc.WithResolvers(r),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(`{"loadBalancingConfig":[{"wrr":{}}]}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	client := healthpb.NewHealthClient(conn)
+	seen := map[string]bool{}
+
+	for range 20 {
+		var p peer.Peer
+
+		_, err = client.Check(t.Context(), &healthpb.HealthCheckRequest{}, grpc.Peer(&p))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		seen[p.Addr.String()] = true
+	}
+
+	if want := map[string]bool{addrs[0]: true, addrs[1]: true}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("called instances = %v, want %v", seen, want)
+	}
+}
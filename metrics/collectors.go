@@ -189,3 +189,42 @@ func (registry *Registry) RegisterOrGetNewGaugeVec(
 
 	return
 }
+
+// NewHistogramVec creates Prometheus HistogramVec.
+func (registry *Registry) NewHistogramVec(opts prometheus.HistogramOpts, labels []string) *prometheus.HistogramVec {
+	opts.Namespace = registry.namespace
+
+	return prometheus.NewHistogramVec(opts, labels)
+}
+
+func (registry *Registry) RegisterNewHistogramVec(
+	name string,
+	opts prometheus.HistogramOpts,
+	labels []string,
+) (c *prometheus.HistogramVec, err error) {
+	c = registry.NewHistogramVec(opts, labels)
+	err = registry.Register(name, c)
+
+	return
+}
+
+func (registry *Registry) RegisterOrGetNewHistogramVec(
+	name string,
+	opts prometheus.HistogramOpts,
+	labels []string,
+) (c *prometheus.HistogramVec, err error) {
+	c = registry.NewHistogramVec(opts, labels)
+
+	cReturned, err := registry.RegisterOrGet(name, c)
+	if err != nil {
+		return
+	}
+
+	c, ok := cReturned.(*prometheus.HistogramVec)
+	if !ok {
+		err = ErrInvalidType
+		return
+	}
+
+	return
+}
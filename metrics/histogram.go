@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceIDExemplarLabel is the exemplar label carrying the trace ID.
+const TraceIDExemplarLabel = "trace_id"
+
+// HistogramConfig configures buckets of latency histograms.
+type HistogramConfig struct {
+	// Buckets are upper bounds of classic buckets in seconds. Defaults to prometheus.DefBuckets.
+	Buckets []float64
+	// NativeBucketFactor enables Prometheus native histograms if greater than 1.
+	// It bounds the growth of consecutive bucket widths, e.g. 1.1 for at most 10%.
+	NativeBucketFactor float64
+	// NativeMaxBucketNumber limits the number of native buckets. Defaults to 160.
+	NativeMaxBucketNumber uint32
+}
+
+// Apply sets the buckets to the histogram options.
+func (c HistogramConfig) Apply(opts *prometheus.HistogramOpts) {
+	opts.Buckets = c.Buckets
+	if len(opts.Buckets) == 0 {
+		opts.Buckets = prometheus.DefBuckets
+	}
+
+	if c.NativeBucketFactor > 1 {
+		opts.NativeHistogramBucketFactor = c.NativeBucketFactor
+		opts.NativeHistogramMaxBucketNumber = c.NativeMaxBucketNumber
+		if opts.NativeHistogramMaxBucketNumber == 0 {
+			opts.NativeHistogramMaxBucketNumber = 160
+		}
+
+		opts.NativeHistogramMinResetDuration = time.Hour
+	}
+}
+
+// ObserveWithTraceExemplar observes the value and attaches the trace ID of the sampled span
+// found in the context as an exemplar.
+func ObserveWithTraceExemplar(ctx context.Context, observer prometheus.Observer, value float64) {
+	sc := trace.SpanContextFromContext(ctx)
+
+	exemplarObserver, ok := observer.(prometheus.ExemplarObserver)
+	if !ok || !sc.IsSampled() {
+		observer.Observe(value)
+		return
+	}
+
+	exemplarObserver.ObserveWithExemplar(value, prometheus.Labels{TraceIDExemplarLabel: sc.TraceID().String()})
+}
@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// ForwardedForHeader is the metadata key proxies use to pass the chain of client addresses.
+const ForwardedForHeader = "x-forwarded-for"
+
+// UnaryServerInterceptor replaces the peer address of calls coming from trusted proxies
+// with the client address found in the x-forwarded-for metadata.
+func UnaryServerInterceptor(trusted TrustedProxies) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(PeerContext(ctx, trusted), req)
+	}
+}
+
+// StreamServerInterceptor replaces the peer address of streams coming from trusted proxies
+// with the client address found in the x-forwarded-for metadata.
+func StreamServerInterceptor(trusted TrustedProxies) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{
+			ServerStream: ss,
+			ctx:          PeerContext(ss.Context(), trusted),
+		})
+	}
+}
+
+// PeerContext returns a context with the peer address resolved through trusted proxies.
+// The rightmost address in x-forwarded-for which is not a trusted proxy is considered to be the client.
+// If the direct peer is not trusted, the context is returned unchanged.
+func PeerContext(ctx context.Context, trusted TrustedProxies) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil || !trusted.ContainsAddr(p.Addr) {
+		return ctx
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	var hops []string
+	for _, v := range md.Get(ForwardedForHeader) {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return ctx
+		}
+
+		if i > 0 && trusted.Contains(addr) {
+			continue
+		}
+
+		realPeer := *p
+		realPeer.Addr = net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, 0))
+
+		return peer.NewContext(ctx, &realPeer)
+	}
+
+	return ctx
+}
+
+type serverStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
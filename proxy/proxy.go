@@ -0,0 +1,92 @@
+// Package proxy provides support for services running behind load balancers and reverse proxies.
+// It allows parsing PROXY protocol (v1/v2) headers on listeners and resolving the real client address
+// of gRPC calls forwarded by trusted proxies.
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/pires/go-proxyproto"
+)
+
+// TrustedProxies is a list of networks whose connections are allowed to carry the original client address.
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies parses CIDRs or plain IP addresses into TrustedProxies.
+func ParseTrustedProxies(cidrs ...string) (tp TrustedProxies, err error) {
+	tp = make(TrustedProxies, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		var prefix netip.Prefix
+
+		if strings.Contains(cidr, "/") {
+			prefix, err = netip.ParsePrefix(cidr)
+		} else {
+			var addr netip.Addr
+
+			addr, err = netip.ParseAddr(cidr)
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+
+		if err != nil {
+			err = fmt.Errorf("invalid trusted proxy `%s`: %w", cidr, err)
+			return
+		}
+
+		tp = append(tp, prefix.Masked())
+	}
+
+	return
+}
+
+// Contains reports whether addr belongs to one of the trusted networks.
+func (tp TrustedProxies) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range tp {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ContainsAddr reports whether the IP of a network address belongs to one of the trusted networks.
+func (tp TrustedProxies) ContainsAddr(addr net.Addr) bool {
+	addrPort, err := netip.ParseAddrPort(addr.String())
+	if err != nil {
+		return false
+	}
+
+	return tp.Contains(addrPort.Addr())
+}
+
+// Strings returns the trusted networks in CIDR notation.
+func (tp TrustedProxies) Strings() []string {
+	s := make([]string, len(tp))
+	for i, prefix := range tp {
+		s[i] = prefix.String()
+	}
+
+	return s
+}
+
+// NewListener wraps the listener so that PROXY protocol headers are parsed on accepted connections
+// and the connection's remote address is replaced with the original client address.
+// Headers are only honoured from the trusted proxies and ignored from anyone else, so without trusted proxies
+// no header is honoured.
+func NewListener(l net.Listener, trusted TrustedProxies) net.Listener {
+	return &proxyproto.Listener{
+		Listener: l,
+		Policy: func(upstream net.Addr) (proxyproto.Policy, error) {
+			if trusted.ContainsAddr(upstream) {
+				return proxyproto.USE, nil
+			}
+
+			return proxyproto.IGNORE, nil
+		},
+	}
+}
@@ -0,0 +1,170 @@
+package proxy
+
+import (
+	"io"
+	"net"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	tp, err := ParseTrustedProxies("10.0.0.0/8", "192.168.1.1", "fd00::/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"10.0.0.0/8", "192.168.1.1/32", "fd00::/8"}
+	if got := tp.Strings(); !slices.Equal(got, want) {
+		t.Errorf("Strings() = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "10.1.2.3", want: true},
+		{addr: "::ffff:10.1.2.3", want: true},
+		{addr: "192.168.1.1", want: true},
+		{addr: "192.168.1.2", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := tp.Contains(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	_, err = ParseTrustedProxies("not-an-ip")
+	if err == nil {
+		t.Error("ParseTrustedProxies() of invalid address succeeded")
+	}
+}
+
+func TestPeerContext(t *testing.T) {
+	trusted, err := ParseTrustedProxies("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		peerAddr     string
+		forwardedFor []string
+		want         string
+	}{
+		{
+			name:         "untrusted peer",
+			peerAddr:     "1.2.3.4:5000",
+			forwardedFor: []string{"5.6.7.8"},
+			want:         "1.2.3.4:5000",
+		},
+		{
+			name:     "trusted peer without metadata",
+			peerAddr: "10.0.0.1:5000",
+			want:     "10.0.0.1:5000",
+		},
+		{
+			name:         "trusted peer",
+			peerAddr:     "10.0.0.1:5000",
+			forwardedFor: []string{"5.6.7.8"},
+			want:         "5.6.7.8:0",
+		},
+		{
+			name:         "chain of trusted proxies",
+			peerAddr:     "10.0.0.1:5000",
+			forwardedFor: []string{"9.9.9.9, 5.6.7.8", "10.0.0.2"},
+			want:         "5.6.7.8:0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peer.NewContext(t.Context(), &peer.Peer{
+				Addr: net.TCPAddrFromAddrPort(netip.MustParseAddrPort(tt.peerAddr)),
+			})
+			if len(tt.forwardedFor) > 0 {
+				ctx = metadata.NewIncomingContext(ctx, metadata.MD{ForwardedForHeader: tt.forwardedFor})
+			}
+
+			p, ok := peer.FromContext(PeerContext(ctx, trusted))
+			if !ok || p.Addr.String() != tt.want {
+				t.Errorf("peer = %v, want %v", p, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewListener(t *testing.T) {
+	tests := []struct {
+		name    string
+		trusted []string
+		want    string
+	}{
+		{
+			name:    "trusted peer",
+			trusted: []string{"127.0.0.0/8"},
+			want:    "5.6.7.8",
+		},
+		{
+			name:    "untrusted peer",
+			trusted: []string{"10.0.0.0/8"},
+			want:    "127.0.0.1",
+		},
+		{
+			name: "no trusted proxies",
+			want: "127.0.0.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trusted, err := ParseTrustedProxies(tt.trusted...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l = NewListener(l, trusted)
+			defer l.Close()
+
+			client, err := net.Dial("tcp", l.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer client.Close()
+
+			_, err = client.Write([]byte("PROXY TCP4 5.6.7.8 127.0.0.1 1000 2000\r\nping"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			conn, err := l.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer conn.Close()
+
+			// the header is consumed even when ignored
+			b := make([]byte, 4)
+
+			_, err = io.ReadFull(conn, b)
+			if err != nil || string(b) != "ping" {
+				t.Errorf("read %q (%v), want %q", b, err, "ping")
+			}
+
+			host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+			if host != tt.want {
+				t.Errorf("remote host = %s, want %s", host, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/moderntv/cadre/config"
+)
+
+// WatchConfig loads policies from the configuration manager and reloads them on its changes (see config.Watch).
+// The previous policies are kept when a reload fails.
+func (l *Limiter) WatchConfig(ctx context.Context, m *config.Manager, onError func(error)) error {
+	return config.Watch(ctx, m, func() error { return l.loadConfig(m) }, onError)
+}
+
+func (l *Limiter) loadConfig(m *config.Manager) (err error) {
+	cfg := &Config{}
+
+	err = m.Load(cfg)
+	if err != nil {
+		err = fmt.Errorf("cannot load rate limit config: %w", err)
+		return
+	}
+
+	err = cfg.PostLoad()
+	if err != nil {
+		return
+	}
+
+	return l.Reload(cfg)
+}
@@ -0,0 +1,36 @@
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/http/responses"
+	"github.com/moderntv/cadre/http/route"
+)
+
+const transportHTTP = "http"
+
+// Middleware returns gin middleware rejecting requests exceeding their limits with 429 Too Many Requests.
+// Requests are matched by `METHOD /route` where route is the registered gin route (e.g. `GET /users/:id`)
+// or the pattern of the grpc-gateway handler (see package http/route).
+func (l *Limiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, retryAfter := l.Allow(Request{
+			Ctx:       c.Request.Context(),
+			Transport: transportHTTP,
+			Route:     route.Method(c),
+			ClientIP:  c.ClientIP(),
+		})
+		if ok {
+			c.Next()
+			return
+		}
+
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		responses.TooManyRequests(c, responses.Error{
+			Type:    "RATE_LIMITED",
+			Message: "Rate limit exceeded",
+		})
+	}
+}
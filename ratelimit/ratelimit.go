@@ -0,0 +1,328 @@
+// Package ratelimit provides token bucket rate limiting for gRPC calls and HTTP requests.
+//
+// Limits are described by policies matched against the gRPC full method (`/package.Service/Method`)
+// or the HTTP method and route (`GET /users/:id`) using path.Match patterns. Every policy keeps
+// a separate bucket per key - client IP, an authenticated subject or anything a custom KeyFunc returns.
+// Rejected gRPC calls fail with codes.ResourceExhausted, rejected HTTP requests with 429 and Retry-After.
+//
+// Use the limiter's interceptors and middleware with cadre's WithUnaryInterceptors,
+// WithStreamInterceptors and WithGlobalMiddleware.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/moderntv/cadre/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// KeyGlobal shares a single bucket among all callers.
+	KeyGlobal = "global"
+	// KeyIP keys buckets by the client IP.
+	KeyIP = "ip"
+
+	// idle buckets are removed after this time.
+	bucketTTL = 10 * time.Minute
+)
+
+var (
+	ErrInvalidPolicy  = errors.New("invalid rate limit policy")
+	ErrUnknownKeyFunc = errors.New("unknown rate limit key function")
+)
+
+// Request describes the limited call or request.
+type Request struct {
+	Ctx       context.Context
+	Transport string // grpc or http
+	Route     string // gRPC full method or `METHOD /route`
+	ClientIP  string
+}
+
+// KeyFunc returns the key whose bucket the request consumes tokens from.
+type KeyFunc func(Request) string
+
+// Policy describes a token bucket limit for routes matching the pattern.
+type Policy struct {
+	// Route is a path.Match pattern matched against the gRPC full method or `METHOD /route` of HTTP requests.
+	Route string `json:"route" yaml:"route"`
+	// Rate is the number of tokens added to the bucket per second.
+	Rate float64 `json:"rate" yaml:"rate"`
+	// Burst is the capacity of the bucket.
+	Burst int `json:"burst" yaml:"burst"`
+	// Key is the name of the key function (ip, global or registered using WithKeyFunc). Defaults to ip.
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+}
+
+func (p Policy) validate() error {
+	if p.Route == "" {
+		return fmt.Errorf("%w: empty route", ErrInvalidPolicy)
+	}
+
+	_, err := path.Match(p.Route, "")
+	if err != nil {
+		return fmt.Errorf("%w: route `%s`: %w", ErrInvalidPolicy, p.Route, err)
+	}
+
+	if p.Rate <= 0 || p.Burst <= 0 {
+		return fmt.Errorf("%w: route `%s`: rate and burst have to be positive", ErrInvalidPolicy, p.Route)
+	}
+
+	return nil
+}
+
+// Config is the reloadable rate limiting configuration.
+type Config struct {
+	Policies []Policy `json:"policies" yaml:"policies"`
+}
+
+func (c *Config) PostLoad() error {
+	for _, p := range c.Policies {
+		err := p.validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type Limiter struct {
+	mu       sync.Mutex
+	policies []Policy
+	buckets  map[bucketKey]*bucket
+	lastGC   time.Time
+
+	keyFuncs map[string]KeyFunc
+	now      func() time.Time
+
+	requests *prometheus.CounterVec
+}
+
+type bucketKey struct {
+	policy Policy
+	key    string
+}
+
+type Option func(*Limiter) error
+
+// NewLimiter creates a new rate limiter exporting allowed and rejected counts to the metrics registry.
+func NewLimiter(metricsRegistry *metrics.Registry, opts ...Option) (l *Limiter, err error) {
+	l = &Limiter{
+		buckets: map[bucketKey]*bucket{},
+		keyFuncs: map[string]KeyFunc{
+			KeyGlobal: func(Request) string { return "" },
+			KeyIP:     func(r Request) string { return r.ClientIP },
+		},
+		now: time.Now,
+	}
+
+	for _, opt := range opts {
+		err = opt(l)
+		if err != nil {
+			err = fmt.Errorf("cannot apply rate limiter option: %w", err)
+			return
+		}
+	}
+
+	err = l.SetPolicies(l.policies)
+	if err != nil {
+		return
+	}
+
+	l.requests, err = metricsRegistry.RegisterOrGetNewCounterVec(
+		"ratelimit_requests_total",
+		prometheus.CounterOpts{
+			Subsystem: "ratelimit",
+			Name:      "requests_total",
+			Help:      "Requests checked by the rate limiter",
+		},
+		[]string{"transport", "route", "result"},
+	)
+	if err != nil {
+		err = fmt.Errorf("cannot register rate limiter metrics: %w", err)
+		return
+	}
+
+	return
+}
+
+// WithPolicies configures the initial rate limiting policies.
+func WithPolicies(policies ...Policy) Option {
+	return func(l *Limiter) error {
+		l.policies = append(l.policies, policies...)
+
+		return nil
+	}
+}
+
+// WithKeyFunc registers a named key function policies can refer to, e.g. an authenticated subject.
+func WithKeyFunc(name string, keyFunc KeyFunc) Option {
+	return func(l *Limiter) error {
+		if name == "" || keyFunc == nil {
+			return errors.New("key function has to have a name")
+		}
+
+		l.keyFuncs[name] = keyFunc
+
+		return nil
+	}
+}
+
+// SetPolicies atomically replaces the rate limiting policies. Buckets of policies which did not change are kept.
+func (l *Limiter) SetPolicies(policies []Policy) error {
+	for _, p := range policies {
+		err := p.validate()
+		if err != nil {
+			return err
+		}
+
+		if _, ok := l.keyFuncs[keyName(p)]; !ok {
+			return fmt.Errorf("%w: `%s`", ErrUnknownKeyFunc, p.Key)
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	kept := map[Policy]bool{}
+	for _, p := range policies {
+		kept[p] = true
+	}
+
+	for k := range l.buckets {
+		if !kept[k.policy] {
+			delete(l.buckets, k)
+		}
+	}
+
+	l.policies = append([]Policy(nil), policies...)
+
+	return nil
+}
+
+// Reload replaces the rate limiting policies by the ones from the configuration.
+func (l *Limiter) Reload(cfg *Config) error {
+	return l.SetPolicies(cfg.Policies)
+}
+
+// Policies returns the currently applied policies.
+func (l *Limiter) Policies() []Policy {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]Policy(nil), l.policies...)
+}
+
+// Allow consumes a token for the request. If the request is limited, it returns the time after which it may be retried.
+func (l *Limiter) Allow(r Request) (ok bool, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var (
+		policy  Policy
+		matched bool
+	)
+
+	for _, p := range l.policies {
+		if matched, _ = path.Match(p.Route, r.Route); matched {
+			policy = p
+			break
+		}
+	}
+
+	if !matched {
+		return true, 0
+	}
+
+	now := l.now()
+
+	l.gc(now)
+
+	k := bucketKey{
+		policy: policy,
+		key:    l.keyFuncs[keyName(policy)](r),
+	}
+
+	b, found := l.buckets[k]
+	if !found {
+		b = newBucket(policy, now)
+		l.buckets[k] = b
+	}
+
+	ok, retryAfter = b.take(now)
+
+	result := "allowed"
+	if !ok {
+		result = "rejected"
+	}
+
+	l.requests.WithLabelValues(r.Transport, policy.Route, result).Inc()
+
+	return ok, retryAfter
+}
+
+// gc removes idle buckets. Has to be called with the lock held.
+func (l *Limiter) gc(now time.Time) {
+	if now.Sub(l.lastGC) < bucketTTL {
+		return
+	}
+
+	for k, b := range l.buckets {
+		if now.Sub(b.last) > bucketTTL {
+			delete(l.buckets, k)
+		}
+	}
+
+	l.lastGC = now
+}
+
+func keyName(p Policy) string {
+	if p.Key == "" {
+		return KeyIP
+	}
+
+	return p.Key
+}
+
+// bucket is a token bucket. It is not safe for concurrent use.
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(p Policy, now time.Time) *bucket {
+	return &bucket{
+		rate:   p.Rate,
+		burst:  float64(p.Burst),
+		tokens: float64(p.Burst),
+		last:   now,
+	}
+}
+
+func (b *bucket) take(now time.Time) (ok bool, retryAfter time.Duration) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+	}
+
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+
+		return true, 0
+	}
+
+	missing := 1 - b.tokens
+
+	return false, time.Duration(missing / b.rate * float64(time.Second))
+}
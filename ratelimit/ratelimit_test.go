@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/metrics"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+// newTestLimiter returns a limiter with a fake clock advanced by the returned function.
+func newTestLimiter(t *testing.T, policies ...Policy) (*Limiter, func(time.Duration)) {
+	t.Helper()
+
+	registry, err := metrics.NewRegistry("test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := NewLimiter(registry, WithPolicies(policies...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(0, 0)
+	l.now = func() time.Time { return now }
+
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	l, advance := newTestLimiter(t, Policy{Route: "/example.GreeterService/*", Rate: 1, Burst: 2})
+
+	r := Request{Transport: transportGRPC, Route: "/example.GreeterService/SayHi", ClientIP: "1.2.3.4"}
+	other := Request{Transport: transportGRPC, Route: r.Route, ClientIP: "5.6.7.8"}
+	unmatched := Request{Transport: transportGRPC, Route: "/other.Service/Method", ClientIP: "1.2.3.4"}
+
+	tests := []struct {
+		name           string
+		advance        time.Duration
+		request        Request
+		want           bool
+		wantRetryAfter time.Duration
+	}{
+		{name: "first", request: r, want: true},
+		{name: "burst", request: r, want: true},
+		{name: "exhausted", request: r, wantRetryAfter: time.Second},
+		{name: "other client", request: other, want: true},
+		{name: "unmatched route", request: unmatched, want: true},
+		{name: "refilled", advance: time.Second, request: r, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			advance(tt.advance)
+
+			ok, retryAfter := l.Allow(tt.request)
+			if ok != tt.want || retryAfter != tt.wantRetryAfter {
+				t.Errorf("Allow() = %v, %v, want %v, %v", ok, retryAfter, tt.want, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestLimiter_SetPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr error
+	}{
+		{name: "zero rate", policy: Policy{Route: "/a/*", Rate: 0, Burst: 1}, wantErr: ErrInvalidPolicy},
+		{
+			name:    "unknown key",
+			policy:  Policy{Route: "/a/*", Rate: 1, Burst: 1, Key: "subject"},
+			wantErr: ErrUnknownKeyFunc,
+		},
+		{name: "valid", policy: Policy{Route: "/a/*", Rate: 1, Burst: 1, Key: KeyGlobal}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, _ := newTestLimiter(t)
+
+			err := l.SetPolicies([]Policy{tt.policy})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("SetPolicies() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLimiter_SetPolicies_keepsBuckets(t *testing.T) {
+	unchanged := Policy{Route: "/a/*", Rate: 1, Burst: 1, Key: KeyGlobal}
+	changed := Policy{Route: "/b/*", Rate: 1, Burst: 1, Key: KeyGlobal}
+
+	l, _ := newTestLimiter(t, changed, unchanged)
+
+	for _, route := range []string{"/a/x", "/b/x"} {
+		if ok, _ := l.Allow(Request{Route: route}); !ok {
+			t.Fatalf("Allow(%s) rejected the first request", route)
+		}
+	}
+
+	// the unchanged policy moves in the list, the other one gets a new burst
+	changed.Burst = 2
+
+	err := l.SetPolicies([]Policy{unchanged, changed})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := l.Allow(Request{Route: "/a/x"}); ok {
+		t.Error("bucket of the unchanged policy was reset")
+	}
+
+	if ok, _ := l.Allow(Request{Route: "/b/x"}); !ok {
+		t.Error("bucket of the changed policy was kept")
+	}
+}
+
+func TestLimiter_allowGRPC(t *testing.T) {
+	l, _ := newTestLimiter(t, Policy{Route: "/a/*", Rate: 1, Burst: 1, Key: KeyGlobal})
+
+	for _, want := range []codes.Code{codes.OK, codes.ResourceExhausted} {
+		if got := status.Code(l.allowGRPC(t.Context(), "/a/b")); got != want {
+			t.Errorf("code = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	l, _ := newTestLimiter(t, Policy{Route: "GET /users/:id", Rate: 0.5, Burst: 1})
+
+	r := gin.New()
+	r.Use(l.Middleware())
+	r.GET("/users/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		path           string
+		wantCode       int
+		wantRetryAfter string
+	}{
+		{path: "/users/1", wantCode: http.StatusOK},
+		{path: "/users/2", wantCode: http.StatusTooManyRequests, wantRetryAfter: "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequestWithContext(t.Context(), http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantCode || w.Header().Get("Retry-After") != tt.wantRetryAfter {
+				t.Errorf("response = %d (Retry-After %q), want %d (Retry-After %q)",
+					w.Code, w.Header().Get("Retry-After"), tt.wantCode, tt.wantRetryAfter)
+			}
+		})
+	}
+}
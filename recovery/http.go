@@ -0,0 +1,42 @@
+package recovery
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/http/responses"
+	"github.com/moderntv/cadre/http/route"
+)
+
+const transportHTTP = "http"
+
+// Middleware returns gin middleware recovering panics of handlers and responding with 500 Internal Server Error.
+// Requests are labeled by `METHOD /route` where route is the registered gin route (e.g. `GET /users/:id`)
+// or the pattern of the grpc-gateway handler (see package http/route).
+func (r *Recovery) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+
+			// the handler deliberately aborted the response, let net/http handle it
+			if err, ok := p.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(p)
+			}
+
+			r.recovered(c.Request.Context(), transportHTTP, route.Method(c), p)
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+
+			responses.InternalError(c)
+		}()
+
+		c.Next()
+	}
+}
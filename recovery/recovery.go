@@ -0,0 +1,144 @@
+// Package recovery recovers panics of gRPC handlers and HTTP handlers in one consistent way.
+//
+// Recovered panics are logged with their stack through the cadre logger together with the request context
+// (route, request ID, trace ID), counted in the `panics_total{transport,route}` metric and turned into
+// codes.Internal or 500 Internal Server Error. Crash reports can optionally be written to sinks,
+// e.g. a directory using NewDirSink.
+package recovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/moderntv/cadre/metrics"
+	"github.com/moderntv/cadre/requestid"
+	"github.com/moderntv/cadre/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Report describes a recovered panic.
+type Report struct {
+	Time      time.Time `json:"time"`
+	Service   string    `json:"service,omitempty"`
+	Transport string    `json:"transport"`
+	Route     string    `json:"route"`
+	RequestID string    `json:"request_id,omitempty"`
+	TraceID   string    `json:"trace_id,omitempty"`
+	Panic     string    `json:"panic"`
+	Stack     string    `json:"stack"`
+}
+
+// Sink receives crash reports of recovered panics.
+type Sink interface {
+	Write(ctx context.Context, report Report) error
+}
+
+// SinkFunc adapts a function to Sink.
+type SinkFunc func(ctx context.Context, report Report) error
+
+func (f SinkFunc) Write(ctx context.Context, report Report) error {
+	return f(ctx, report)
+}
+
+type Recovery struct {
+	logger  zerolog.Logger
+	service string
+	sinks   []Sink
+
+	panics *prometheus.CounterVec
+}
+
+type Option func(*Recovery) error
+
+// New creates a new panic recovery logging to the logger and counting panics in the metrics registry.
+func New(logger zerolog.Logger, metricsRegistry *metrics.Registry, opts ...Option) (r *Recovery, err error) {
+	r = &Recovery{
+		logger: logger,
+	}
+
+	for _, opt := range opts {
+		err = opt(r)
+		if err != nil {
+			err = fmt.Errorf("cannot apply recovery option: %w", err)
+			return
+		}
+	}
+
+	r.panics, err = metricsRegistry.RegisterOrGetNewCounterVec(
+		"panics_total",
+		prometheus.CounterOpts{
+			Name: "panics_total",
+			Help: "Panics recovered in handlers",
+		},
+		[]string{"transport", "route"},
+	)
+	if err != nil {
+		err = fmt.Errorf("cannot register recovery metrics: %w", err)
+		return
+	}
+
+	return
+}
+
+// WithSink writes crash reports of recovered panics to the sink.
+func WithSink(sink Sink) Option {
+	return func(r *Recovery) error {
+		if sink == nil {
+			return errors.New("crash report sink cannot be nil")
+		}
+
+		r.sinks = append(r.sinks, sink)
+
+		return nil
+	}
+}
+
+// WithService sets the service name written to crash reports.
+func WithService(service string) Option {
+	return func(r *Recovery) error {
+		r.service = service
+
+		return nil
+	}
+}
+
+// recovered handles the recovered panic value p.
+func (r *Recovery) recovered(ctx context.Context, transport, route string, p any) {
+	report := Report{
+		Time:      time.Now(),
+		Service:   r.service,
+		Transport: transport,
+		Route:     route,
+		Panic:     fmt.Sprint(p),
+		Stack:     string(debug.Stack()),
+	}
+
+	report.RequestID, _ = requestid.FromContext(ctx)
+
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		report.TraceID = sc.TraceID().String()
+	}
+
+	r.panics.WithLabelValues(transport, route).Inc()
+
+	r.logger.Error().
+		Str("transport", transport).
+		Str("route", route).
+		Str(requestid.LogField, report.RequestID).
+		Str(tracing.TraceIDField, report.TraceID).
+		Str("panic", report.Panic).
+		Str("stack", report.Stack).
+		Msg("recovered from panic")
+
+	for _, sink := range r.sinks {
+		err := sink.Write(ctx, report)
+		if err != nil {
+			r.logger.Error().Err(err).Msg("cannot write crash report")
+		}
+	}
+}
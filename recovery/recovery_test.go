@@ -0,0 +1,207 @@
+package recovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/metrics"
+	"github.com/moderntv/cadre/requestid"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+type serverStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestRecovery(t *testing.T) {
+	// calls return whether the panic was answered with an internal error
+	tests := []struct {
+		name      string
+		call      func(r *Recovery, ctx context.Context) bool
+		transport string
+		route     string
+	}{
+		{
+			name: "unary",
+			call: func(r *Recovery, ctx context.Context) bool {
+				_, err := r.UnaryServerInterceptor()(ctx, nil,
+					&grpc.UnaryServerInfo{FullMethod: "/example.Service/Method"},
+					func(context.Context, any) (any, error) { panic("boom") },
+				)
+
+				return status.Code(err) == codes.Internal
+			},
+			transport: "grpc",
+			route:     "/example.Service/Method",
+		},
+		{
+			name: "stream",
+			call: func(r *Recovery, ctx context.Context) bool {
+				err := r.StreamServerInterceptor()(nil, &serverStream{ctx: ctx},
+					&grpc.StreamServerInfo{FullMethod: "/example.Service/Stream"},
+					func(any, grpc.ServerStream) error { panic("boom") },
+				)
+
+				return status.Code(err) == codes.Internal
+			},
+			transport: "grpc",
+			route:     "/example.Service/Stream",
+		},
+		{
+			name: "http",
+			call: func(r *Recovery, ctx context.Context) bool {
+				router := gin.New()
+				router.Use(r.Middleware())
+				router.GET("/users/:id", func(*gin.Context) { panic("boom") })
+
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequestWithContext(ctx, http.MethodGet, "/users/1", nil))
+
+				return w.Code == http.StatusInternalServerError
+			},
+			transport: "http",
+			route:     "GET /users/:id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				logs    bytes.Buffer
+				reports []Report
+			)
+
+			registry, err := metrics.NewRegistry("test", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := New(zerolog.New(&logs), registry, WithService("test"),
+				WithSink(SinkFunc(func(_ context.Context, report Report) error {
+					reports = append(reports, report)
+					return nil
+				})),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !tt.call(r, requestid.NewContext(t.Context(), "req-1")) {
+				t.Error("panic was not answered with an internal error")
+			}
+
+			m := &dto.Metric{}
+			_ = r.panics.WithLabelValues(tt.transport, tt.route).Write(m)
+
+			if m.GetCounter().GetValue() != 1 {
+				t.Errorf("panics = %v, want 1", m.GetCounter().GetValue())
+			}
+
+			for _, want := range []string{`"panic":"boom"`, "recovery_test.go"} {
+				if !strings.Contains(logs.String(), want) {
+					t.Errorf("logs %s do not contain %s", logs.String(), want)
+				}
+			}
+
+			if len(reports) != 1 {
+				t.Fatalf("reports = %d, want 1", len(reports))
+			}
+
+			got := reports[0]
+			if got.Service != "test" || got.Transport != tt.transport || got.Route != tt.route || got.Panic != "boom" ||
+				got.Stack == "" {
+				t.Errorf("report = %+v, want %s panic of %s", got, tt.transport, tt.route)
+			}
+
+			if tt.transport == "grpc" && got.RequestID != "req-1" {
+				t.Errorf("report request id = %q, want %q", got.RequestID, "req-1")
+			}
+		})
+	}
+}
+
+func TestDirSink(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "crashes")
+
+	sink, err := NewDirSink(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Report{Service: "test", Transport: "http", Route: "GET /users/:id", Panic: "boom"}
+
+	err = sink.Write(t.Context(), want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("crash files = %v (%v), want 1", files, err)
+	}
+
+	d, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Report
+
+	err = json.Unmarshal(d, &got)
+	if err != nil || got.Route != want.Route || got.Panic != want.Panic {
+		t.Errorf("report = %+v (%v), want %+v", got, err, want)
+	}
+}
+
+func TestRecovery_MiddlewareAbortHandler(t *testing.T) {
+	var logs bytes.Buffer
+
+	registry, err := metrics.NewRegistry("test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New(zerolog.New(&logs), registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(r.Middleware())
+	router.GET("/abort", func(*gin.Context) {
+		panic(http.ErrAbortHandler)
+	})
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler { //nolint: errorlint
+			t.Errorf("recovered %v, want %v", v, http.ErrAbortHandler)
+		}
+
+		if logs.Len() > 0 {
+			t.Errorf("logs = %s, want none", logs.String())
+		}
+	}()
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+}
@@ -0,0 +1,45 @@
+package recovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// DirSink writes every crash report as a separate JSON file to a directory.
+type DirSink struct {
+	dir string
+	seq atomic.Uint64
+}
+
+// NewDirSink creates a sink writing crash reports to the directory. The directory is created if it does not exist.
+func NewDirSink(dir string) (s *DirSink, err error) {
+	err = os.MkdirAll(dir, 0o750)
+	if err != nil {
+		err = fmt.Errorf("cannot create crash report directory: %w", err)
+		return
+	}
+
+	s = &DirSink{dir: dir}
+
+	return
+}
+
+func (s *DirSink) Write(_ context.Context, report Report) error {
+	d, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot encode crash report: %w", err)
+	}
+
+	name := fmt.Sprintf("crash-%s-%d.json", report.Time.UTC().Format("20060102T150405.000000000"), s.seq.Add(1))
+
+	err = os.WriteFile(filepath.Join(s.dir, name), d, 0o600)
+	if err != nil {
+		return fmt.Errorf("cannot write crash report: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package requestid
+
+import (
+	"context"
+
+	"github.com/rkollar/go-grpc-middleware/logging/zerolog/ctxzerolog"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// UnaryServerInterceptor accepts or generates the request ID of unary calls, echoes it in the response header
+// and attaches it to the call context together with a request-scoped logger derived from logger.
+func UnaryServerInterceptor(logger zerolog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(serverContext(ctx, logger), req)
+	}
+}
+
+// StreamServerInterceptor accepts or generates the request ID of streams, echoes it in the response header
+// and attaches it to the stream context together with a request-scoped logger derived from logger.
+func StreamServerInterceptor(logger zerolog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{
+			ServerStream: ss,
+			ctx:          serverContext(ss.Context(), logger),
+		})
+	}
+}
+
+// UnaryClientInterceptor forwards the request ID found in the context to the called server.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		return invoker(clientContext(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor forwards the request ID found in the context to the called server.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return streamer(clientContext(ctx), desc, cc, method, opts...)
+	}
+}
+
+func serverContext(ctx context.Context, logger zerolog.Logger) context.Context {
+	var id string
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		if values := md.Get(MetadataKey); len(values) > 0 {
+			id = values[0]
+		}
+	}
+
+	id = sanitize(id)
+
+	// echo the id back; fails only when headers were already sent which cannot happen here
+	_ = grpc.SetHeader(ctx, metadata.Pairs(MetadataKey, id))
+
+	// field picked up by the grpc logging middleware
+	ctxzerolog.AddFields(ctx, map[string]any{
+		LogField: id,
+	})
+
+	return newRequestContext(ctx, id, logger)
+}
+
+func clientContext(ctx context.Context) context.Context {
+	id, ok := FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if len(md.Get(MetadataKey)) > 0 {
+		return ctx
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, MetadataKey, id)
+}
+
+type serverStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
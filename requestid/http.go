@@ -0,0 +1,56 @@
+package requestid
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// Middleware returns gin middleware accepting or generating the request ID, echoing it in the response
+// and attaching it to the request context together with a request-scoped logger derived from logger.
+func Middleware(logger zerolog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := sanitize(c.GetHeader(Header))
+
+		c.Header(Header, id)
+		c.Request = c.Request.WithContext(newRequestContext(c.Request.Context(), id, logger))
+
+		c.Next()
+	}
+}
+
+// Get returns the request ID of the request handled by gin.
+func Get(c *gin.Context) string {
+	id, _ := FromContext(c.Request.Context())
+
+	return id
+}
+
+// Logger returns the request-scoped logger of the request handled by gin.
+func Logger(c *gin.Context) *zerolog.Logger {
+	return zerolog.Ctx(c.Request.Context())
+}
+
+// Transport wraps the round tripper so that outgoing HTTP requests carry the request ID found in their context.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		id, ok := FromContext(r.Context())
+		if ok && r.Header.Get(Header) == "" {
+			r = r.Clone(r.Context())
+			r.Header.Set(Header, id)
+		}
+
+		return next.RoundTrip(r)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
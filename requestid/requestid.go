@@ -0,0 +1,72 @@
+// Package requestid propagates a correlation (request) ID across HTTP and gRPC calls.
+// Incoming IDs are accepted from the X-Request-ID header or x-request-id metadata, or generated when missing.
+// The ID is echoed back in responses, forwarded in outgoing calls and attached to a request-scoped zerolog logger
+// available through zerolog.Ctx (log.Ctx).
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// Header is the HTTP header carrying the request ID.
+	Header = "X-Request-ID"
+	// MetadataKey is the gRPC metadata key carrying the request ID.
+	MetadataKey = "x-request-id"
+	// LogField is the log field containing the request ID.
+	LogField = "request_id"
+
+	// maxLength limits the length of accepted request IDs.
+	maxLength = 128
+)
+
+type ctxKey struct{}
+
+// NewContext returns a new context carrying the request ID.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the request ID stored in the context.
+func FromContext(ctx context.Context) (id string, ok bool) {
+	id, ok = ctx.Value(ctxKey{}).(string)
+
+	return
+}
+
+// Generate creates a new request ID.
+func Generate() string {
+	return uuid.NewString()
+}
+
+// sanitize returns the incoming request ID or generates a new one if it is missing or invalid.
+func sanitize(id string) string {
+	if id == "" || len(id) > maxLength {
+		return Generate()
+	}
+
+	for _, r := range id {
+		// printable ascii only - the id ends up in headers and logs
+		if r < 0x21 || r > 0x7e {
+			return Generate()
+		}
+	}
+
+	return id
+}
+
+// newRequestContext stores the request ID and a logger enriched with it into the context.
+// The logger already present in the context is preferred over the base logger.
+func newRequestContext(ctx context.Context, id string, baseLogger zerolog.Logger) context.Context {
+	logger := zerolog.Ctx(ctx)
+	if logger == zerolog.DefaultContextLogger || logger.GetLevel() == zerolog.Disabled {
+		logger = &baseLogger
+	}
+
+	ctx = NewContext(ctx, id)
+
+	return logger.With().Str(LogField, id).Logger().WithContext(ctx)
+}
@@ -0,0 +1,102 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		wantSame bool
+	}{
+		{name: "accepts incoming id", incoming: "abc-123", wantSame: true},
+		{name: "generates missing id", incoming: ""},
+		{name: "replaces invalid id", incoming: "abc 123"},
+		{name: "replaces too long id", incoming: strings.Repeat("a", maxLength+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				buf       bytes.Buffer
+				handlerID string
+			)
+
+			r := gin.New()
+			r.Use(Middleware(zerolog.New(&buf)))
+			r.GET("/", func(c *gin.Context) {
+				handlerID = Get(c)
+				Logger(c).Info().Msg("handled")
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+			req.Header.Set(Header, tt.incoming)
+			r.ServeHTTP(w, req)
+
+			id := w.Header().Get(Header)
+			if id == "" || id != handlerID {
+				t.Errorf("response id = %q, handler id = %q", id, handlerID)
+			}
+
+			if (id == tt.incoming) != tt.wantSame {
+				t.Errorf("id = %q, incoming %q, want same %v", id, tt.incoming, tt.wantSame)
+			}
+
+			if !strings.Contains(buf.String(), `"request_id":"`+id+`"`) {
+				t.Errorf("log %q does not contain request id %q", buf.String(), id)
+			}
+		})
+	}
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	var outgoing metadata.MD
+
+	invoker := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+
+		return nil
+	}
+
+	ctx := NewContext(t.Context(), "abc-123")
+
+	err := UnaryClientInterceptor()(ctx, "/example.GreeterService/SayHi", nil, nil, nil, invoker)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var handlerID string
+
+	_, err = UnaryServerInterceptor(zerolog.Nop())(
+		metadata.NewIncomingContext(t.Context(), outgoing),
+		nil,
+		&grpc.UnaryServerInfo{FullMethod: "/example.GreeterService/SayHi"},
+		func(ctx context.Context, _ any) (any, error) {
+			handlerID, _ = FromContext(ctx)
+
+			return nil, nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if handlerID != "abc-123" {
+		t.Errorf("handler id = %q, want %q", handlerID, "abc-123")
+	}
+}
@@ -11,26 +11,25 @@ type Service interface {
 	// Address() string
 }
 
+// service reports the serving status of a grpc service derived from the status components it depends on.
 type service struct {
 	name string
 	// endpoint    string
 
-	status        *status.ComponentStatus
+	components    []string       // empty - the service follows the overall status
 	healthService *health.Server // can be nil
 }
 
 // newService creates a new service instance. if healthService is disabled in Server, it can be nil
-//
-//nolint:deadcode
-func newService(name string, cs *status.ComponentStatus, healthService *health.Server) (i Service) {
+func newService(name string, components []string, healthService *health.Server) (i *service) {
 	i = &service{
 		name: name,
 
-		status:        cs,
+		components:    components,
 		healthService: healthService,
 	}
 
-	(i.(*service)).SetUnhealthy()
+	i.SetUnhealthy()
 
 	return
 }
@@ -39,12 +38,36 @@ func (i *service) Name() string { return i.name }
 
 // func (i *instance) Address() string     { return i.endpoint }
 
+// Update sets the serving status according to the report. The service is unhealthy if any of its components
+// (or any component when it depends on none) is in ERROR or missing. Degraded (WARN) components, e.g. a shedding
+// concurrency limiter or an open circuit breaker, keep the service serving - taking it out of rotation would only
+// move the load elsewhere.
+func (i *service) Update(report status.Report) {
+	healthy := report.Status != status.ERROR
+	if len(i.components) > 0 {
+		healthy = true
+
+		for _, name := range i.components {
+			component, ok := report.Components[name]
+			if !ok || component.Status == status.ERROR {
+				healthy = false
+				break
+			}
+		}
+	}
+
+	if healthy {
+		i.SetHealthy()
+	} else {
+		i.SetUnhealthy()
+	}
+}
+
 func (i *service) SetHealthy() {
 	if i.healthService == nil {
 		return
 	}
 
-	i.status.SetStatus(status.OK, "OK")
 	i.healthService.SetServingStatus(i.Name(), grpc_health_v1.HealthCheckResponse_SERVING)
 }
 
@@ -53,6 +76,5 @@ func (i *service) SetUnhealthy() {
 		return
 	}
 
-	i.status.SetStatus(status.ERROR, "unhealthy")
 	i.healthService.SetServingStatus(i.Name(), grpc_health_v1.HealthCheckResponse_NOT_SERVING)
 }
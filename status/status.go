@@ -25,6 +25,9 @@ type Status struct {
 
 	mu         sync.RWMutex
 	components map[string]*ComponentStatus
+
+	watchersMu sync.Mutex
+	watchers   map[chan Report]struct{}
 }
 
 var ErrAlreadyExists = errors.New("component already exists")
@@ -47,19 +50,27 @@ func NewStatus(version string) (status *Status) {
 
 func (s *Status) Register(name string) (cs *ComponentStatus, err error) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
 	if _, ok := s.components[name]; ok {
+		s.mu.Unlock()
+
 		err = ErrAlreadyExists
+
 		return
 	}
 
 	cs = &ComponentStatus{
-		status:  ERROR,
-		message: "uninitialized",
+		status:   ERROR,
+		message:  "uninitialized",
+		onChange: s.notify,
 	}
 	s.components[name] = cs
 
+	s.mu.Unlock()
+
+	// the new component changes the report
+	s.notify()
+
 	return
 }
 
@@ -74,7 +85,10 @@ func (s *Status) RegisterOrGet(name string) (cs *ComponentStatus, err error) {
 	}
 
 	err = nil
+
+	s.mu.RLock()
 	cs = s.components[name]
+	s.mu.RUnlock()
 
 	return
 }
@@ -110,21 +124,68 @@ func (s *Status) Report() (report Report) {
 	return
 }
 
+// Watch returns a channel receiving the report whenever a component is registered or its status type changes.
+// Only the latest report is kept for a slow receiver. The returned function stops the watch.
+func (s *Status) Watch() (<-chan Report, func()) {
+	ch := make(chan Report, 1)
+
+	s.watchersMu.Lock()
+	if s.watchers == nil {
+		s.watchers = make(map[chan Report]struct{})
+	}
+
+	s.watchers[ch] = struct{}{}
+	s.watchersMu.Unlock()
+
+	return ch, func() {
+		s.watchersMu.Lock()
+		delete(s.watchers, ch)
+		s.watchersMu.Unlock()
+	}
+}
+
+// notify sends the current report to the watchers, replacing reports they have not received yet.
+func (s *Status) notify() {
+	s.watchersMu.Lock()
+	defer s.watchersMu.Unlock()
+
+	if len(s.watchers) == 0 {
+		return
+	}
+
+	report := s.Report()
+	for ch := range s.watchers {
+		select {
+		case <-ch:
+		default:
+		}
+
+		ch <- report
+	}
+}
+
 type ComponentStatus struct {
 	mu        sync.RWMutex
 	status    StatusType
 	message   string
 	updatedAt time.Time
+
+	onChange func() // can be nil
 }
 
 func (cs *ComponentStatus) SetStatus(statusType StatusType, message string) {
 	cs.mu.Lock()
 
+	changed := cs.status != statusType
 	cs.message = message
 	cs.status = statusType
 	cs.updatedAt = time.Now()
 
 	cs.mu.Unlock()
+
+	if changed && cs.onChange != nil {
+		cs.onChange()
+	}
 }
 
 func (cs *ComponentStatus) Status() StatusType {
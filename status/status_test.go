@@ -224,3 +224,46 @@ func TestStatus_Report(t *testing.T) {
 		})
 	}
 }
+
+func TestStatus_Watch(t *testing.T) {
+	status := NewStatus("v6.6.6")
+
+	reports, stop := status.Watch()
+	defer stop()
+
+	cs, err := status.Register("foo")
+	if err != nil {
+		t.Fatalf("Register() returned error = %v", err)
+	}
+
+	report := <-reports
+	if report.Status != ERROR || report.Components["foo"].Status != ERROR {
+		t.Errorf("Watch() report after Register() = %v, want ERROR", report)
+	}
+
+	cs.SetStatus(ERROR, "still failing")
+
+	select {
+	case report = <-reports:
+		t.Errorf("Watch() reported unchanged status type: %v", report)
+	default:
+	}
+
+	cs.SetStatus(WARN, "slow")
+	cs.SetStatus(OK, "OK")
+
+	// only the latest report is kept
+	report = <-reports
+	if report.Status != OK {
+		t.Errorf("Watch() report status = %v, want OK", report.Status)
+	}
+
+	stop()
+	cs.SetStatus(ERROR, "down")
+
+	select {
+	case report = <-reports:
+		t.Errorf("Watch() reported after stop: %v", report)
+	default:
+	}
+}
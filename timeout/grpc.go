@@ -0,0 +1,51 @@
+package timeout
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const transportGRPC = "grpc"
+
+// UnaryServerInterceptor applies the method's deadline to unary calls.
+func (t *Timeouts) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, cancel := t.withDeadline(ctx, info.FullMethod)
+		defer cancel()
+
+		resp, err := handler(ctx, req)
+		if err != nil && t.observe(ctx, transportGRPC, info.FullMethod) {
+			return nil, status.Error(codes.DeadlineExceeded, "deadline exceeded")
+		}
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor applies the method's deadline to streams.
+func (t *Timeouts) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := t.withDeadline(ss.Context(), info.FullMethod)
+		defer cancel()
+
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		if err != nil && t.observe(ctx, transportGRPC, info.FullMethod) {
+			return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+		}
+
+		return err
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
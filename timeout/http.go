@@ -0,0 +1,30 @@
+package timeout
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/http/responses"
+	"github.com/moderntv/cadre/http/route"
+)
+
+const transportHTTP = "http"
+
+// Middleware returns gin middleware applying the route's deadline to the request context.
+// Requests are matched by `METHOD /route` where route is the registered gin route (e.g. `GET /users/:id`)
+// or the pattern of the grpc-gateway handler (see package http/route).
+// If the deadline is exceeded before the handler writes a response, 408 Request Timeout is returned.
+func (t *Timeouts) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		methodRoute := route.Method(c)
+
+		ctx, cancel := t.withDeadline(c.Request.Context(), methodRoute)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if t.observe(ctx, transportHTTP, methodRoute) && !c.Writer.Written() {
+			responses.Timeout(c)
+		}
+	}
+}
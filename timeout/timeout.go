@@ -0,0 +1,138 @@
+// Package timeout enforces server-side deadlines of gRPC calls and HTTP requests.
+//
+// Timeouts are configured per gRPC full method (`/package.Service/Method`) or HTTP method and route
+// (`GET /users/:id`). Keys are matched exactly first, then as path.Match patterns (longer patterns win).
+// Routes without a configured timeout use the default one. The timeout is applied only if the incoming
+// deadline is missing or longer. Handlers are expected to honor context cancellation - when the deadline
+// is exceeded, gRPC calls fail with codes.DeadlineExceeded and HTTP requests with 408.
+//
+// Only deadlines applied by the server are enforced and counted - calls canceled or timed out by the caller
+// keep the handler's result. Every exceeded server deadline is counted in the `timeout_exceeded_total{transport,route}` metric.
+package timeout
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/moderntv/cadre/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// errTimeout is the cause of deadlines applied by the server.
+var errTimeout = errors.New("server timeout exceeded")
+
+type Timeouts struct {
+	exact          map[string]time.Duration
+	patterns       []pattern
+	defaultTimeout time.Duration
+
+	exceeded *prometheus.CounterVec
+}
+
+type pattern struct {
+	pattern string
+	timeout time.Duration
+}
+
+// New creates timeouts for the routes. Zero timeout disables the deadline of the route, zero default timeout
+// leaves routes without configured timeout unlimited.
+func New(
+	metricsRegistry *metrics.Registry,
+	timeouts map[string]time.Duration,
+	defaultTimeout time.Duration,
+) (t *Timeouts, err error) {
+	if defaultTimeout < 0 {
+		err = fmt.Errorf("invalid default timeout `%s`", defaultTimeout)
+		return
+	}
+
+	t = &Timeouts{
+		exact:          map[string]time.Duration{},
+		defaultTimeout: defaultTimeout,
+	}
+
+	for route, timeout := range timeouts {
+		if timeout < 0 {
+			err = fmt.Errorf("invalid timeout `%s` of route `%s`", timeout, route)
+			return
+		}
+
+		_, err = path.Match(route, "")
+		if err != nil {
+			err = fmt.Errorf("invalid timeout route `%s`: %w", route, err)
+			return
+		}
+
+		t.exact[route] = timeout
+
+		if strings.ContainsAny(route, `*?[\`) {
+			t.patterns = append(t.patterns, pattern{pattern: route, timeout: timeout})
+		}
+	}
+
+	slices.SortFunc(t.patterns, func(a, b pattern) int {
+		return cmp.Or(cmp.Compare(len(b.pattern), len(a.pattern)), strings.Compare(a.pattern, b.pattern))
+	})
+
+	t.exceeded, err = metricsRegistry.RegisterOrGetNewCounterVec(
+		"timeout_exceeded_total",
+		prometheus.CounterOpts{
+			Subsystem: "timeout",
+			Name:      "exceeded_total",
+			Help:      "Requests which exceeded their deadline",
+		},
+		[]string{"transport", "route"},
+	)
+	if err != nil {
+		err = fmt.Errorf("cannot register timeout metrics: %w", err)
+		return
+	}
+
+	return
+}
+
+// Timeout returns the timeout of the route. Zero means no timeout.
+func (t *Timeouts) Timeout(route string) time.Duration {
+	if timeout, ok := t.exact[route]; ok {
+		return timeout
+	}
+
+	for _, p := range t.patterns {
+		if ok, _ := path.Match(p.pattern, route); ok {
+			return p.timeout
+		}
+	}
+
+	return t.defaultTimeout
+}
+
+// withDeadline applies the route's timeout to the context unless it already has an earlier deadline.
+func (t *Timeouts) withDeadline(ctx context.Context, route string) (context.Context, context.CancelFunc) {
+	timeout := t.Timeout(route)
+	if timeout == 0 {
+		return ctx, func() {}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= timeout {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeoutCause(ctx, timeout, errTimeout)
+}
+
+// observe reports and counts whether the deadline applied by withDeadline was exceeded.
+func (t *Timeouts) observe(ctx context.Context, transport, route string) bool {
+	if !errors.Is(context.Cause(ctx), errTimeout) {
+		return false
+	}
+
+	t.exceeded.WithLabelValues(transport, route).Inc()
+
+	return true
+}
@@ -0,0 +1,204 @@
+package timeout
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/metrics"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+var testRoutes = map[string]time.Duration{
+	"/example.Service/*":        time.Second,
+	"/example.Service/Slow":     10 * time.Millisecond,
+	"/example.Service/Stream*":  0,
+	"/example.Service/Streamer": time.Minute,
+	"GET /slow":                 10 * time.Millisecond,
+}
+
+func exceeded(t *testing.T, timeouts *Timeouts, transport, route string) float64 {
+	t.Helper()
+
+	m := &dto.Metric{}
+
+	err := timeouts.exceeded.WithLabelValues(transport, route).Write(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m.GetCounter().GetValue()
+}
+
+func TestTimeouts_Timeout(t *testing.T) {
+	registry, err := metrics.NewRegistry("test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timeouts, err := New(registry, testRoutes, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		route string
+		want  time.Duration
+	}{
+		{route: "/example.Service/Slow", want: 10 * time.Millisecond},
+		{route: "/example.Service/Fast", want: time.Second},
+		{route: "/example.Service/StreamAll", want: 0},
+		{route: "/example.Service/Streamer", want: time.Minute},
+		{route: "/example.Other/Method", want: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			if got := timeouts.Timeout(tt.route); got != tt.want {
+				t.Errorf("Timeouts.Timeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeouts_UnaryServerInterceptor(t *testing.T) {
+	errHandler := errors.New("handler failed")
+
+	wait := func(ctx context.Context, _ any) (any, error) {
+		<-ctx.Done()
+		return nil, errHandler
+	}
+	succeedLate := func(ctx context.Context, _ any) (any, error) {
+		<-ctx.Done()
+		return "ok", nil
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		callerTimeout time.Duration
+		handler       grpc.UnaryHandler
+		wantResp      any
+		wantCode      codes.Code
+		wantErr       error
+		wantExceeded  float64
+	}{
+		{
+			name:         "server deadline",
+			method:       "/example.Service/Slow",
+			handler:      wait,
+			wantCode:     codes.DeadlineExceeded,
+			wantExceeded: 1,
+		},
+		{
+			name:          "caller deadline",
+			method:        "/example.Service/Fast",
+			callerTimeout: 10 * time.Millisecond,
+			handler:       wait,
+			wantCode:      codes.Unknown,
+			wantErr:       errHandler,
+		},
+		{
+			name:     "success after server deadline",
+			method:   "/example.Service/Slow",
+			handler:  succeedLate,
+			wantResp: "ok",
+			wantCode: codes.OK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, err := metrics.NewRegistry("test", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			timeouts, err := New(registry, testRoutes, 5*time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := t.Context()
+
+			if tt.callerTimeout > 0 {
+				var cancel context.CancelFunc
+
+				ctx, cancel = context.WithTimeout(ctx, tt.callerTimeout)
+				defer cancel()
+			}
+
+			info := &grpc.UnaryServerInfo{FullMethod: tt.method}
+
+			resp, err := timeouts.UnaryServerInterceptor()(ctx, nil, info, tt.handler)
+			if resp != tt.wantResp {
+				t.Errorf("response = %v, want %v", resp, tt.wantResp)
+			}
+
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("code = %v, want %v", status.Code(err), tt.wantCode)
+			}
+
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got := exceeded(t, timeouts, transportGRPC, tt.method); got != tt.wantExceeded {
+				t.Errorf("exceeded = %v, want %v", got, tt.wantExceeded)
+			}
+		})
+	}
+}
+
+func TestTimeouts_Middleware(t *testing.T) {
+	tests := []struct {
+		path         string
+		wantCode     int
+		wantExceeded float64
+	}{
+		{path: "/slow", wantCode: http.StatusRequestTimeout, wantExceeded: 1},
+		{path: "/fast", wantCode: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			registry, err := metrics.NewRegistry("test", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			timeouts, err := New(registry, testRoutes, 5*time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := gin.New()
+			r.Use(timeouts.Middleware())
+			r.GET("/slow", func(c *gin.Context) {
+				<-c.Request.Context().Done()
+			})
+			r.GET("/fast", func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+
+			if got := exceeded(t, timeouts, transportHTTP, "GET "+tt.path); got != tt.wantExceeded {
+				t.Errorf("exceeded = %v, want %v", got, tt.wantExceeded)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package tracing
+
+import (
+	"io"
+
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// NewStdoutExporter creates an exporter writing spans as pretty-printed JSON to w.
+func NewStdoutExporter(w io.Writer) (*stdouttrace.Exporter, error) {
+	return stdouttrace.New(
+		stdouttrace.WithWriter(w),
+		stdouttrace.WithPrettyPrint(),
+	)
+}
+
+// NewInMemoryExporter creates an exporter keeping spans in memory. Useful in tests together with WithSyncExporter.
+func NewInMemoryExporter() *tracetest.InMemoryExporter {
+	return tracetest.NewInMemoryExporter()
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/http/route"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware returns gin middleware starting a server span for every request continuing the trace propagated by the client.
+// Span names consist of the HTTP method and the matched route (e.g. `GET /users/:id`).
+func (t *Tracing) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := t.propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		httpRoute := route.FromContext(c)
+		if httpRoute == "" {
+			httpRoute = "unmatched"
+		}
+
+		ctx, span := t.tracer.Start(
+			ctx,
+			c.Request.Method+" "+httpRoute,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", httpRoute),
+				attribute.String("url.path", c.Request.URL.Path),
+				attribute.String("network.protocol.version", c.Request.Proto),
+				attribute.String("client.address", c.ClientIP()),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(t.loggerContext(ctx, span))
+
+		c.Next()
+
+		statusCode := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+
+		if statusCode >= http.StatusInternalServerError {
+			span.SetStatus(otelcodes.Error, http.StatusText(statusCode))
+		}
+
+		if len(c.Errors) > 0 {
+			span.SetStatus(otelcodes.Error, c.Errors.String())
+		}
+	}
+}
+
+// Transport wraps the round tripper so that outgoing HTTP requests propagate the trace context.
+func (t *Tracing) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		r = r.Clone(r.Context())
+		t.propagator.Inject(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		return next.RoundTrip(r)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+type samplingRule struct {
+	pattern string
+	sampler sdktrace.Sampler
+}
+
+func newSamplingRule(pattern string, sampler sdktrace.Sampler) (rule samplingRule, err error) {
+	if sampler == nil {
+		err = errors.New("sampler cannot be nil")
+		return
+	}
+
+	_, err = path.Match(pattern, "")
+	if err != nil {
+		err = fmt.Errorf("invalid sampling rule pattern `%s`: %w", pattern, err)
+		return
+	}
+
+	rule = samplingRule{
+		pattern: pattern,
+		sampler: sampler,
+	}
+
+	return
+}
+
+// ruleSampler delegates the sampling decision to the first rule matching the span name.
+type ruleSampler struct {
+	rules          []samplingRule
+	defaultSampler sdktrace.Sampler
+}
+
+func newRuleSampler(rules []samplingRule, defaultSampler sdktrace.Sampler) sdktrace.Sampler {
+	if len(rules) == 0 {
+		return defaultSampler
+	}
+
+	return &ruleSampler{
+		rules:          rules,
+		defaultSampler: defaultSampler,
+	}
+}
+
+func (rs *ruleSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	for _, rule := range rs.rules {
+		if ok, _ := path.Match(rule.pattern, p.Name); ok {
+			return rule.sampler.ShouldSample(p)
+		}
+	}
+
+	return rs.defaultSampler.ShouldSample(p)
+}
+
+func (rs *ruleSampler) Description() string {
+	return fmt.Sprintf("RuleSampler{rules:%d,default:%s}", len(rs.rules), rs.defaultSampler.Description())
+}
@@ -0,0 +1,220 @@
+// Package tracing provides OpenTelemetry tracing for cadre's gRPC and HTTP servers.
+// Trace context is propagated using W3C trace-context headers/metadata
+// and trace and span IDs are injected into the request-scoped zerolog logger.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/moderntv/cadre/tracing"
+
+	// TraceIDField is the log field containing the trace ID.
+	TraceIDField = "trace_id"
+	// SpanIDField is the log field containing the span ID.
+	SpanIDField = "span_id"
+)
+
+// Tracing holds the tracer provider and propagator used by cadre's interceptors and middleware.
+type Tracing struct {
+	provider   *sdktrace.TracerProvider
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	logger     zerolog.Logger
+}
+
+type options struct {
+	exporters      []sdktrace.SpanExporter
+	syncExporters  []sdktrace.SpanExporter
+	defaultSampler sdktrace.Sampler
+	samplingRules  []samplingRule
+	attributes     []attribute.KeyValue
+	propagator     propagation.TextMapPropagator
+	logger         zerolog.Logger
+}
+
+type Option func(*options) error
+
+// New creates a new Tracing for the service with the given name.
+// Without any exporter spans are still created and propagated but never exported.
+func New(serviceName string, opts ...Option) (t *Tracing, err error) {
+	o := &options{
+		defaultSampler: sdktrace.ParentBased(sdktrace.AlwaysSample()),
+		propagator:     propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+		logger:         zerolog.Nop(),
+	}
+
+	for _, opt := range opts {
+		err = opt(o)
+		if err != nil {
+			err = fmt.Errorf("cannot apply tracing option: %w", err)
+			return
+		}
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(append(o.attributes, attribute.String("service.name", serviceName))...),
+	)
+	if err != nil {
+		err = fmt.Errorf("cannot create tracing resource: %w", err)
+		return
+	}
+
+	providerOptions := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(newRuleSampler(o.samplingRules, o.defaultSampler)),
+	}
+	for _, exporter := range o.exporters {
+		providerOptions = append(providerOptions, sdktrace.WithBatcher(exporter))
+	}
+
+	for _, exporter := range o.syncExporters {
+		providerOptions = append(providerOptions, sdktrace.WithSyncer(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(providerOptions...)
+	t = &Tracing{
+		provider:   provider,
+		tracer:     provider.Tracer(instrumentationName),
+		propagator: o.propagator,
+		logger:     o.logger,
+	}
+
+	return
+}
+
+// TracerProvider returns the underlying tracer provider. It can be used to create custom spans
+// or registered globally using otel.SetTracerProvider.
+func (t *Tracing) TracerProvider() trace.TracerProvider {
+	return t.provider
+}
+
+// Propagator returns the propagator used to inject and extract trace context.
+func (t *Tracing) Propagator() propagation.TextMapPropagator {
+	return t.propagator
+}
+
+// ForceFlush exports all ended spans which have not been exported yet.
+func (t *Tracing) ForceFlush(ctx context.Context) error {
+	return t.provider.ForceFlush(ctx)
+}
+
+// Shutdown flushes remaining spans and shuts the exporters down.
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	return t.provider.Shutdown(ctx)
+}
+
+// loggerContext stores a logger enriched with trace and span IDs into the context
+// so that handlers using zerolog.Ctx log correlated lines.
+func (t *Tracing) loggerContext(ctx context.Context, span trace.Span) context.Context {
+	sc := span.SpanContext()
+	if !sc.IsValid() {
+		return ctx
+	}
+
+	logger := zerolog.Ctx(ctx)
+	if logger == zerolog.DefaultContextLogger || logger.GetLevel() == zerolog.Disabled {
+		logger = &t.logger
+	}
+
+	return logger.With().
+		Str(TraceIDField, sc.TraceID().String()).
+		Str(SpanIDField, sc.SpanID().String()).
+		Logger().
+		WithContext(ctx)
+}
+
+// WithExporter adds an exporter to which spans are exported in batches.
+func WithExporter(exporter sdktrace.SpanExporter) Option {
+	return func(o *options) error {
+		if exporter == nil {
+			return errors.New("exporter cannot be nil")
+		}
+
+		o.exporters = append(o.exporters, exporter)
+
+		return nil
+	}
+}
+
+// WithSyncExporter adds an exporter to which every span is exported synchronously when it ends.
+// This is meant for tests and debugging (in combination with NewInMemoryExporter or NewStdoutExporter).
+func WithSyncExporter(exporter sdktrace.SpanExporter) Option {
+	return func(o *options) error {
+		if exporter == nil {
+			return errors.New("exporter cannot be nil")
+		}
+
+		o.syncExporters = append(o.syncExporters, exporter)
+
+		return nil
+	}
+}
+
+// WithSampler replaces the default sampler (parent based, always sample) used for spans not matching any sampling rule.
+func WithSampler(sampler sdktrace.Sampler) Option {
+	return func(o *options) error {
+		if sampler == nil {
+			return errors.New("sampler cannot be nil")
+		}
+
+		o.defaultSampler = sampler
+
+		return nil
+	}
+}
+
+// WithSamplingRule configures a sampler for spans whose name matches the pattern. Rules are evaluated in order they were added.
+// Span names are gRPC full method names without the leading slash (`package.Service/Method`)
+// and `METHOD /route` for HTTP (e.g. `GET /users/:id`). Patterns use path.Match syntax,
+// e.g. `grpc.health.v1.Health/*` or `GET /status`.
+func WithSamplingRule(pattern string, sampler sdktrace.Sampler) Option {
+	return func(o *options) error {
+		rule, err := newSamplingRule(pattern, sampler)
+		if err != nil {
+			return err
+		}
+
+		o.samplingRules = append(o.samplingRules, rule)
+
+		return nil
+	}
+}
+
+// WithResourceAttributes adds attributes describing the service to all spans.
+func WithResourceAttributes(attributes ...attribute.KeyValue) Option {
+	return func(o *options) error {
+		o.attributes = append(o.attributes, attributes...)
+
+		return nil
+	}
+}
+
+// WithPropagator replaces the default W3C trace-context and baggage propagator.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(o *options) error {
+		o.propagator = propagator
+
+		return nil
+	}
+}
+
+// WithLogger configures the logger enriched with trace and span IDs when the request context carries none.
+func WithLogger(logger zerolog.Logger) Option {
+	return func(o *options) error {
+		o.logger = logger
+
+		return nil
+	}
+}
@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	testTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testTraceparent = "00-" + testTraceID + "-00f067aa0ba902b7-01"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+func TestTracing_Middleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		wantSpans []string
+	}{
+		{name: "sampled", path: "/users/42", wantSpans: []string{"GET /users/:id"}},
+		{name: "never sampled route", path: "/status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := NewInMemoryExporter()
+
+			tr, err := New("test",
+				WithSyncExporter(exporter),
+				WithSamplingRule("GET /status", sdktrace.NeverSample()),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var handlerTraceID string
+
+			r := gin.New()
+			r.Use(tr.Middleware())
+			r.GET("/users/:id", func(c *gin.Context) {
+				handlerTraceID = trace.SpanContextFromContext(c.Request.Context()).TraceID().String()
+			})
+			r.GET("/status", func(*gin.Context) {})
+
+			req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, tt.path, nil)
+			req.Header.Set("Traceparent", testTraceparent)
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := exporter.GetSpans()
+			if len(spans) != len(tt.wantSpans) {
+				t.Fatalf("spans = %v, want %v", spans, tt.wantSpans)
+			}
+
+			for i, span := range spans {
+				if span.Name != tt.wantSpans[i] || span.SpanContext.TraceID().String() != testTraceID {
+					t.Errorf("span = %s (trace %s), want %s (trace %s)",
+						span.Name, span.SpanContext.TraceID(), tt.wantSpans[i], testTraceID)
+				}
+			}
+
+			if len(spans) > 0 && handlerTraceID != testTraceID {
+				t.Errorf("handler trace id = %s, want %s", handlerTraceID, testTraceID)
+			}
+		})
+	}
+}
+
+func TestTracing_UnaryServerInterceptor(t *testing.T) {
+	exporter := NewInMemoryExporter()
+
+	tr, err := New("test", WithSyncExporter(exporter), WithLogger(zerolog.New(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs("traceparent", testTraceparent))
+
+	var logger *zerolog.Logger
+
+	_, err = tr.UnaryServerInterceptor()(
+		ctx,
+		nil,
+		&grpc.UnaryServerInfo{FullMethod: "/example.GreeterService/SayHi"},
+		func(ctx context.Context, _ any) (any, error) {
+			logger = zerolog.Ctx(ctx)
+
+			return nil, nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "example.GreeterService/SayHi" ||
+		spans[0].SpanContext.TraceID().String() != testTraceID {
+		t.Errorf("spans = %v, want example.GreeterService/SayHi of trace %s", spans, testTraceID)
+	}
+
+	if logger == zerolog.DefaultContextLogger {
+		t.Error("handler logger is not the tracing logger")
+	}
+}
@@ -0,0 +1,72 @@
+package validation
+
+import (
+	"context"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor rejects invalid requests with codes.InvalidArgument.
+func (v *Validator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		err := v.Validate(req)
+		if err != nil {
+			return nil, invalidArgument(err)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor validates every message received from the stream.
+// Receiving an invalid message fails the call with codes.InvalidArgument.
+func (v *Validator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ServerStream: ss, validator: v})
+	}
+}
+
+func invalidArgument(err error) error {
+	violations := Violations(err)
+
+	br := &errdetails.BadRequest{}
+	for _, violation := range violations {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       violation.Field,
+			Description: violation.Description,
+			Reason:      violation.Reason,
+		})
+	}
+
+	s := status.New(codes.InvalidArgument, "invalid request: "+err.Error())
+
+	sd, detailsErr := s.WithDetails(br)
+	if detailsErr != nil {
+		return s.Err()
+	}
+
+	return sd.Err()
+}
+
+type serverStream struct {
+	grpc.ServerStream
+
+	validator *Validator
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err != nil {
+		return err
+	}
+
+	err = s.validator.Validate(m)
+	if err != nil {
+		return invalidArgument(err)
+	}
+
+	return nil
+}
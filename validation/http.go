@@ -0,0 +1,48 @@
+package validation
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/moderntv/cadre/http/responses"
+)
+
+// Bind binds the request into obj according to its content type and validates it by the `binding` tags
+// and its validation methods. If anything fails, it responds with 400 Bad Request and returns false.
+//
+//	var req CreateUserRequest
+//	if !validator.Bind(c, &req) {
+//		return
+//	}
+func (v *Validator) Bind(c *gin.Context, obj any) bool {
+	err := c.ShouldBind(obj)
+	if err == nil {
+		err = v.Validate(obj)
+	}
+
+	if err != nil {
+		BadRequest(c, err)
+		return false
+	}
+
+	return true
+}
+
+// BadRequest responds with 400 Bad Request containing one error per field violation of the validation error.
+func BadRequest(c *gin.Context, err error) {
+	violations := Violations(err)
+
+	errs := make([]responses.Error, 0, len(violations))
+	for _, violation := range violations {
+		errType := "INVALID_FIELD"
+		if violation.Field == "" {
+			errType = "INVALID_INPUT"
+		}
+
+		errs = append(errs, responses.Error{
+			Type:    errType,
+			Message: violation.Description,
+			Data:    violation,
+		})
+	}
+
+	responses.BadRequest(c, errs...)
+}
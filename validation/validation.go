@@ -0,0 +1,290 @@
+// Package validation validates incoming gRPC messages and HTTP request bodies before they reach handlers.
+//
+// Messages and structs are validated by their `ValidateAll() error` or `Validate() error` methods
+// (as generated by protoc-gen-validate) and optionally by a pluggable proto validator such as protovalidate.
+// Bound gin structs are additionally validated by gin's `binding` tags.
+//
+// Invalid gRPC requests fail with codes.InvalidArgument carrying errdetails.BadRequest field violations,
+// invalid HTTP requests with responses.BadRequest containing one responses.Error per field.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FieldViolation describes a single invalid field. Field is empty if the violation cannot be attributed to a field.
+type FieldViolation struct {
+	Field       string `json:"field,omitempty"`
+	Description string `json:"description"`
+	// Reason is the failed rule, e.g. `required` or `string.min_len`.
+	Reason string `json:"reason,omitempty"`
+}
+
+// ProtoValidator validates proto messages against their constraints, e.g. protovalidate.Validate.
+type ProtoValidator func(msg proto.Message) error
+
+type validatorAll interface {
+	ValidateAll() error
+}
+
+type validatorOne interface {
+	Validate() error
+}
+
+type Validator struct {
+	protoValidator ProtoValidator
+	failFast       bool
+}
+
+type Option func(*Validator) error
+
+// New creates a new validator.
+func New(opts ...Option) (v *Validator, err error) {
+	v = &Validator{}
+
+	for _, opt := range opts {
+		err = opt(v)
+		if err != nil {
+			err = fmt.Errorf("cannot apply validator option: %w", err)
+			return
+		}
+	}
+
+	return
+}
+
+// WithProtoValidator validates proto messages by the validator in addition to their own validation methods.
+// Violations of protovalidate's ValidationError are reported per field.
+func WithProtoValidator(protoValidator ProtoValidator) Option {
+	return func(v *Validator) error {
+		if protoValidator == nil {
+			return errors.New("proto validator cannot be nil")
+		}
+
+		v.protoValidator = protoValidator
+
+		return nil
+	}
+}
+
+// WithFailFast prefers `Validate()` over `ValidateAll()`, reporting only the first violation.
+func WithFailFast() Option {
+	return func(v *Validator) error {
+		v.failFast = true
+
+		return nil
+	}
+}
+
+// Validate validates the value. The error can be inspected by Violations.
+func (v *Validator) Validate(value any) error {
+	if msg, ok := value.(proto.Message); ok && v.protoValidator != nil {
+		err := v.protoValidator(msg)
+		if err != nil {
+			return err
+		}
+	}
+
+	all, hasAll := value.(validatorAll)
+	one, hasOne := value.(validatorOne)
+
+	switch {
+	case hasAll && (!v.failFast || !hasOne):
+		return all.ValidateAll()
+	case hasOne:
+		return one.Validate()
+	default:
+		return nil
+	}
+}
+
+// Violations extracts field violations from validation errors - gin binding (go-playground/validator) errors,
+// protoc-gen-validate errors, protovalidate's ValidationError and errors implementing
+// `FieldViolations() []FieldViolation`. Other errors are reported as a single violation without a field.
+func Violations(err error) []FieldViolation {
+	if err == nil {
+		return nil
+	}
+
+	var custom interface{ FieldViolations() []FieldViolation }
+	if errors.As(err, &custom) {
+		return custom.FieldViolations()
+	}
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		return bindingViolations(validationErrors)
+	}
+
+	if violations, ok := protovalidateViolations(err); ok {
+		return violations
+	}
+
+	if violations, ok := pgvViolations("", err); ok {
+		return violations
+	}
+
+	return []FieldViolation{{Description: err.Error()}}
+}
+
+func bindingViolations(errs validator.ValidationErrors) []FieldViolation {
+	violations := make([]FieldViolation, 0, len(errs))
+
+	for _, fe := range errs {
+		// drop the top level struct name
+		_, field, _ := strings.Cut(fe.Namespace(), ".")
+
+		description := fmt.Sprintf("failed on the `%s` rule", fe.Tag())
+		if fe.Param() != "" {
+			description = fmt.Sprintf("failed on the `%s=%s` rule", fe.Tag(), fe.Param())
+		}
+
+		violations = append(violations, FieldViolation{
+			Field:       field,
+			Description: description,
+			Reason:      fe.Tag(),
+		})
+	}
+
+	return violations
+}
+
+// pgvViolations flattens errors generated by protoc-gen-validate. Multi errors implement `AllErrors() []error`,
+// field errors `Field() string`, `Reason() string` and `Cause() error` - the cause of embedded messages is their error.
+func pgvViolations(prefix string, err error) (violations []FieldViolation, ok bool) {
+	if multi, isMulti := err.(interface{ AllErrors() []error }); isMulti {
+		for _, e := range multi.AllErrors() {
+			v, _ := pgvViolations(prefix, e)
+			violations = append(violations, v...)
+		}
+
+		return violations, true
+	}
+
+	fieldErr, isField := err.(interface {
+		Field() string
+		Reason() string
+		Cause() error
+	})
+	if !isField {
+		return []FieldViolation{{Field: prefix, Description: err.Error()}}, false
+	}
+
+	field := joinField(prefix, fieldErr.Field())
+
+	if cause := fieldErr.Cause(); cause != nil {
+		if nested, nestedOK := pgvViolations(field, cause); nestedOK {
+			return nested, true
+		}
+	}
+
+	return []FieldViolation{{Field: field, Description: fieldErr.Reason()}}, true
+}
+
+// protovalidateViolations reads violations of protovalidate's ValidationError through its `ToProto()` method
+// (returning buf.validate.Violations) using proto reflection, so that protovalidate does not have to be a dependency.
+func protovalidateViolations(err error) (violations []FieldViolation, ok bool) {
+	var msg proto.Message
+
+	for e := err; e != nil && msg == nil; e = errors.Unwrap(e) {
+		method := reflect.ValueOf(e).MethodByName("ToProto")
+		if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+			continue
+		}
+
+		msg, _ = method.Call(nil)[0].Interface().(proto.Message)
+	}
+
+	if msg == nil {
+		return nil, false
+	}
+
+	m := msg.ProtoReflect()
+
+	list := fieldByName(m, "violations")
+	if list == nil || !list.IsList() {
+		return nil, false
+	}
+
+	l := m.Get(list).List()
+	for i := range l.Len() {
+		violation := l.Get(i).Message()
+
+		v := FieldViolation{
+			Description: stringField(violation, "message"),
+			Reason:      stringField(violation, "rule_id", "constraint_id"),
+			Field:       stringField(violation, "field_path"),
+		}
+
+		if path := fieldByName(violation, "field"); v.Field == "" && path != nil && violation.Has(path) {
+			v.Field = fieldPath(violation.Get(path).Message())
+		}
+
+		violations = append(violations, v)
+	}
+
+	return violations, true
+}
+
+// fieldPath formats buf.validate.FieldPath elements as `a.b[0].c["key"]`.
+func fieldPath(path protoreflect.Message) (s string) {
+	elements := fieldByName(path, "elements")
+	if elements == nil {
+		return
+	}
+
+	l := path.Get(elements).List()
+	for i := range l.Len() {
+		element := l.Get(i).Message()
+
+		s = joinField(s, stringField(element, "field_name"))
+
+		for _, subscript := range []string{"index", "bool_key", "int_key", "uint_key", "sint_key"} {
+			fd := fieldByName(element, subscript)
+			if fd != nil && element.Has(fd) {
+				s += "[" + fmt.Sprint(element.Get(fd).Interface()) + "]"
+			}
+		}
+
+		if fd := fieldByName(element, "string_key"); fd != nil && element.Has(fd) {
+			s += "[" + strconv.Quote(element.Get(fd).String()) + "]"
+		}
+	}
+
+	return
+}
+
+func fieldByName(m protoreflect.Message, name string) protoreflect.FieldDescriptor {
+	return m.Descriptor().Fields().ByName(protoreflect.Name(name))
+}
+
+func stringField(m protoreflect.Message, names ...string) string {
+	for _, name := range names {
+		fd := fieldByName(m, name)
+		if fd != nil && fd.Kind() == protoreflect.StringKind && m.Has(fd) {
+			return m.Get(fd).String()
+		}
+	}
+
+	return ""
+}
+
+func joinField(prefix, field string) string {
+	if prefix == "" {
+		return field
+	}
+
+	if field == "" {
+		return prefix
+	}
+
+	return prefix + "." + field
+}
@@ -0,0 +1,321 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func init() {
+	gin.SetMode(gin.ReleaseMode)
+}
+
+// protoc-gen-validate style errors.
+type fieldError struct {
+	field  string
+	reason string
+	cause  error
+}
+
+func (e fieldError) Error() string  { return e.field + ": " + e.reason }
+func (e fieldError) Field() string  { return e.field }
+func (e fieldError) Reason() string { return e.reason }
+func (e fieldError) Cause() error   { return e.cause }
+
+type multiError []error
+
+func (e multiError) Error() string      { return errors.Join(e...).Error() }
+func (e multiError) AllErrors() []error { return e }
+
+type request struct {
+	Name string `json:"name" binding:"required,min=3"`
+	Age  int    `json:"age"`
+
+	validateAllCalled bool
+}
+
+func (r *request) ValidateAll() error {
+	r.validateAllCalled = true
+
+	if r.Age < 0 {
+		return multiError{
+			fieldError{field: "Age", reason: "value must be greater than or equal to 0"},
+			fieldError{field: "Address", reason: "embedded message failed validation", cause: multiError{
+				fieldError{field: "City", reason: "value is required"},
+			}},
+		}
+	}
+
+	return nil
+}
+
+func (r *request) Validate() error {
+	if r.Age < 0 {
+		return fieldError{field: "Age", reason: "value must be greater than or equal to 0"}
+	}
+
+	return nil
+}
+
+func TestViolations_PGV(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            []Option
+		want            []FieldViolation
+		wantValidateAll bool
+	}{
+		{
+			name: "all violations",
+			want: []FieldViolation{
+				{Field: "Age", Description: "value must be greater than or equal to 0"},
+				{Field: "Address.City", Description: "value is required"},
+			},
+			wantValidateAll: true,
+		},
+		{
+			name: "fail fast",
+			opts: []Option{WithFailFast()},
+			want: []FieldViolation{{Field: "Age", Description: "value must be greater than or equal to 0"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := New(tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := &request{Age: -1}
+			if got := Violations(v.Validate(req)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Violations() = %v, want %v", got, tt.want)
+			}
+
+			if req.validateAllCalled != tt.wantValidateAll {
+				t.Errorf("ValidateAll called = %v, want %v", req.validateAllCalled, tt.wantValidateAll)
+			}
+		})
+	}
+}
+
+// protovalidate style error.
+type validationError struct {
+	violations proto.Message
+}
+
+func (e *validationError) Error() string          { return "validation error" }
+func (e *validationError) ToProto() proto.Message { return e.violations }
+
+func newViolations(t *testing.T) proto.Message {
+	t.Helper()
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("validate_test.proto"),
+		Package: proto.String("buf.validate"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Violations"),
+				Field: []*descriptorpb.FieldDescriptorProto{{
+					Name:     proto.String("violations"),
+					Number:   proto.Int32(1),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+					TypeName: proto.String(".buf.validate.Violation"),
+				}},
+			},
+			{
+				Name: proto.String("Violation"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:   proto.String("field_path"),
+						Number: proto.Int32(1),
+						Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					},
+					{
+						Name:   proto.String("rule_id"),
+						Number: proto.Int32(2),
+						Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					},
+					{
+						Name:   proto.String("message"),
+						Number: proto.Int32(3),
+						Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					},
+				},
+			},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	violationsDesc := fd.Messages().ByName("Violations")
+	violationDesc := fd.Messages().ByName("Violation")
+
+	violations := dynamicpb.NewMessage(violationsDesc)
+	list := violations.Mutable(violationsDesc.Fields().ByName("violations")).List()
+
+	violation := dynamicpb.NewMessage(violationDesc)
+	violation.Set(violationDesc.Fields().ByName("field_path"), protoreflect.ValueOfString("user.email"))
+	violation.Set(violationDesc.Fields().ByName("rule_id"), protoreflect.ValueOfString("string.email"))
+	violation.Set(
+		violationDesc.Fields().ByName("message"),
+		protoreflect.ValueOfString("value must be a valid email address"),
+	)
+	list.Append(protoreflect.ValueOfMessage(violation))
+
+	return violations
+}
+
+func TestViolations_Protovalidate(t *testing.T) {
+	want := []FieldViolation{{
+		Field:       "user.email",
+		Description: "value must be a valid email address",
+		Reason:      "string.email",
+	}}
+
+	if got := Violations(&validationError{violations: newViolations(t)}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Violations() = %v, want %v", got, want)
+	}
+}
+
+func TestValidator_UnaryServerInterceptor(t *testing.T) {
+	protoValidatorErr := &validationError{violations: newViolations(t)}
+
+	v, err := New(WithProtoValidator(func(msg proto.Message) error {
+		if _, ok := msg.(*descriptorpb.FileDescriptorProto); ok {
+			return protoValidatorErr
+		}
+
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		req        any
+		wantCode   codes.Code
+		wantFields []string
+	}{
+		{
+			name:       "pgv",
+			req:        &request{Age: -1},
+			wantCode:   codes.InvalidArgument,
+			wantFields: []string{"Age", "Address.City"},
+		},
+		{
+			name:       "protovalidate",
+			req:        &descriptorpb.FileDescriptorProto{},
+			wantCode:   codes.InvalidArgument,
+			wantFields: []string{"user.email"},
+		},
+		{name: "valid", req: &request{Age: 1}, wantCode: codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.UnaryServerInterceptor()(
+				t.Context(),
+				tt.req,
+				&grpc.UnaryServerInfo{FullMethod: "/example.Service/Method"},
+				func(context.Context, any) (any, error) { return "ok", nil },
+			)
+
+			s := status.Convert(err)
+			if s.Code() != tt.wantCode {
+				t.Errorf("code = %v, want %v", s.Code(), tt.wantCode)
+			}
+
+			var fields []string
+
+			for _, detail := range s.Details() {
+				if br, ok := detail.(*errdetails.BadRequest); ok {
+					for _, violation := range br.GetFieldViolations() {
+						fields = append(fields, violation.GetField())
+					}
+				}
+			}
+
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("field violations = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestValidator_Bind(t *testing.T) {
+	v, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.POST("/users", func(c *gin.Context) {
+		var req request
+		if !v.Bind(c, &req) {
+			return
+		}
+
+		c.String(http.StatusOK, req.Name)
+	})
+
+	tests := []struct {
+		name   string
+		body   string
+		code   int
+		fields []string
+	}{
+		{name: "valid", body: `{"name":"john","age":30}`, code: http.StatusOK},
+		{
+			name:   "binding",
+			body:   `{"name":"jo"}`,
+			code:   http.StatusBadRequest,
+			fields: []string{`"field":"Name"`, `"reason":"min"`},
+		},
+		{
+			name:   "validate all",
+			body:   `{"name":"john","age":-1}`,
+			code:   http.StatusBadRequest,
+			fields: []string{`"field":"Age"`, `"field":"Address.City"`},
+		},
+		{name: "malformed", body: `{`, code: http.StatusBadRequest, fields: []string{`"type":"INVALID_INPUT"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.code {
+				t.Errorf("status = %d, want %d", w.Code, tt.code)
+			}
+
+			for _, field := range tt.fields {
+				if !strings.Contains(w.Body.String(), field) {
+					t.Errorf("body %s does not contain %s", w.Body.String(), field)
+				}
+			}
+		})
+	}
+}